@@ -0,0 +1,60 @@
+package main
+
+import (
+	"auth-service/internal/config"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const keystoreUsage = "usage: server [flags] keystore generate-key | list | set <name> | delete <name>"
+
+// runKeystore manages the keystore of the config, set reads the secret from stdin so it stays out of the shell history
+func runKeystore(keystoreConfig config.KeystoreConfig, args []string, stdin io.Reader, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(keystoreUsage)
+	}
+	if args[0] == "generate-key" {
+		key, err := config.NewKeystoreKey()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(stdout, key)
+		return err
+	}
+	if keystoreConfig.File == "" || keystoreConfig.Key == "" {
+		return errors.New("keystore.file and keystore.key are required to open the keystore")
+	}
+	keystore, err := config.OpenKeystore(keystoreConfig.File, keystoreConfig.Key)
+	if err != nil {
+		return err
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		for _, name := range keystore.Names() {
+			if _, err = fmt.Fprintln(stdout, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	case args[0] == "set" && len(args) == 2:
+		secret, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if secret = strings.TrimRight(secret, "\r\n"); secret == "" {
+			return errors.New("the secret is read from stdin and must not be empty")
+		}
+		if err = keystore.Set(args[1], secret); err != nil {
+			return err
+		}
+		return keystore.Save()
+	case args[0] == "delete" && len(args) == 2:
+		keystore.Delete(args[1])
+		return keystore.Save()
+	default:
+		return errors.New(keystoreUsage)
+	}
+}
@@ -5,9 +5,15 @@ import (
 	"auth-service/internal/dependencies"
 	"auth-service/internal/gen/auth/v1/v1connect"
 	"auth-service/internal/server"
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,29 +21,87 @@ import (
 )
 
 func main() {
-	load := config.Load()
+	load, options, err := config.Load(os.Args[1:], os.Environ())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if options.PrintConfig {
+		printed, printErr := config.Print(load)
+		if printErr != nil {
+			log.Fatal(printErr.Error())
+		}
+		fmt.Print(printed)
+	}
+	if len(options.Args) > 0 && options.Args[0] == "keystore" {
+		// secrets referring to the keystore can not be resolved before they are stored, so problems are ignored
+		if err := runKeystore(load.KeystoreConfig, options.Args[1:], os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	if options.PrintConfig {
+		return
+	}
+	dependencies.ConfigureLogging(load.LogConfig)
+	if len(options.Args) > 0 && options.Args[0] == "migrate" {
+		if err := runMigrate(load.DatabaseConfig, options.Args[1:]); err != nil {
+			log.Fatal(err.Error())
+		}
+		return
+	}
 	deps, err := dependencies.Initialize(load)
 	if err != nil {
 		log.Fatal(err.Error())
 		return
 	}
-	authServer := server.NewAuthServer(deps.AuthService)
+	authServer := server.NewAuthServer(deps.AuthService, server.NewIdempotency(deps.IdempotencyKeys, load.IdempotencyConfig.Window, load.IdempotencyConfig.Lease))
 	mux := http.NewServeMux()
-	path, handler := v1connect.NewAuthServiceHandler(authServer)
+	path, handler := v1connect.NewAuthServiceHandler(authServer, connect.WithInterceptors(server.NewMessageContextInterceptor()))
 	mux.Handle(path, handler)
 	go func() {
-		log.Println("Starting server on localhost:8080")
-		if err := http.ListenAndServe("localhost:8080", h2c.NewHandler(mux, &http2.Server{})); err != nil {
+		log.Printf("Starting server on %s", load.ServerConfig.ListenAddress)
+		if err := http.ListenAndServe(load.ServerConfig.ListenAddress, h2c.NewHandler(mux, &http2.Server{})); err != nil {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
 
 	// Create a channel to listen for OS signals.
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	// The config is reloaded on SIGHUP and when the config file changes, which applies its runtime settings.
+	watching, stopWatching := context.WithCancel(context.Background())
+	var fileChanges <-chan struct{}
+	if options.File != "" && load.ServerConfig.ReloadInterval > 0 {
+		fileChanges = config.Watch(watching, options.File, load.ServerConfig.ReloadInterval)
+	}
+	reload := func(trigger string) {
+		reloaded, _, err := config.Load(os.Args[1:], os.Environ())
+		if err != nil {
+			slog.Error("Keeping the current config, reloading it failed", "trigger", trigger, "error", err)
+			return
+		}
+		deps.Reload(reloaded)
+	}
 
-	// Block until a signal is received.
-	sig := <-sigCh
+	// Block until a stop signal is received.
+	var sig os.Signal
+	for sig == nil {
+		select {
+		case received := <-sigCh:
+			if received == syscall.SIGHUP {
+				reload("SIGHUP")
+			} else {
+				sig = received
+			}
+		case <-fileChanges:
+			reload(options.File + " changed")
+		}
+	}
+	stopWatching()
 	log.Printf("Received signal: %v", sig)
 
 	// Shutdown the server gracefully.
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"auth-service/internal/config"
+	"auth-service/internal/dependencies"
+	"auth-service/internal/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+)
+
+const migrateUsage = "usage: server [flags] migrate up | down [steps] | status"
+
+// runMigrate handles the migrate sub command, so schema changes can be rolled out before a deploy
+func runMigrate(config config.DatabaseConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	db, err := dependencies.OpenDatabase(config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("steps must be a positive number, got %s", args[1])
+			}
+		}
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied"
+			}
+			log.Printf("%04d_%s: %s", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
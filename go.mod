@@ -1,17 +1,25 @@
 module auth-service
 
-go 1.22.0
+go 1.23.0
 
 require (
 	connectrpc.com/connect v1.16.1
 	github.com/lib/pq v1.10.9
+	github.com/nats-io/nats.go v1.37.0
+	github.com/segmentio/kafka-go v0.4.51
 	github.com/streadway/amqp v1.1.0
 	google.golang.org/protobuf v1.33.0
 )
 
 require (
 	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
 	github.com/kr/pretty v0.1.0 // indirect
+	github.com/nats-io/nkeys v0.4.7 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
 
@@ -20,10 +28,10 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/stretchr/testify v1.9.0
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	golang.org/x/net v0.24.0
-	golang.org/x/text v0.14.0 // indirect
+	golang.org/x/net v0.38.0
+	golang.org/x/text v0.23.0 // indirect
 )
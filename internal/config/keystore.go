@@ -0,0 +1,127 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+const keystoreVersion = 1
+
+// Keystore is a local file of secrets encrypted with AES-256-GCM. Every secret is sealed on its own with its name as
+// additional data, so secrets can be listed without the key and can not be swapped between names
+type Keystore struct {
+	file    string
+	aead    cipher.AEAD
+	secrets map[string]sealedSecret
+}
+
+type keystoreFile struct {
+	Version int                     `json:"version"`
+	Secrets map[string]sealedSecret `json:"secrets"`
+}
+
+type sealedSecret struct {
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewKeystoreKey returns a random key for a keystore, base64 encoded like KeystoreConfig.Key
+func NewKeystoreKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// OpenKeystore reads the keystore in file, a missing file is an empty keystore that Save creates
+func OpenKeystore(file string, key string) (*Keystore, error) {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 32 {
+		return nil, errors.New("the keystore key has to be 32 base64 encoded bytes")
+	}
+	block, err := aes.NewCipher(decoded)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	keystore := &Keystore{file: file, aead: aead, secrets: map[string]sealedSecret{}}
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return keystore, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored keystoreFile
+	if err = json.Unmarshal(content, &stored); err != nil {
+		return nil, fmt.Errorf("reading keystore %s: %w", file, err)
+	}
+	if stored.Version != keystoreVersion {
+		return nil, fmt.Errorf("keystore %s has unsupported version %d", file, stored.Version)
+	}
+	if stored.Secrets != nil {
+		keystore.secrets = stored.Secrets
+	}
+	return keystore, nil
+}
+
+// Get decrypts the secret, it fails when the key is not the key the secret was stored with
+func (k *Keystore) Get(name string) (string, error) {
+	sealed, ok := k.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("no secret %s in keystore %s", name, k.file)
+	}
+	value, err := k.aead.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("decrypting secret %s of keystore %s failed, is the keystore key right?", name, k.file)
+	}
+	return string(value), nil
+}
+
+// Set stores the secret, replacing a secret with the same name. It is written by Save
+func (k *Keystore) Set(name string, value string) error {
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	k.secrets[name] = sealedSecret{Nonce: nonce, Ciphertext: k.aead.Seal(nil, nonce, []byte(value), []byte(name))}
+	return nil
+}
+
+func (k *Keystore) Delete(name string) {
+	delete(k.secrets, name)
+}
+
+// Names lists the names of the secrets in order
+func (k *Keystore) Names() []string {
+	names := make([]string, 0, len(k.secrets))
+	for name := range k.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save writes the keystore to a temporary file and renames it, so a running service never reads a partial keystore
+func (k *Keystore) Save() error {
+	content, err := json.MarshalIndent(keystoreFile{Version: keystoreVersion, Secrets: k.secrets}, "", "  ")
+	if err != nil {
+		return err
+	}
+	temporary := k.file + ".tmp"
+	if err = os.WriteFile(temporary, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, k.file)
+}
@@ -0,0 +1,377 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Every setting has a key made of the snake case names of its fields, config sections drop their Config suffix.
+// OTPConfig.SecretKey is otp.secret_key in the config file, AUTH_OTP_SECRET_KEY in the environment and
+// --otp.secret-key on the command line
+const (
+	ENV_PREFIX = "AUTH_"
+	// CONFIG_FILE_ENV names the config file when the --config flag is not given
+	CONFIG_FILE_ENV = ENV_PREFIX + "CONFIG_FILE"
+	redacted        = "<redacted>"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Options are the flags of the command that are not settings
+type Options struct {
+	// File is the yaml config file that was loaded, empty when there is none
+	File string
+	// PrintConfig asks to print the loaded config with its secrets redacted instead of starting the server
+	PrintConfig bool
+	// Args are the arguments after the flags, like the migrate sub command
+	Args []string
+}
+
+// ValidationError lists every problem with the loaded config, so they can be fixed at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Load returns the defaults overridden by the yaml config file, then by AUTH_ environment variables and then by flags.
+// The config is returned along with a *ValidationError when settings can not be parsed or are invalid, and flag.ErrHelp
+// when the usage was requested
+func Load(args []string, environ []string) (Config, Options, error) {
+	config := Defaults()
+	settings := settingsOf(&config)
+	env := map[string]string{}
+	for _, variable := range environ {
+		if name, value, ok := strings.Cut(variable, "="); ok && strings.HasPrefix(name, ENV_PREFIX) {
+			env[name] = value
+		}
+	}
+
+	var options Options
+	flags := flag.NewFlagSet("auth-service", flag.ContinueOnError)
+	flags.StringVar(&options.File, "config", env[CONFIG_FILE_ENV], "yaml config file, env "+CONFIG_FILE_ENV)
+	flags.BoolVar(&options.PrintConfig, "print-config", false, "print the config with secrets redacted and exit")
+	flagValues := map[string]string{}
+	for _, setting := range settings {
+		name := setting.flagName()
+		flags.Func(name, "env "+setting.envName(), func(value string) error {
+			flagValues[name] = value
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return config, options, err
+	}
+	options.Args = flags.Args()
+
+	var problems []string
+	if options.File != "" {
+		problems = append(problems, loadFile(options.File, settings)...)
+	}
+	for _, setting := range settings {
+		if value, ok := env[setting.envName()]; ok {
+			if err := setting.set(value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", setting.envName(), err))
+			}
+		}
+	}
+	for _, setting := range settings {
+		if value, ok := flagValues[setting.flagName()]; ok {
+			if err := setting.set(value); err != nil {
+				problems = append(problems, fmt.Sprintf("--%s: %v", setting.flagName(), err))
+			}
+		}
+	}
+	problems = append(problems, resolveSecrets(&config, settings)...)
+	problems = append(problems, validate(config, settings)...)
+	if len(problems) > 0 {
+		return config, options, &ValidationError{Problems: problems}
+	}
+	return config, options, nil
+}
+
+// loadFile sets the settings found in the yaml file, keys that are no setting are reported as problems
+func loadFile(file string, settings []setting) []string {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return []string{fmt.Sprintf("%s: %v", file, err)}
+	}
+	if len(document.Content) == 0 {
+		return nil
+	}
+	values := map[string]*yaml.Node{}
+	sections := map[string]bool{}
+	for _, setting := range settings {
+		for i := 1; i < len(setting.path); i++ {
+			sections[strings.Join(setting.path[:i], ".")] = true
+		}
+	}
+	var problems []string
+	var walk func(node *yaml.Node, prefix string)
+	walk = func(node *yaml.Node, prefix string) {
+		if node.Kind != yaml.MappingNode {
+			problems = append(problems, fmt.Sprintf("%s line %d: %s must be a mapping", file, node.Line, strings.TrimSuffix(prefix, ".")))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := prefix + node.Content[i].Value
+			if sections[key] {
+				walk(node.Content[i+1], key+".")
+				continue
+			}
+			values[key] = node.Content[i+1]
+		}
+	}
+	walk(document.Content[0], "")
+	for _, setting := range settings {
+		node, ok := values[setting.key()]
+		if !ok {
+			continue
+		}
+		delete(values, setting.key())
+		if err = setting.decode(node); err != nil {
+			problems = append(problems, fmt.Sprintf("%s line %d: %s: %v", file, node.Line, setting.key(), err))
+		}
+	}
+	unknown := make([]string, 0, len(values))
+	for key := range values {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		problems = append(problems, fmt.Sprintf("%s line %d: unknown setting %s", file, values[key].Line, key))
+	}
+	return problems
+}
+
+// Print returns the config as yaml in the format of the config file, secrets are redacted
+func Print(config Config) (string, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, setting := range settingsOf(&config) {
+		parent := root
+		for _, name := range setting.path[:len(setting.path)-1] {
+			parent = childMapping(parent, name)
+		}
+		value, err := setting.node()
+		if err != nil {
+			return "", err
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: setting.path[len(setting.path)-1]}, value)
+	}
+	out, err := yaml.Marshal(root)
+	return string(out), err
+}
+
+func childMapping(parent *yaml.Node, name string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+	return child
+}
+
+// setting is a field of Config holding a value, sections are flattened into the path of their fields
+type setting struct {
+	path  []string
+	value reflect.Value
+	// secret settings are redacted when printed
+	secret bool
+	// zero durations and numbers are allowed
+	zero bool
+	// runtime settings are applied when the config is reloaded, the others need a restart
+	runtime bool
+}
+
+func settingsOf(config *Config) []setting {
+	var settings []setting
+	var walk func(value reflect.Value, path []string)
+	walk = func(value reflect.Value, path []string) {
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			name, options, _ := strings.Cut(field.Tag.Get("config"), ",")
+			isSection := field.Type.Kind() == reflect.Struct
+			if name == "" {
+				if isSection {
+					name = snakeCase(strings.TrimSuffix(field.Name, "Config"))
+				} else {
+					name = snakeCase(field.Name)
+				}
+			}
+			fieldPath := append(append([]string(nil), path...), name)
+			if isSection {
+				walk(value.Field(i), fieldPath)
+				continue
+			}
+			settings = append(settings, setting{
+				path:    fieldPath,
+				value:   value.Field(i),
+				secret:  strings.Contains(options, "secret"),
+				zero:    strings.Contains(options, "zero"),
+				runtime: strings.Contains(options, "runtime"),
+			})
+		}
+	}
+	walk(reflect.ValueOf(config).Elem(), nil)
+	return settings
+}
+
+// snakeCase splits at lower to upper case changes and at the end of acronyms, MessageTTL becomes message_ttl
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previousLower := !unicode.IsUpper(runes[i-1])
+			acronymEnds := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1])
+			if previousLower || acronymEnds {
+				builder.WriteByte('_')
+			}
+		}
+		builder.WriteRune(unicode.ToLower(r))
+	}
+	return builder.String()
+}
+
+func (s setting) key() string {
+	return strings.Join(s.path, ".")
+}
+
+func (s setting) envName() string {
+	return ENV_PREFIX + strings.ToUpper(strings.Join(s.path, "_"))
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.key(), "_", "-")
+}
+
+// isList reports if the setting is a list of strings or numbers, which are comma separated outside of yaml lists
+func (s setting) isList() bool {
+	if s.value.Kind() != reflect.Slice {
+		return false
+	}
+	kind := s.value.Type().Elem().Kind()
+	return kind == reflect.String || kind == reflect.Int32
+}
+
+// set parses a value from the environment or a flag. Lists of strings and numbers are comma separated, other lists
+// are yaml
+func (s setting) set(value string) error {
+	switch {
+	case s.value.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(int64(duration))
+	case s.value.Kind() == reflect.String:
+		s.value.SetString(value)
+	case s.value.Kind() == reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		s.value.SetBool(parsed)
+	case s.value.Kind() == reflect.Int || s.value.Kind() == reflect.Int32:
+		parsed, err := strconv.ParseInt(value, 10, s.value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		s.value.SetInt(parsed)
+	case s.isList():
+		items := reflect.Zero(s.value.Type())
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item == "" {
+				continue
+			}
+			parsed := setting{value: reflect.New(s.value.Type().Elem()).Elem()}
+			if err := parsed.set(item); err != nil {
+				return err
+			}
+			items = reflect.Append(items, parsed.value)
+		}
+		s.value.Set(items)
+	default:
+		parsed := reflect.New(s.value.Type())
+		if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+			return err
+		}
+		s.value.Set(parsed.Elem())
+	}
+	return nil
+}
+
+// decode sets the setting from the config file, scalars are parsed like environment variables
+func (s setting) decode(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && (s.value.Kind() != reflect.Slice || s.isList()) {
+		return s.set(node.Value)
+	}
+	parsed := reflect.New(s.value.Type())
+	if err := node.Decode(parsed.Interface()); err != nil {
+		return err
+	}
+	// empty lists are nil like lists that were never set
+	if parsed.Elem().Kind() == reflect.Slice && parsed.Elem().Len() == 0 {
+		s.value.Set(reflect.Zero(s.value.Type()))
+		return nil
+	}
+	s.value.Set(parsed.Elem())
+	return nil
+}
+
+// node encodes the value for Print, secrets are redacted
+func (s setting) node() (*yaml.Node, error) {
+	value := s.value.Interface()
+	if s.value.Type() == durationType {
+		value = s.value.Interface().(time.Duration).String()
+	}
+	if s.secret && s.value.Kind() == reflect.String && s.value.String() != "" {
+		value = redact(s.value.String())
+	}
+	if s.secret && s.value.Kind() == reflect.Slice {
+		secrets := make([]string, s.value.Len())
+		for i := range secrets {
+			secrets[i] = redact(s.value.Index(i).String())
+		}
+		value = secrets
+	}
+	node := &yaml.Node{}
+	return node, node.Encode(value)
+}
+
+// redact keeps the host, path and options of urls whose secret is their password, everything else is replaced
+func redact(secret string) string {
+	parsed, err := url.Parse(secret)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return redacted
+	}
+	for name := range parsed.Query() {
+		if name = strings.ToLower(name); strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token") {
+			return redacted
+		}
+	}
+	if parsed.User == nil {
+		return secret
+	}
+	if _, hasPassword := parsed.User.Password(); !hasPassword {
+		return redacted
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Change is a setting that differs between the active and a reloaded config
+type Change struct {
+	Key string
+	// Old and New are the values of the setting, they are empty for secrets
+	Old string
+	New string
+	// Applied changes are runtime settings, the others keep their active value until a restart
+	Applied bool
+}
+
+func (c Change) String() string {
+	if c.Old == "" && c.New == "" {
+		return c.Key + " changed"
+	}
+	return fmt.Sprintf("%s changed from %s to %s", c.Key, c.Old, c.New)
+}
+
+// Reload returns the active config with the runtime settings of the reloaded config, and every setting that changed.
+// Settings that are not tagged runtime, like connection strings, keep their active value
+func Reload(active Config, reloaded Config) (Config, []Change) {
+	activeSettings := settingsOf(&active)
+	reloadedSettings := settingsOf(&reloaded)
+	var changes []Change
+	for i, setting := range activeSettings {
+		value := reloadedSettings[i].value
+		if reflect.DeepEqual(setting.value.Interface(), value.Interface()) {
+			continue
+		}
+		change := Change{Key: setting.key(), Applied: setting.runtime}
+		if !setting.secret {
+			change.Old, change.New = fmt.Sprint(setting.value.Interface()), fmt.Sprint(value.Interface())
+		}
+		changes = append(changes, change)
+		if setting.runtime {
+			setting.value.Set(value)
+		}
+	}
+	return active, changes
+}
+
+// Watch signals on the returned channel when the modification time or size of the file changes, it checks the file
+// every interval until ctx is done. A change is signalled once even when the file changes again before it is received
+func Watch(ctx context.Context, file string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	last, _ := os.Stat(file)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// a file that is being replaced may be missing for a moment, it is compared again on the next check
+			info, err := os.Stat(file)
+			if err != nil {
+				continue
+			}
+			if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+				continue
+			}
+			last = info
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return changes
+}
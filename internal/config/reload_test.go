@@ -0,0 +1,80 @@
+package config
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReload_AppliesRuntimeSettings(t *testing.T) {
+	active := Defaults()
+	reloaded := Defaults()
+	reloaded.OTPConfig.MaxResends = 2
+	reloaded.OTPConfig.SecretKey = "rotated"
+	reloaded.PhoneConfig.AllowedCountryCodes = []int32{91, 1}
+	reloaded.DatabaseConfig.ConnectionString = "postgresql://localhost/other"
+
+	applied, changes := Reload(active, reloaded)
+	assert.Equal(t, 2, applied.OTPConfig.MaxResends)
+	assert.Equal(t, "rotated", applied.OTPConfig.SecretKey)
+	assert.Equal(t, []int32{91, 1}, applied.PhoneConfig.AllowedCountryCodes)
+	assert.Equal(t, active.DatabaseConfig.ConnectionString, applied.DatabaseConfig.ConnectionString)
+	assert.Equal(t, []Change{
+		{Key: "phone.allowed_country_codes", Old: "[91]", New: "[91 1]", Applied: true},
+		{Key: "database.connection_string", Applied: false},
+		{Key: "otp.secret_key", Applied: true},
+		{Key: "otp.max_resends", Old: "5", New: "2", Applied: true},
+	}, changes)
+	assert.Equal(t, "otp.max_resends changed from 5 to 2", changes[3].String())
+	assert.Equal(t, "otp.secret_key changed", changes[2].String())
+}
+
+func TestReload_KeepsTheOtpInterval(t *testing.T) {
+	active := Defaults()
+	reloaded := Defaults()
+	reloaded.OTPConfig.Interval = time.Minute
+
+	// the otp service derives otps with the same interval, so it needs a restart of both
+	applied, changes := Reload(active, reloaded)
+	assert.Equal(t, active.OTPConfig.Interval, applied.OTPConfig.Interval)
+	assert.Equal(t, []Change{{Key: "otp.interval", Old: "10m0s", New: "1m0s", Applied: false}}, changes)
+}
+
+func TestReload_NothingChanged(t *testing.T) {
+	applied, changes := Reload(Defaults(), Defaults())
+	assert.Equal(t, Defaults(), applied)
+	assert.Empty(t, changes)
+}
+
+func TestWatch_SignalsChangedFiles(t *testing.T) {
+	file := writeConfigFile(t, "otp:\n  max_resends: 3\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := Watch(ctx, file, 10*time.Millisecond)
+
+	select {
+	case <-changes:
+		t.Fatal("the file did not change yet")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.NoError(t, os.WriteFile(file, []byte("otp:\n  max_resends: 2\n"), 0600))
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("the changed file was not signalled")
+	}
+}
+
+func TestLoad_ListsOfNumbers(t *testing.T) {
+	config, _, err := Load(nil, append(credentials, "AUTH_PHONE_ALLOWED_COUNTRY_CODES=91, 1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int32{91, 1}, config.PhoneConfig.AllowedCountryCodes)
+
+	file := writeConfigFile(t, "phone:\n  allowed_country_codes: [44]\nlog:\n  level: loud\n")
+	_, _, err = Load([]string{"--config", file, "--phone.allowed-country-codes", "1,x"}, credentials)
+	assert.EqualError(t, err, "invalid configuration:\n"+
+		"  - --phone.allowed-country-codes: strconv.ParseInt: parsing \"x\": invalid syntax\n"+
+		"  - log.level must be one of [debug info warn error], got \"loud\"")
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Secret settings hold a reference instead of the secret when their value starts with one of these prefixes.
+// file:/run/secrets/otp-key reads a mounted file, keystore:otp-key reads the keystore of KeystoreConfig
+const (
+	FILE_REFERENCE     = "file:"
+	KEYSTORE_REFERENCE = "keystore:"
+)
+
+// resolveSecrets replaces the references of secret settings with the secrets they point to. Files and the keystore
+// are read again on every load, so reloading the config picks up rotated secrets
+func resolveSecrets(config *Config, settings []setting) []string {
+	var problems []string
+	// the keystore key can only be a file reference, it can not be stored in the keystore it opens
+	key, err := resolveFile(config.KeystoreConfig.Key)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("keystore.key: %v", err))
+	} else {
+		config.KeystoreConfig.Key = key
+	}
+	var keystore *Keystore
+	if config.KeystoreConfig.File != "" && err == nil {
+		if keystore, err = OpenKeystore(config.KeystoreConfig.File, key); err != nil {
+			problems = append(problems, fmt.Sprintf("keystore.file: %v", err))
+		}
+	}
+	// a reference that can not be resolved is kept, the problem is reported instead of the setting missing
+	resolve := func(key string, value string) string {
+		secret, err := resolveReference(value, keystore, config.KeystoreConfig.File != "")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+			return value
+		}
+		return secret
+	}
+	for _, setting := range settings {
+		if !setting.secret || setting.key() == "keystore.key" {
+			continue
+		}
+		switch setting.value.Kind() {
+		case reflect.String:
+			setting.value.SetString(resolve(setting.key(), setting.value.String()))
+		case reflect.Slice:
+			for i := 0; i < setting.value.Len(); i++ {
+				item := setting.value.Index(i)
+				item.SetString(resolve(fmt.Sprintf("%s[%d]", setting.key(), i), item.String()))
+			}
+		}
+	}
+	return problems
+}
+
+func resolveReference(value string, keystore *Keystore, hasKeystore bool) (string, error) {
+	if !strings.HasPrefix(value, KEYSTORE_REFERENCE) {
+		return resolveFile(value)
+	}
+	if !hasKeystore {
+		return "", fmt.Errorf("%s needs keystore.file", value)
+	}
+	if keystore == nil {
+		return "", errors.New("the keystore could not be opened")
+	}
+	return keystore.Get(strings.TrimPrefix(value, KEYSTORE_REFERENCE))
+}
+
+// resolveFile reads file references without their trailing newline, other values are returned as they are
+func resolveFile(value string) (string, error) {
+	if !strings.HasPrefix(value, FILE_REFERENCE) {
+		return value, nil
+	}
+	content, err := os.ReadFile(strings.TrimPrefix(value, FILE_REFERENCE))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
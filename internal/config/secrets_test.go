@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestKeystore(t *testing.T) (*Keystore, string) {
+	key, err := NewKeystoreKey()
+	assert.NoError(t, err)
+	keystore, err := OpenKeystore(filepath.Join(t.TempDir(), "keystore.json"), key)
+	assert.NoError(t, err)
+	return keystore, key
+}
+
+func TestKeystore_SecretsSurviveSaving(t *testing.T) {
+	keystore, key := newTestKeystore(t)
+	assert.NoError(t, keystore.Set("otp-key", "first"))
+	assert.NoError(t, keystore.Set("otp-key", "second"))
+	assert.NoError(t, keystore.Set("db-password", "postgres"))
+	assert.NoError(t, keystore.Save())
+
+	opened, err := OpenKeystore(keystore.file, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"db-password", "otp-key"}, opened.Names())
+	secret, err := opened.Get("otp-key")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", secret)
+
+	opened.Delete("otp-key")
+	_, err = opened.Get("otp-key")
+	assert.EqualError(t, err, "no secret otp-key in keystore "+keystore.file)
+}
+
+func TestKeystore_RejectsTheWrongKey(t *testing.T) {
+	keystore, _ := newTestKeystore(t)
+	assert.NoError(t, keystore.Set("otp-key", "secret"))
+	assert.NoError(t, keystore.Save())
+
+	_, err := OpenKeystore(keystore.file, "not a key")
+	assert.EqualError(t, err, "the keystore key has to be 32 base64 encoded bytes")
+	otherKey, err := NewKeystoreKey()
+	assert.NoError(t, err)
+	opened, err := OpenKeystore(keystore.file, otherKey)
+	assert.NoError(t, err)
+	_, err = opened.Get("otp-key")
+	assert.EqualError(t, err, "decrypting secret otp-key of keystore "+keystore.file+" failed, is the keystore key right?")
+}
+
+func TestLoad_ResolvesSecretReferences(t *testing.T) {
+	keystore, key := newTestKeystore(t)
+	assert.NoError(t, keystore.Set("otp-key", "from-keystore"))
+	assert.NoError(t, keystore.Save())
+	directory := t.TempDir()
+	keyFile := filepath.Join(directory, "keystore-key")
+	secretFile := filepath.Join(directory, "previous-otp-key")
+	assert.NoError(t, os.WriteFile(keyFile, []byte(key+"\n"), 0600))
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	config, _, err := Load([]string{
+		"--keystore.file", keystore.file,
+		"--keystore.key", FILE_REFERENCE + keyFile,
+		"--otp.secret-key", KEYSTORE_REFERENCE + "otp-key",
+		"--otp.previous-secret-keys", FILE_REFERENCE + secretFile + ",plain",
+	}, credentials)
+	assert.NoError(t, err)
+	assert.Equal(t, key, config.KeystoreConfig.Key)
+	assert.Equal(t, "from-keystore", config.OTPConfig.SecretKey)
+	assert.Equal(t, []string{"from-file", "plain"}, config.OTPConfig.PreviousSecretKeys)
+}
+
+func TestLoad_ReportsUnresolvedSecretReferences(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	_, _, err := Load([]string{
+		"--otp.secret-key", KEYSTORE_REFERENCE + "otp-key",
+		"--messaging.webhook.secret", FILE_REFERENCE + missing,
+	}, credentials)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	assert.Equal(t, []string{
+		"messaging.webhook.secret: open " + missing + ": no such file or directory",
+		"otp.secret_key: keystore:otp-key needs keystore.file",
+	}, validationErr.Problems)
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// validate returns every problem with the config, named by the keys of the settings
+func validate(config Config, settings []setting) []string {
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	for _, setting := range settings {
+		if setting.value.Kind() != reflect.Int && setting.value.Kind() != reflect.Int64 {
+			continue
+		}
+		switch {
+		case setting.value.Int() < 0:
+			problem("%s must not be negative", setting.key())
+		case setting.value.Int() == 0 && !setting.zero:
+			problem("%s must be positive", setting.key())
+		}
+	}
+	required := func(key string, value string) {
+		if value == "" {
+			problem("%s is required", key)
+		}
+	}
+	oneOf := func(key string, value string, allowed ...string) {
+		for _, option := range allowed {
+			if value == option {
+				return
+			}
+		}
+		problem("%s must be one of %v, got %q", key, allowed, value)
+	}
+	ordered := func(minKey string, min time.Duration, maxKey string, max time.Duration) {
+		if min > max {
+			problem("%s must not exceed %s", minKey, maxKey)
+		}
+	}
+
+	required("server.listen_address", config.ServerConfig.ListenAddress)
+	oneOf("log.level", config.LogConfig.Level, "debug", "info", "warn", "error")
+	if len(config.PhoneConfig.AllowedCountryCodes) == 0 {
+		problem("phone.allowed_country_codes is required")
+	}
+	for i, code := range config.PhoneConfig.AllowedCountryCodes {
+		if code < 1 || code > 999 {
+			problem("phone.allowed_country_codes[%d] must be a country calling code from 1 to 999, got %d", i, code)
+		}
+	}
+	if !config.DatabaseConfig.InMemory {
+		required("database.connection_string", config.DatabaseConfig.ConnectionString)
+	}
+
+	messaging := config.MessagingConfig
+	oneOf("messaging.transport", messaging.Transport, "rabbitmq", "kafka", "nats", "webhook", "log")
+	required("messaging.sms_routing_key", messaging.SmsRoutingKey)
+	required("messaging.voice_routing_key", messaging.VoiceRoutingKey)
+	required("messaging.email_routing_key", messaging.EmailRoutingKey)
+	ordered("messaging.min_publish_backoff", messaging.MinPublishBackoff, "messaging.max_publish_backoff", messaging.MaxPublishBackoff)
+	switch messaging.Transport {
+	case "rabbitmq":
+		required("rabbitmq.connection_string", config.RabbitMQConfig.ConnectionString)
+		required("rabbitmq.topology.exchange", config.RabbitMQConfig.Topology.Exchange)
+		oneOf("rabbitmq.topology.exchange_type", config.RabbitMQConfig.Topology.ExchangeType, "direct", "topic")
+		for i, queue := range config.RabbitMQConfig.Topology.Queues {
+			required(fmt.Sprintf("rabbitmq.topology.queues[%d].name", i), queue.Name)
+		}
+		required("messaging.receipt_queue", messaging.ReceiptQueue)
+		required("messaging.receipt_routing_key", messaging.ReceiptRoutingKey)
+	case "kafka":
+		if len(messaging.Kafka.Brokers) == 0 {
+			problem("messaging.kafka.brokers is required")
+		}
+		required("messaging.kafka.topic", messaging.Kafka.Topic)
+		required("messaging.kafka.receipt_topic", messaging.Kafka.ReceiptTopic)
+		required("messaging.kafka.group_id", messaging.Kafka.GroupId)
+	case "nats":
+		required("messaging.nats.url", messaging.Nats.Url)
+		required("messaging.receipt_queue", messaging.ReceiptQueue)
+		required("messaging.receipt_routing_key", messaging.ReceiptRoutingKey)
+	case "webhook":
+		required("messaging.webhook.url", messaging.Webhook.Url)
+		required("messaging.webhook.secret", messaging.Webhook.Secret)
+	}
+	ordered("rabbitmq.min_reconnect_delay", config.RabbitMQConfig.MinReconnectDelay, "rabbitmq.max_reconnect_delay", config.RabbitMQConfig.MaxReconnectDelay)
+
+	required("otp.secret_key", config.OTPConfig.SecretKey)
+	oneOf("otp.mode", config.OTPConfig.Mode, "derived", "server")
+	if config.OTPConfig.Mode == "server" {
+		required("otp.delivery_public_key_file", config.OTPConfig.DeliveryPublicKeyFile)
+	}
+	for i, key := range config.OTPConfig.PreviousSecretKeys {
+		required(fmt.Sprintf("otp.previous_secret_keys[%d]", i), key)
+	}
+	if config.KeystoreConfig.File != "" {
+		required("keystore.key", config.KeystoreConfig.Key)
+	}
+	ordered("outbox.min_retry_delay", config.OutboxConfig.MinRetryDelay, "outbox.max_retry_delay", config.OutboxConfig.MaxRetryDelay)
+	return problems
+}
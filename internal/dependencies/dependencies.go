@@ -3,83 +3,412 @@ package dependencies
 import (
 	"auth-service/internal/config"
 	"auth-service/internal/gateway"
+	"auth-service/internal/migrations"
 	"auth-service/internal/repository"
 	"auth-service/internal/service"
 	"auth-service/internal/validators"
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	_ "github.com/lib/pq"
-	"github.com/streadway/amqp"
 	"log"
+	"log/slog"
+	"sync"
+	"time"
 )
 
 type Dependencies struct {
-	Db                 *sql.DB
-	AuthService        service.IAuthService
-	RabbitMQConnection *amqp.Connection
-	Channel            *amqp.Channel
-	GateWayService     gateway.IMessagePublisher
+	Db          *sql.DB
+	AuthService service.IAuthService
+	// GateWayService publishes otp requests to the transport selected by MessagingConfig.Transport
+	GateWayService gateway.ITransport
+	// IdempotencyKeys stores responses replayed to retried requests
+	IdempotencyKeys repository.IIdempotencyRepository
+	// active is the config the running services use, Reload swaps it along with the runtime settings below
+	active         *service.Tunable[config.Config]
+	otpKeys        *service.OtpKeys
+	authConfig     *service.Tunable[service.AuthServiceConfig]
+	codesConfig    *service.Tunable[service.OtpCodesConfig]
+	stopBackground context.CancelFunc
+	background     *sync.WaitGroup
+}
+
+type repositories struct {
+	users       repository.IUserRepository
+	events      repository.IEventRepository
+	outbox      repository.IOutboxRepository
+	idempotency repository.IIdempotencyRepository
+	exports     repository.IDataExportRepository
+	deliveries  repository.IOtpDeliveryRepository
+	codes       repository.IOtpCodeRepository
 }
 
 func Initialize(config config.Config) (*Dependencies, error) {
-	db, err := sql.Open("postgres", config.DatabaseConfig.ConnectionString)
+	db, repositories, err := initializeRepositories(config.DatabaseConfig)
 	if err != nil {
 		return nil, err
 	}
-	if err = db.Ping(); err != nil {
-		log.Fatal(err)
-		return nil, err
+	var disposableDomains []string
+	if config.EmailConfig.DisposableDomainsFile != "" {
+		disposableDomains, err = validators.LoadDisposableDomains(config.EmailConfig.DisposableDomainsFile)
+		if err != nil {
+			return nil, err
+		}
 	}
-	conn, err := amqp.Dial(config.RabbitMQConfig.ConnectionString)
+	validators.SetAllowedCountryCodes(countryCodes(config.PhoneConfig))
+	validator := validators.NewValidator(validators.NewEmailPolicy(disposableDomains, config.EmailConfig.CanonicalizeGmail))
+	otpKeys := service.NewOtpKeys(config.OTPConfig.SecretKey, config.OTPConfig.PreviousSecretKeys)
+	generator := service.NewOtpGenerator(otpKeys, config.OTPConfig.Interval)
+	codesConfig := service.NewTunable(otpCodesConfig(config.OTPConfig))
+	codes, err := initializeOtpCodes(config.OTPConfig, otpKeys, codesConfig, repositories.codes)
 	if err != nil {
-		log.Fatal(err)
 		return nil, err
 	}
-	ch, err := conn.Channel()
+	ctx, stopBackground := context.WithCancel(context.Background())
+	background := &sync.WaitGroup{}
+	transport, receipts, err := initializeTransport(ctx, background, config)
 	if err != nil {
-		log.Fatal(err)
+		stopBackground()
 		return nil, err
 	}
-
-	queue, err := ch.QueueDeclare(
-		"teja", // name
-		false,  // durable
-		false,  // delete when unused
-		false,  // exclusive
-		false,  // no-wait
-		nil,    // arguments
-	)
-	publisher := gateway.NewRabbitMqPublisher(queue.Name, ch)
-	validator := validators.NewValidator()
-	newRepository := repository.NewUserRepository(db)
-	eventRepository := repository.NewEventRepository(db)
-	generator := service.NewOtpGenerator(config.OTPConfig.SecretKey, config.OTPConfig.Interval)
-	authService := service.NewAuthService(newRepository, validator, publisher, generator, eventRepository)
+	var publisher gateway.IMessagePublisher = transport
+	if codes != nil {
+		publisher = service.NewIssuingPublisher(publisher, codes)
+	}
+	// the tracking publisher assigns the request id the issued otp is bound to
+	publisher = service.NewTrackingPublisher(publisher, repositories.deliveries)
+	authConfig := service.NewTunable(authServiceConfig(config))
+	authService := service.NewAuthService(repositories.users, validator, publisher, generator, repositories.events, repositories.exports, repositories.deliveries, codes, authConfig)
+	relay := service.NewOutboxRelay(repositories.outbox, publisher, service.OutboxRelayConfig{
+		PollInterval:  config.OutboxConfig.PollInterval,
+		BatchSize:     config.OutboxConfig.BatchSize,
+		Lease:         config.OutboxConfig.Lease,
+		MinRetryDelay: config.OutboxConfig.MinRetryDelay,
+		MaxRetryDelay: config.OutboxConfig.MaxRetryDelay,
+		MaxAttempts:   int32(config.OutboxConfig.MaxAttempts),
+		MaxAge:        config.OTPConfig.Interval,
+	})
+	exporter := service.NewDataExporter(repositories.exports, repositories.users, repositories.events, service.DataExporterConfig{
+		PollInterval: config.DataExportConfig.PollInterval,
+		BatchSize:    config.DataExportConfig.BatchSize,
+		Lease:        config.DataExportConfig.Lease,
+	})
+	runInBackground(ctx, background, relay.Run)
+	runInBackground(ctx, background, exporter.Run)
+	if receipts != nil {
+		deliveryReceipts := service.NewDeliveryReceipts(repositories.deliveries, repositories.events)
+		runInBackground(ctx, background, func(ctx context.Context) {
+			receipts.Run(ctx, deliveryReceipts.Handle)
+		})
+	}
+	runInBackground(ctx, background, every(config.OTPConfig.DeliveryCleanupInterval, func(ctx context.Context) error {
+		_, err := repositories.deliveries.DeleteOldDeliveries(ctx, config.OTPConfig.DeliveryRetention)
+		return err
+	}))
+	if codes != nil {
+		runInBackground(ctx, background, every(config.OTPConfig.CodeCleanupInterval, func(ctx context.Context) error {
+			_, err := repositories.codes.DeleteExpiredCodes(ctx)
+			return err
+		}))
+	}
+	runInBackground(ctx, background, every(config.DataExportConfig.CleanupInterval, func(ctx context.Context) error {
+		_, err := repositories.exports.DeleteExpired(ctx)
+		return err
+	}))
+	runInBackground(ctx, background, every(config.IdempotencyConfig.CleanupInterval, func(ctx context.Context) error {
+		_, err := repositories.idempotency.DeleteExpired(ctx)
+		return err
+	}))
+	if config.SignupConfig.UnverifiedUserTTL > 0 {
+		runInBackground(ctx, background, every(config.SignupConfig.ReapInterval, func(ctx context.Context) error {
+			deleted, err := repositories.users.DeleteExpiredUnverified(ctx, config.SignupConfig.UnverifiedUserTTL)
+			if deleted > 0 {
+				log.Printf("Deleted %d unverified users older than %v", deleted, config.SignupConfig.UnverifiedUserTTL)
+			}
+			return err
+		}))
+	}
+	runInBackground(ctx, background, every(config.AccountConfig.PurgeInterval, func(ctx context.Context) error {
+		purged, err := repositories.users.PurgeDeletedUsers(ctx, config.AccountConfig.DeletionGracePeriod)
+		if purged > 0 {
+			log.Printf("Purged %d accounts deleted more than %v ago", purged, config.AccountConfig.DeletionGracePeriod)
+		}
+		return err
+	}))
 	return &Dependencies{
-		Db:                 db,
-		AuthService:        authService,
-		RabbitMQConnection: conn,
-		Channel:            ch,
+		Db:              db,
+		AuthService:     authService,
+		GateWayService:  transport,
+		IdempotencyKeys: repositories.idempotency,
+		active:          service.NewTunable(config),
+		otpKeys:         otpKeys,
+		authConfig:      authConfig,
+		codesConfig:     codesConfig,
+		stopBackground:  stopBackground,
+		background:      background,
 	}, nil
 }
 
-func (d Dependencies) ShutDown() error {
-	err := d.Db.Close()
+func authServiceConfig(config config.Config) service.AuthServiceConfig {
+	return service.AuthServiceConfig{
+		ResendCooldown:      config.OTPConfig.ResendCooldown,
+		ResendWindow:        config.OTPConfig.Interval,
+		MaxResends:          config.OTPConfig.MaxResends,
+		EmailEscalationAge:  config.OTPConfig.EmailEscalationAge,
+		UnverifiedUserTTL:   config.SignupConfig.UnverifiedUserTTL,
+		FreshLoginWindow:    config.AccountConfig.FreshLoginWindow,
+		DeletionGracePeriod: config.AccountConfig.DeletionGracePeriod,
+		DataExportTTL:       config.DataExportConfig.TTL,
+	}
+}
+
+func otpCodesConfig(config config.OTPConfig) service.OtpCodesConfig {
+	return service.OtpCodesConfig{
+		TTL:         config.Interval,
+		MaxAttempts: config.MaxVerifyAttempts,
+	}
+}
+
+func countryCodes(config config.PhoneConfig) []validators.CountryCode {
+	codes := make([]validators.CountryCode, len(config.AllowedCountryCodes))
+	for i, code := range config.AllowedCountryCodes {
+		codes[i] = validators.CountryCode(code)
+	}
+	return codes
+}
+
+// initializeOtpCodes returns the otp codes of server mode, it is nil in derived mode where the otp service derives otps
+func initializeOtpCodes(config config.OTPConfig, keys *service.OtpKeys, codesConfig *service.Tunable[service.OtpCodesConfig], codes repository.IOtpCodeRepository) (service.IOtpCodes, error) {
+	switch config.Mode {
+	case service.OTP_MODE_DERIVED:
+		return nil, nil
+	case service.OTP_MODE_SERVER:
+		if config.DeliveryPublicKeyFile == "" {
+			return nil, errors.New("the server otp mode needs the public key of the otp service")
+		}
+		deliveryKey, err := service.LoadDeliveryKey(config.DeliveryPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return service.NewOtpCodes(codes, keys, deliveryKey, codesConfig)
+	default:
+		return nil, fmt.Errorf("unknown otp mode %q", config.Mode)
+	}
+}
+
+// initializeTransport connects to the message transport and returns the consumer of delivery receipts, which is nil
+// for transports without receipts. The rabbit mq connection is kept open in the background
+func initializeTransport(ctx context.Context, background *sync.WaitGroup, config config.Config) (gateway.ITransport, gateway.IReceiptConsumer, error) {
+	messaging := config.MessagingConfig
+	routingKeys := gateway.RoutingKeys{
+		Sms:   messaging.SmsRoutingKey,
+		Voice: messaging.VoiceRoutingKey,
+		Email: messaging.EmailRoutingKey,
+	}
+	publisherConfig := gateway.PublisherConfig{
+		ConfirmTimeout: messaging.ConfirmTimeout,
+		MaxAttempts:    messaging.PublishAttempts,
+		MinRetryDelay:  messaging.MinPublishBackoff,
+		MaxRetryDelay:  messaging.MaxPublishBackoff,
+	}
+	// failing to store a receipt backs off like failing to publish
+	receiptConfig := gateway.ReceiptConfig{
+		Queue:         messaging.ReceiptQueue,
+		RoutingKey:    messaging.ReceiptRoutingKey,
+		MinRetryDelay: messaging.MinPublishBackoff,
+		MaxRetryDelay: messaging.MaxPublishBackoff,
+	}
+	switch messaging.Transport {
+	case gateway.TRANSPORT_RABBITMQ:
+		connections, err := gateway.NewConnectionManager(gateway.ConnectionConfig{
+			ConnectionString:  config.RabbitMQConfig.ConnectionString,
+			Topology:          topology(config.RabbitMQConfig.Topology, routingKeys),
+			MinReconnectDelay: config.RabbitMQConfig.MinReconnectDelay,
+			MaxReconnectDelay: config.RabbitMQConfig.MaxReconnectDelay,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		runInBackground(ctx, background, connections.Run)
+		return gateway.NewRabbitMqPublisher(connections, publisherConfig), gateway.NewRabbitMqReceiptConsumer(connections, receiptConfig), nil
+	case gateway.TRANSPORT_KAFKA:
+		publisher := gateway.NewKafkaPublisher(gateway.KafkaConfig{
+			Brokers:     messaging.Kafka.Brokers,
+			Topic:       messaging.Kafka.Topic,
+			RoutingKeys: routingKeys,
+		}, publisherConfig)
+		return publisher, gateway.NewKafkaReceiptConsumer(messaging.Kafka.Brokers, messaging.Kafka.ReceiptTopic, messaging.Kafka.GroupId, receiptConfig), nil
+	case gateway.TRANSPORT_NATS:
+		publisher, err := gateway.NewNatsPublisher(gateway.NatsConfig{
+			Url:         messaging.Nats.Url,
+			JetStream:   messaging.Nats.JetStream,
+			RoutingKeys: routingKeys,
+		}, publisherConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+		receipts, err := gateway.NewNatsReceiptConsumer(messaging.Nats.Url, receiptConfig)
+		if err != nil {
+			_ = publisher.Close()
+			return nil, nil, err
+		}
+		return publisher, receipts, nil
+	case gateway.TRANSPORT_WEBHOOK:
+		log.Println("Delivery receipts are not received over webhooks, the delivery status of otps stays pending")
+		return gateway.NewWebhookPublisher(gateway.WebhookConfig{
+			Url:         messaging.Webhook.Url,
+			Secret:      messaging.Webhook.Secret,
+			RoutingKeys: routingKeys,
+		}, publisherConfig), nil, nil
+	case gateway.TRANSPORT_LOG:
+		log.Println("Logging otp requests instead of sending them, phone numbers and emails end up in the log")
+		return gateway.NewLogPublisher(log.Default(), routingKeys), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown message transport %q", messaging.Transport)
+	}
+}
+
+func topology(config config.TopologyConfig, routingKeys gateway.RoutingKeys) gateway.Topology {
+	queues := make([]gateway.QueueBinding, 0, len(config.Queues))
+	for _, queue := range config.Queues {
+		queues = append(queues, gateway.QueueBinding{Name: queue.Name, RoutingKeys: queue.RoutingKeys})
+	}
+	return gateway.Topology{
+		Exchange:           config.Exchange,
+		ExchangeType:       config.ExchangeType,
+		RoutingKeys:        routingKeys,
+		Durable:            config.Durable,
+		Persistent:         config.Persistent,
+		Queues:             queues,
+		MessageTTL:         config.MessageTTL,
+		DeadLetterExchange: config.DeadLetterExchange,
+		DeadLetterQueue:    config.DeadLetterQueue,
+	}
+}
+
+// runInBackground starts a worker that ShutDown stops before closing the connections it uses
+func runInBackground(ctx context.Context, background *sync.WaitGroup, worker func(ctx context.Context)) {
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		worker(ctx)
+	}()
+}
+
+// every adapts a job to runInBackground, running it each interval and logging failures
+func every(interval time.Duration, job func(ctx context.Context) error) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(ctx); err != nil && ctx.Err() == nil {
+					slog.Error("background job failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+// initializeRepositories returns postgres repositories, or in-memory ones without a database when configured
+func initializeRepositories(config config.DatabaseConfig) (*sql.DB, repositories, error) {
+	if config.InMemory {
+		log.Println("Using in-memory repositories, data is lost on restart")
+		store := repository.NewMemoryStore()
+		return nil, repositories{
+			users:       repository.NewMemoryUserRepository(store),
+			events:      repository.NewMemoryEventRepository(store),
+			outbox:      repository.NewMemoryOutboxRepository(store),
+			idempotency: repository.NewMemoryIdempotencyRepository(store),
+			exports:     repository.NewMemoryDataExportRepository(store),
+			deliveries:  repository.NewMemoryOtpDeliveryRepository(store),
+			codes:       repository.NewMemoryOtpCodeRepository(store),
+		}, nil
+	}
+	db, err := OpenDatabase(config)
 	if err != nil {
 		log.Fatal(err)
-		return err
+		return nil, repositories{}, err
+	}
+	if config.MigrateOnStartup {
+		if err = migrateUp(db); err != nil {
+			return nil, repositories{}, err
+		}
 	}
-	log.Println("DatabaseConfig connection closed")
-	err = d.Channel.Close()
+	return db, repositories{
+		users:       repository.NewUserRepository(db),
+		events:      repository.NewEventRepository(db),
+		outbox:      repository.NewOutboxRepository(db),
+		idempotency: repository.NewIdempotencyRepository(db),
+		exports:     repository.NewDataExportRepository(db),
+		deliveries:  repository.NewOtpDeliveryRepository(db),
+		codes:       repository.NewOtpCodeRepository(db),
+	}, nil
+}
+
+// OpenDatabase connects to postgres and verifies the connection
+func OpenDatabase(config config.DatabaseConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.ConnectionString)
 	if err != nil {
-		log.Fatal(err)
-		return err
+		return nil, err
+	}
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
-	log.Println("RabbitMQConfig Channel closed")
-	err = d.RabbitMQConnection.Close()
+	return db, nil
+}
+
+func migrateUp(db *sql.DB) error {
+	migrator, err := migrations.NewMigrator(db)
 	if err != nil {
+		return err
+	}
+	return migrator.Up(context.Background())
+}
+
+// Reload applies the runtime settings of a reloaded config while requests are served and logs every changed setting.
+// The other settings, like connection strings and credentials, keep their value until a restart
+func (d Dependencies) Reload(reloaded config.Config) {
+	active, changes := config.Reload(d.active.Load(), reloaded)
+	if len(changes) == 0 {
+		reloadLog.Info("Reloaded the config, nothing changed")
+		return
+	}
+	d.active.Swap(active)
+	d.otpKeys.Rotate(active.OTPConfig.SecretKey, active.OTPConfig.PreviousSecretKeys)
+	d.authConfig.Swap(authServiceConfig(active))
+	d.codesConfig.Swap(otpCodesConfig(active.OTPConfig))
+	validators.SetAllowedCountryCodes(countryCodes(active.PhoneConfig))
+	setLogLevel(active.LogConfig)
+	for _, change := range changes {
+		if change.Applied {
+			reloadLog.Info("Config setting changed", "change", change.String())
+		} else {
+			reloadLog.Warn("Config setting changed, it is applied after a restart", "change", change.String())
+		}
+	}
+}
+
+func (d Dependencies) ShutDown() error {
+	d.stopBackground()
+	d.background.Wait()
+	log.Println("Background workers stopped")
+	if d.Db != nil {
+		if err := d.Db.Close(); err != nil {
+			log.Fatal(err)
+			return err
+		}
+		log.Println("DatabaseConfig connection closed")
+	}
+	if err := d.GateWayService.Close(); err != nil {
 		log.Fatal(err)
 		return err
 	}
-	log.Println("RabbitMQConfig connection closed")
+	log.Println("Message transport closed")
 	return nil
 }
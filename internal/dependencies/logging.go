@@ -0,0 +1,27 @@
+package dependencies
+
+import (
+	"auth-service/internal/config"
+	"log/slog"
+	"os"
+)
+
+// logLevel filters what is logged, it is set on startup and swapped when the config is reloaded
+var logLevel = new(slog.LevelVar)
+
+// reloadLog logs config changes at every log level, they explain why the service behaves differently
+var reloadLog = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+// ConfigureLogging logs lines of key=value pairs with their level to stderr. Messages of the log package are logged at
+// the info level
+func ConfigureLogging(config config.LogConfig) {
+	setLogLevel(config)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+}
+
+func setLogLevel(config config.LogConfig) {
+	var level slog.Level
+	// the level was validated when the config was loaded
+	_ = level.UnmarshalText([]byte(config.Level))
+	logLevel.Set(level)
+}
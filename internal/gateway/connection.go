@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"github.com/streadway/amqp"
+	"log"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var errNotConnected = errors.New("not connected to rabbit mq, reconnecting")
+
+type ConnectionConfig struct {
+	ConnectionString  string
+	Topology          Topology
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
+}
+
+// amqpConnection is the part of *amqp.Connection used by the connection manager
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+type dialedConnection struct {
+	*amqp.Connection
+}
+
+func (c dialedConnection) Channel() (amqpChannel, error) {
+	channel, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// session is one connection with a confirm mode channel, it is replaced as a whole when either of them closes
+type session struct {
+	connection       amqpConnection
+	channel          amqpChannel
+	confirmations    chan amqp.Confirmation
+	returns          chan amqp.Return
+	connectionClosed chan *amqp.Error
+	channelClosed    chan *amqp.Error
+	// mu serializes publishes, so the delivery tag the channel gives the next message is known while publishing it
+	mu          sync.Mutex
+	deliveryTag uint64
+	// pending are the published messages waiting for their confirmation by delivery tag, dispatch resolves them
+	pendingMu sync.Mutex
+	pending   map[uint64]*pendingConfirmation
+	// closed is set once the channel closed, nothing is confirmed afterwards
+	closed bool
+}
+
+// ConnectionManager keeps a rabbit mq connection open, reconnecting with backoff whenever the broker closes it
+type ConnectionManager struct {
+	dial   func() (amqpConnection, error)
+	config ConnectionConfig
+	mu     sync.RWMutex
+	// current is nil while reconnecting
+	current *session
+}
+
+// NewConnectionManager connects to rabbit mq, Run has to be started to recover from lost connections
+func NewConnectionManager(config ConnectionConfig) (*ConnectionManager, error) {
+	return newConnectionManager(config, func() (amqpConnection, error) {
+		connection, err := amqp.Dial(config.ConnectionString)
+		if err != nil {
+			return nil, err
+		}
+		return dialedConnection{connection}, nil
+	})
+}
+
+func newConnectionManager(config ConnectionConfig, dial func() (amqpConnection, error)) (*ConnectionManager, error) {
+	manager := &ConnectionManager{dial: dial, config: config}
+	current, err := manager.connect()
+	if err != nil {
+		return nil, err
+	}
+	manager.current = current
+	return manager, nil
+}
+
+// Run reconnects whenever the connection or channel is closed until the context is cancelled
+func (m *ConnectionManager) Run(ctx context.Context) {
+	for {
+		m.mu.RLock()
+		current := m.current
+		m.mu.RUnlock()
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-current.connectionClosed:
+			slog.Warn("rabbit mq connection closed", "error", err)
+		case err := <-current.channelClosed:
+			slog.Warn("rabbit mq channel closed", "error", err)
+		}
+		m.mu.Lock()
+		m.current = nil
+		m.mu.Unlock()
+		_ = current.connection.Close()
+		if !m.reconnect(ctx) {
+			return
+		}
+	}
+}
+
+func (m *ConnectionManager) reconnect(ctx context.Context) bool {
+	delay := m.config.MinReconnectDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		next, err := m.connect()
+		if err == nil {
+			m.mu.Lock()
+			m.current = next
+			m.mu.Unlock()
+			log.Printf("Reconnected to rabbit mq after %d attempts", attempt)
+			return true
+		}
+		slog.Warn("reconnecting to rabbit mq failed", "attempt", attempt, "error", err)
+		if delay *= 2; delay > m.config.MaxReconnectDelay {
+			delay = m.config.MaxReconnectDelay
+		}
+	}
+}
+
+// connect dials the broker, declares the topology and puts a new channel in confirm mode
+func (m *ConnectionManager) connect() (*session, error) {
+	connection, err := m.dial()
+	if err != nil {
+		return nil, err
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+	err = m.config.Topology.declare(channel)
+	if err == nil {
+		err = channel.Confirm(false)
+	}
+	if err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+	current := &session{
+		connection:       connection,
+		channel:          channel,
+		confirmations:    channel.NotifyPublish(make(chan amqp.Confirmation, 16)),
+		returns:          channel.NotifyReturn(make(chan amqp.Return, 16)),
+		connectionClosed: connection.NotifyClose(make(chan *amqp.Error, 1)),
+		channelClosed:    channel.NotifyClose(make(chan *amqp.Error, 1)),
+		pending:          map[uint64]*pendingConfirmation{},
+	}
+	go current.dispatch()
+	return current, nil
+}
+
+// session returns the open session, publishes fail fast while reconnecting and are retried by the publisher
+func (m *ConnectionManager) session() (*session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.current == nil {
+		return nil, errNotConnected
+	}
+	return m.current, nil
+}
+
+// Close closes the current connection, Run has to be stopped first so it is not reopened
+func (m *ConnectionManager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return nil
+	}
+	err := m.current.connection.Close()
+	m.current = nil
+	return err
+}
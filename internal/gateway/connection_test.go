@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBroker hands out a new connection for every dial, failing the dials listed in failures
+type fakeBroker struct {
+	mu          sync.Mutex
+	dials       int
+	failures    map[int]bool
+	connections []*fakeConnection
+}
+
+func (f *fakeBroker) dial() (amqpConnection, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dials++
+	if f.failures[f.dials] {
+		return nil, errors.New("connection refused")
+	}
+	connection := &fakeConnection{channel: &fakeChannel{replies: []string{"ack", "ack"}}}
+	f.connections = append(f.connections, connection)
+	return connection, nil
+}
+
+func (f *fakeBroker) connection(i int) *fakeConnection {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i >= len(f.connections) {
+		return nil
+	}
+	return f.connections[i]
+}
+
+var testConnectionConfig = ConnectionConfig{Topology: testTopology, MinReconnectDelay: time.Millisecond, MaxReconnectDelay: 2 * time.Millisecond}
+
+func TestConnectionManager_ReconnectsAfterTheConnectionIsLost(t *testing.T) {
+	broker := &fakeBroker{failures: map[int]bool{2: true, 3: true}}
+	connections, err := newConnectionManager(testConnectionConfig, broker.dial)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go connections.Run(ctx)
+	publisher := NewRabbitMqPublisher(connections, PublisherConfig{ConfirmTimeout: 50 * time.Millisecond, MaxAttempts: 50, MinRetryDelay: time.Millisecond, MaxRetryDelay: 5 * time.Millisecond})
+	assert.NoError(t, publisher.Publish(ctx, &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}))
+
+	first := broker.connection(0)
+	first.closed <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "broker shutdown"}
+	first.channel.close()
+
+	// publishing fails over to the new connection once the failed dials were retried
+	assert.NoError(t, publisher.Publish(ctx, &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}))
+	second := broker.connection(1)
+	if assert.NotNil(t, second) {
+		assert.Equal(t, []string{"otp.requests"}, second.channel.declared)
+		assert.Len(t, second.channel.published, 1)
+	}
+	assert.Len(t, first.channel.published, 1)
+}
+
+func TestConnectionManager_PublishFailsFastWhileReconnecting(t *testing.T) {
+	broker := &fakeBroker{}
+	connections, err := newConnectionManager(testConnectionConfig, broker.dial)
+	assert.NoError(t, err)
+	connections.current = nil
+	publisher := NewRabbitMqPublisher(connections, testPublisherConfig)
+	err = publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.ErrorIs(t, err, ErrDeliveryUnavailable)
+	assert.ErrorIs(t, err, errNotConnected)
+}
+
+func TestConnectionManager_FailsWhenTheBrokerIsUnreachable(t *testing.T) {
+	broker := &fakeBroker{failures: map[int]bool{1: true}}
+	_, err := newConnectionManager(testConnectionConfig, broker.dial)
+	assert.EqualError(t, err, "connection refused")
+}
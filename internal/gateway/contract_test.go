@@ -0,0 +1,109 @@
+package gateway_test
+
+import (
+	"auth-service/internal/gateway"
+	"auth-service/internal/gateway/gatewaytest"
+	otp "auth-service/internal/gen/otp/v1"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var routingKeys = gateway.RoutingKeys{Sms: "otp.sms", Voice: "otp.voice", Email: "otp.email"}
+
+// decoded adapts a backend returning raw message bodies to gatewaytest.Transport.Received
+func decoded(bodies func() [][]byte) func(t *testing.T, count int) []*otp.GenerateOTPRequest {
+	return func(t *testing.T, _ int) []*otp.GenerateOTPRequest {
+		var requests []*otp.GenerateOTPRequest
+		for _, body := range bodies() {
+			request := &otp.GenerateOTPRequest{}
+			assert.NoError(t, proto.Unmarshal(body, request))
+			requests = append(requests, request)
+		}
+		return requests
+	}
+}
+
+func TestRabbitMqPublisherContract(t *testing.T) {
+	gatewaytest.RunPublisherContractTests(t, func(t *testing.T) gatewaytest.Transport {
+		publisher, delivered, breakBroker := gateway.NewFakeRabbitMqTransport(t)
+		return gatewaytest.Transport{Publisher: publisher, Received: decoded(delivered), Break: breakBroker}
+	})
+}
+
+func TestKafkaPublisherContract(t *testing.T) {
+	gatewaytest.RunPublisherContractTests(t, func(t *testing.T) gatewaytest.Transport {
+		publisher, delivered, breakBroker := gateway.NewFakeKafkaTransport()
+		return gatewaytest.Transport{Publisher: publisher, Received: decoded(delivered), Break: breakBroker}
+	})
+}
+
+func TestNatsPublisherContract(t *testing.T) {
+	gatewaytest.RunPublisherContractTests(t, func(t *testing.T) gatewaytest.Transport {
+		publisher, delivered, breakBroker := gateway.NewFakeNatsTransport()
+		return gatewaytest.Transport{Publisher: publisher, Received: decoded(delivered), Break: breakBroker}
+	})
+}
+
+func TestWebhookPublisherContract(t *testing.T) {
+	gatewaytest.RunPublisherContractTests(t, func(t *testing.T) gatewaytest.Transport {
+		var mu sync.Mutex
+		var bodies [][]byte
+		status := http.StatusNoContent
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if status == http.StatusNoContent {
+				body, _ := io.ReadAll(request.Body)
+				bodies = append(bodies, body)
+			}
+			writer.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		publisher := gateway.NewWebhookPublisher(gateway.WebhookConfig{Url: server.URL, RoutingKeys: routingKeys}, gateway.TestPublisherConfig)
+		return gatewaytest.Transport{
+			Publisher: publisher,
+			Received: decoded(func() [][]byte {
+				mu.Lock()
+				defer mu.Unlock()
+				return bodies
+			}),
+			Break: func() {
+				mu.Lock()
+				defer mu.Unlock()
+				status = http.StatusServiceUnavailable
+			},
+		}
+	})
+}
+
+func TestLogPublisherContract(t *testing.T) {
+	gatewaytest.RunPublisherContractTests(t, func(t *testing.T) gatewaytest.Transport {
+		output := &bytes.Buffer{}
+		publisher := gateway.NewLogPublisher(log.New(output, "", 0), routingKeys)
+		return gatewaytest.Transport{
+			Publisher: publisher,
+			Received: func(t *testing.T, _ int) []*otp.GenerateOTPRequest {
+				var requests []*otp.GenerateOTPRequest
+				for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+					_, marshalled, found := strings.Cut(line, ": ")
+					if !found {
+						continue
+					}
+					request := &otp.GenerateOTPRequest{}
+					assert.NoError(t, protojson.Unmarshal([]byte(marshalled), request))
+					requests = append(requests, request)
+				}
+				return requests
+			},
+		}
+	})
+}
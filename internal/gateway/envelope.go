@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/segmentio/kafka-go"
+	"github.com/streadway/amqp"
+	"time"
+)
+
+// Every otp request is sent in the binary content mode of the CloudEvents binding of its transport, the body stays
+// the protobuf encoded request and the attributes are headers prefixed per binding
+const (
+	CLOUDEVENTS_SPEC_VERSION = "1.0"
+	EVENT_SOURCE             = "auth-service"
+	// OTP_REQUEST_SCHEMA_VERSION changes when a field of GenerateOTPRequest changes its meaning, added fields keep it
+	OTP_REQUEST_SCHEMA_VERSION = "1"
+
+	AMQP_ATTRIBUTE_PREFIX  = "cloudEvents:"
+	KAFKA_ATTRIBUTE_PREFIX = "ce_"
+	HTTP_ATTRIBUTE_PREFIX  = "ce-"
+)
+
+// MessageContext is carried from the rpc causing a message to the message, so consumers can correlate and trace it
+type MessageContext struct {
+	// CorrelationId identifies the rpc, consumers see the same id on every message it caused
+	CorrelationId string
+	// TraceParent and TraceState are the W3C trace context of the rpc
+	TraceParent string
+	TraceState  string
+}
+
+type messageContextKey struct{}
+
+// WithMessageContext returns a context whose published messages carry the message context
+func WithMessageContext(ctx context.Context, messageContext MessageContext) context.Context {
+	return context.WithValue(ctx, messageContextKey{}, messageContext)
+}
+
+// MessageContextFrom returns the message context of ctx, it is empty outside of rpcs
+func MessageContextFrom(ctx context.Context) MessageContext {
+	messageContext, _ := ctx.Value(messageContextKey{}).(MessageContext)
+	return messageContext
+}
+
+// NewMessageId returns a random id for a message, consumers dedupe messages by it
+func NewMessageId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Envelope is the metadata sent with an otp request
+type Envelope struct {
+	// Id is the request id of the otp request, it is the same on every attempt to publish it
+	Id     string
+	Source string
+	// Type is the protobuf name of the body
+	Type          string
+	Time          time.Time
+	SchemaVersion string
+	MessageContext
+}
+
+func newEnvelope(ctx context.Context, request *otp.GenerateOTPRequest) (Envelope, error) {
+	id := request.RequestId
+	if id == "" {
+		var err error
+		if id, err = NewMessageId(); err != nil {
+			return Envelope{}, err
+		}
+	}
+	return Envelope{
+		Id:             id,
+		Source:         EVENT_SOURCE,
+		Type:           string(request.ProtoReflect().Descriptor().FullName()),
+		Time:           time.Now().UTC(),
+		SchemaVersion:  OTP_REQUEST_SCHEMA_VERSION,
+		MessageContext: MessageContextFrom(ctx),
+	}, nil
+}
+
+// each calls add with every CloudEvents attribute that is set, the trace context uses the distributed tracing extension
+func (e Envelope) each(add func(name, value string)) {
+	for _, attribute := range [][2]string{
+		{"specversion", CLOUDEVENTS_SPEC_VERSION},
+		{"id", e.Id},
+		{"source", e.Source},
+		{"type", e.Type},
+		{"time", e.Time.Format(time.RFC3339Nano)},
+		{"schemaversion", e.SchemaVersion},
+		{"correlationid", e.CorrelationId},
+		{"traceparent", e.TraceParent},
+		{"tracestate", e.TraceState},
+	} {
+		if attribute[1] != "" {
+			add(attribute[0], attribute[1])
+		}
+	}
+}
+
+func (e Envelope) amqpHeaders() amqp.Table {
+	headers := amqp.Table{}
+	e.each(func(name, value string) {
+		headers[AMQP_ATTRIBUTE_PREFIX+name] = value
+	})
+	return headers
+}
+
+func (e Envelope) kafkaHeaders() []kafka.Header {
+	var headers []kafka.Header
+	e.each(func(name, value string) {
+		headers = append(headers, kafka.Header{Key: KAFKA_ATTRIBUTE_PREFIX + name, Value: []byte(value)})
+	})
+	return headers
+}
+
+// setHeaders sets the attributes as http style headers, which nats uses as well
+func (e Envelope) setHeaders(set func(key, value string)) {
+	e.each(func(name, value string) {
+		set(HTTP_ATTRIBUTE_PREFIX+name, value)
+	})
+}
@@ -0,0 +1,40 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestNewEnvelope_CarriesTheMessageContext(t *testing.T) {
+	ctx := WithMessageContext(context.Background(), MessageContext{CorrelationId: "rpc-1", TraceParent: testTraceParent, TraceState: "vendor=1"})
+	envelope, err := newEnvelope(ctx, &otp.GenerateOTPRequest{RequestId: "request-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "request-1", envelope.Id)
+	assert.Equal(t, "rpc-1", envelope.CorrelationId)
+	assert.Equal(t, testTraceParent, envelope.TraceParent)
+	assert.Equal(t, "vendor=1", envelope.TraceState)
+	assert.Equal(t, "com.service.otp.GenerateOTPRequest", envelope.Type)
+}
+
+func TestNewEnvelope_IdentifiesRequestsWithoutRequestId(t *testing.T) {
+	first, err := newEnvelope(context.Background(), &otp.GenerateOTPRequest{})
+	assert.NoError(t, err)
+	second, err := newEnvelope(context.Background(), &otp.GenerateOTPRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, first.Id, 32)
+	assert.NotEqual(t, first.Id, second.Id)
+}
+
+func TestEnvelope_LeavesOutMissingAttributes(t *testing.T) {
+	envelope, err := newEnvelope(context.Background(), &otp.GenerateOTPRequest{RequestId: "request-1"})
+	assert.NoError(t, err)
+	headers := envelope.amqpHeaders()
+	assert.Equal(t, CLOUDEVENTS_SPEC_VERSION, headers["cloudEvents:specversion"])
+	assert.Equal(t, EVENT_SOURCE, headers["cloudEvents:source"])
+	assert.NotContains(t, headers, "cloudEvents:correlationid")
+	assert.NotContains(t, headers, "cloudEvents:traceparent")
+}
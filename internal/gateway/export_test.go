@@ -0,0 +1,49 @@
+package gateway
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// the contract tests of package gateway_test run against the fake backends of this package
+
+// NewFakeRabbitMqTransport returns a publisher on a fake broker, the bodies the broker acked, and a function
+// making the broker nack every message
+func NewFakeRabbitMqTransport(t *testing.T) (ITransport, func() [][]byte, func()) {
+	channel := &fakeChannel{fallback: "ack"}
+	connections, err := newConnectionManager(ConnectionConfig{Topology: testTopology}, func() (amqpConnection, error) {
+		return &fakeConnection{channel: channel}, nil
+	})
+	assert.NoError(t, err)
+	return NewRabbitMqPublisher(connections, testPublisherConfig),
+		func() [][]byte { return channel.delivered },
+		func() { channel.fallback = "nack" }
+}
+
+func NewFakeKafkaTransport() (ITransport, func() [][]byte, func()) {
+	writer := &fakeKafkaWriter{}
+	return newKafkaPublisher(writer, testTopology.RoutingKeys, testPublisherConfig),
+		func() [][]byte {
+			values := make([][]byte, 0, len(writer.messages))
+			for _, message := range writer.messages {
+				values = append(values, message.Value)
+			}
+			return values
+		},
+		func() { writer.broken = true }
+}
+
+func NewFakeNatsTransport() (ITransport, func() [][]byte, func()) {
+	backend := &fakeNats{}
+	return newNatsPublisher(backend.publish, func() error { return nil }, testTopology.RoutingKeys, testPublisherConfig),
+		func() [][]byte {
+			values := make([][]byte, 0, len(backend.messages))
+			for _, message := range backend.messages {
+				values = append(values, message.Data)
+			}
+			return values
+		},
+		func() { backend.broken = true }
+}
+
+var TestPublisherConfig = testPublisherConfig
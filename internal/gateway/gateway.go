@@ -2,39 +2,316 @@ package gateway
 
 import (
 	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"fmt"
 	"github.com/streadway/amqp"
 	"google.golang.org/protobuf/proto"
+	"strconv"
+	"time"
 )
 
 type IMessagePublisher interface {
-	Publish(request *otp.GenerateOTPRequest) error
+	Publish(ctx context.Context, request *otp.GenerateOTPRequest) error
 }
 
-func NewRabbitMqPublisher(queueName string, channel *amqp.Channel) IMessagePublisher {
+// ITransport is a message publisher owning its connection to the broker, Close releases it
+type ITransport interface {
+	IMessagePublisher
+	Close() error
+}
+
+// Transports selectable in config
+const (
+	TRANSPORT_RABBITMQ = "rabbitmq"
+	TRANSPORT_KAFKA    = "kafka"
+	TRANSPORT_NATS     = "nats"
+	TRANSPORT_WEBHOOK  = "webhook"
+	TRANSPORT_LOG      = "log"
+)
+
+// ErrDeliveryUnavailable matches a DeliveryUnavailableError with errors.Is
+var ErrDeliveryUnavailable = errors.New("otp delivery is unavailable, please try again after some time")
+
+// DeliveryUnavailableError is returned when the broker did not confirm a message after all attempts
+type DeliveryUnavailableError struct {
+	Attempts int
+	// Err is the failure of the last attempt
+	Err error
+}
+
+func (e *DeliveryUnavailableError) Error() string {
+	return fmt.Sprintf("%v: not confirmed after %d attempts: %v", ErrDeliveryUnavailable, e.Attempts, e.Err)
+}
+
+func (e *DeliveryUnavailableError) Is(target error) bool {
+	return target == ErrDeliveryUnavailable
+}
+
+func (e *DeliveryUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// publishTagHeader tells the attempts to publish a message apart, consumers can ignore it
+const publishTagHeader = "x-publish-tag"
+
+var (
+	errNacked    = errors.New("message was nacked by the broker")
+	errReturned  = errors.New("message was returned by the broker, no queue is bound to its routing key")
+	errTimeout   = errors.New("timed out waiting for the broker to confirm the message")
+	errNoConfirm = errors.New("channel closed before the broker confirmed the message")
+)
+
+type PublisherConfig struct {
+	// ConfirmTimeout is how long one attempt waits for the broker to ack the message
+	ConfirmTimeout time.Duration
+	// MaxAttempts is how often a nacked, returned or unconfirmed message is published before giving up
+	MaxAttempts   int
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// retry runs publish until it succeeds, giving each attempt ConfirmTimeout and backing off between attempts
+func (c PublisherConfig) retry(ctx context.Context, publish func(ctx context.Context) error) error {
+	delay := c.MinRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, publish)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= c.MaxAttempts {
+			return &DeliveryUnavailableError{Attempts: attempt, Err: err}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.MaxRetryDelay {
+			delay = c.MaxRetryDelay
+		}
+	}
+}
+
+func (c PublisherConfig) attempt(ctx context.Context, publish func(ctx context.Context) error) error {
+	attemptCtx, cancel := context.WithTimeout(ctx, c.ConfirmTimeout)
+	defer cancel()
+	err := publish(attemptCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		return errTimeout
+	}
+	return err
+}
+
+// amqpChannel is the part of *amqp.Channel used by the publisher, the receipt consumer and the connection manager
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Confirm(noWait bool) error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
+	NotifyClose(c chan *amqp.Error) chan *amqp.Error
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Close() error
+}
+
+// NewRabbitMqPublisher publishes to the exchange of the connection manager, messages are only reported as published
+// once the broker acked them
+func NewRabbitMqPublisher(connections *ConnectionManager, config PublisherConfig) ITransport {
 	return &rabbitMqPublisher{
-		queueName: queueName,
-		channel:   channel,
+		topology:    connections.config.Topology,
+		connections: connections,
+		config:      config,
 	}
 }
 
 type rabbitMqPublisher struct {
-	queueName string
-	channel   *amqp.Channel
+	topology    Topology
+	connections *ConnectionManager
+	config      PublisherConfig
 }
 
-func (r rabbitMqPublisher) Publish(request *otp.GenerateOTPRequest) error {
+func (r *rabbitMqPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	// the amqp client has no context aware publish, so at least skip publishing for abandoned requests
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	marshalledBytes, err := proto.Marshal(request)
 	if err != nil {
 		return err
 	}
-	err = r.channel.Publish("", r.queueName, false, false, amqp.Publishing{
-		ContentType: "application/octet-stream",
-		Body:        marshalledBytes,
+	envelope, err := newEnvelope(ctx, request)
+	if err != nil {
+		return err
+	}
+	routingKey := r.topology.RoutingKeys.of(request.Channel)
+	return r.config.retry(ctx, func(ctx context.Context) error {
+		return r.publishOnce(ctx, routingKey, marshalledBytes, envelope)
 	})
-	return err
 }
 
-type RabbitMQConnectionDetails struct {
-	Queue   *amqp.Queue
-	Channel *amqp.Channel
+// Close closes the connection, the connection manager has to be stopped first so it is not reopened
+func (r *rabbitMqPublisher) Close() error {
+	return r.connections.Close()
+}
+
+// publishOnce publishes a mandatory message on the current session and waits for the broker to confirm it. Other
+// messages are published while it waits, the session only serializes the publishing itself
+func (r *rabbitMqPublisher) publishOnce(ctx context.Context, routingKey string, body []byte, envelope Envelope) error {
+	current, err := r.connections.session()
+	if err != nil {
+		return err
+	}
+	tag, confirmed, err := current.publish(r.topology.Exchange, routingKey, amqp.Publishing{
+		Headers:       envelope.amqpHeaders(),
+		ContentType:   "application/octet-stream",
+		DeliveryMode:  r.topology.deliveryMode(),
+		CorrelationId: envelope.CorrelationId,
+		MessageId:     envelope.Id,
+		Timestamp:     envelope.Time,
+		Type:          envelope.Type,
+		AppId:         envelope.Source,
+		Body:          body,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		// the confirmation arriving late is dropped
+		current.forget(tag)
+		return ctx.Err()
+	case err := <-confirmed:
+		return err
+	}
+}
+
+// pendingConfirmation is a published message waiting for the broker to confirm it
+type pendingConfirmation struct {
+	returned bool
+	// result receives the outcome once, it is buffered so dispatch never blocks on it
+	result chan error
+}
+
+// publish publishes a mandatory message and returns its delivery tag and the channel its outcome is sent to
+func (s *session) publish(exchange string, routingKey string, message amqp.Publishing) (uint64, <-chan error, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tag := s.deliveryTag + 1
+	// every attempt has the same message id, the delivery tag in a header matches returned messages to this attempt
+	message.Headers[publishTagHeader] = strconv.FormatUint(tag, 10)
+	// the confirmation can arrive before Publish returns, so it is expected first
+	confirmed := s.expect(tag)
+	if err := s.channel.Publish(exchange, routingKey, true, false, message); err != nil {
+		s.forget(tag)
+		return 0, nil, err
+	}
+	s.deliveryTag = tag
+	return tag, confirmed, nil
+}
+
+func (s *session) expect(tag uint64) <-chan error {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	pending := &pendingConfirmation{result: make(chan error, 1)}
+	if s.closed {
+		pending.result <- errNoConfirm
+		return pending.result
+	}
+	s.pending[tag] = pending
+	return pending.result
+}
+
+func (s *session) forget(tag uint64) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	delete(s.pending, tag)
+}
+
+// dispatch matches the confirmations and returned messages of the broker to the pending messages by delivery tag until
+// the channel closes. It keeps draining both, so the amqp client never blocks on a confirmation nobody waits for
+func (s *session) dispatch() {
+	returns := s.returns
+	for {
+		select {
+		case message, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			s.markReturned(message)
+		case confirmation, ok := <-s.confirmations:
+			if !ok {
+				s.abandonPending()
+				return
+			}
+			// the broker returns an unroutable mandatory message before acking it
+			s.drainReturns()
+			s.resolve(confirmation)
+		}
+	}
+}
+
+// drainReturns handles the returned messages that are already buffered
+func (s *session) drainReturns() {
+	for {
+		select {
+		case message, ok := <-s.returns:
+			if !ok {
+				return
+			}
+			s.markReturned(message)
+		default:
+			return
+		}
+	}
+}
+
+func (s *session) markReturned(message amqp.Return) {
+	publishTag, _ := message.Headers[publishTagHeader].(string)
+	tag, err := strconv.ParseUint(publishTag, 10, 64)
+	if err != nil {
+		return
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if pending, ok := s.pending[tag]; ok {
+		pending.returned = true
+	}
+}
+
+// resolve hands the outcome to the message of the confirmation, confirmations of attempts that gave up are dropped
+func (s *session) resolve(confirmation amqp.Confirmation) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	pending, ok := s.pending[confirmation.DeliveryTag]
+	if !ok {
+		return
+	}
+	delete(s.pending, confirmation.DeliveryTag)
+	switch {
+	case !confirmation.Ack:
+		pending.result <- errNacked
+	case pending.returned:
+		pending.result <- errReturned
+	default:
+		pending.result <- nil
+	}
+}
+
+// abandonPending fails the messages still waiting once the channel closed, they are published again on the next one
+func (s *session) abandonPending() {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.closed = true
+	for tag, pending := range s.pending {
+		pending.result <- errNoConfirm
+		delete(s.pending, tag)
+	}
 }
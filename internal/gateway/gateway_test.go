@@ -0,0 +1,260 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// fakeChannel answers every publish with the next scripted reply, and with fallback once they ran out
+type fakeChannel struct {
+	confirmations chan amqp.Confirmation
+	returns       chan amqp.Return
+	closed        chan *amqp.Error
+	isClosed      bool
+	replies       []string
+	fallback      string
+	delivered     [][]byte
+	exchanges     []string
+	declared      []string
+	arguments     map[string]amqp.Table
+	bindings      []string
+	published     []amqp.Publishing
+	routingKeys   []string
+	mandatory     []bool
+	receipts      chan amqp.Delivery
+	consumed      []string
+	// deliveryTag counts the messages the channel sent, failed publishes get no tag like with the amqp client
+	deliveryTag uint64
+	// onPublish is called once a message was published
+	onPublish func()
+}
+
+func (f *fakeChannel) ExchangeDeclare(name, kind string, durable, _, _, _ bool, _ amqp.Table) error {
+	f.exchanges = append(f.exchanges, fmt.Sprintf("%s %s durable=%v", name, kind, durable))
+	return nil
+}
+
+func (f *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, args amqp.Table) (amqp.Queue, error) {
+	f.declared = append(f.declared, name)
+	if f.arguments == nil {
+		f.arguments = map[string]amqp.Table{}
+	}
+	f.arguments[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (f *fakeChannel) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	f.bindings = append(f.bindings, fmt.Sprintf("%s <- %s %s", name, exchange, key))
+	return nil
+}
+
+func (f *fakeChannel) Confirm(bool) error {
+	return nil
+}
+
+func (f *fakeChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	f.confirmations = confirm
+	return confirm
+}
+
+func (f *fakeChannel) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	f.returns = c
+	return c
+}
+
+func (f *fakeChannel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	f.closed = c
+	return c
+}
+
+func (f *fakeChannel) Qos(int, int, bool) error {
+	return nil
+}
+
+func (f *fakeChannel) Consume(queue, _ string, _, _, _, _ bool, _ amqp.Table) (<-chan amqp.Delivery, error) {
+	f.consumed = append(f.consumed, queue)
+	return f.receipts, nil
+}
+
+func (f *fakeChannel) Close() error {
+	return nil
+}
+
+// close shuts the channel down the way the amqp client does, closing every notification channel
+func (f *fakeChannel) close() {
+	f.isClosed = true
+	close(f.confirmations)
+	close(f.returns)
+	close(f.closed)
+}
+
+type fakeConnection struct {
+	channel *fakeChannel
+	closed  chan *amqp.Error
+}
+
+func (f *fakeConnection) Channel() (amqpChannel, error) {
+	return f.channel, nil
+}
+
+func (f *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	f.closed = receiver
+	return receiver
+}
+
+func (f *fakeConnection) Close() error {
+	return nil
+}
+
+func (f *fakeChannel) Publish(exchange, key string, mandatory, _ bool, msg amqp.Publishing) error {
+	if f.isClosed {
+		return amqp.ErrClosed
+	}
+	f.published = append(f.published, msg)
+	f.routingKeys = append(f.routingKeys, exchange+" "+key)
+	f.mandatory = append(f.mandatory, mandatory)
+	reply := f.fallback
+	if len(f.replies) > 0 {
+		reply, f.replies = f.replies[0], f.replies[1:]
+	}
+	if reply == "error" {
+		return errors.New("channel closed")
+	}
+	f.deliveryTag++
+	tag := f.deliveryTag
+	if f.onPublish != nil {
+		defer f.onPublish()
+	}
+	switch reply {
+	case "ack":
+		f.delivered = append(f.delivered, msg.Body)
+		f.confirmations <- amqp.Confirmation{DeliveryTag: tag, Ack: true}
+	case "nack":
+		f.confirmations <- amqp.Confirmation{DeliveryTag: tag, Ack: false}
+	case "return":
+		f.returns <- amqp.Return{MessageId: msg.MessageId, Headers: msg.Headers}
+		f.confirmations <- amqp.Confirmation{DeliveryTag: tag, Ack: true}
+	}
+	return nil
+}
+
+var testPublisherConfig = PublisherConfig{
+	ConfirmTimeout: 50 * time.Millisecond,
+	MaxAttempts:    3,
+	MinRetryDelay:  time.Millisecond,
+	MaxRetryDelay:  2 * time.Millisecond,
+}
+
+func newTestPublisher(t *testing.T, replies ...string) (IMessagePublisher, *fakeChannel) {
+	channel := &fakeChannel{replies: replies}
+	connections, err := newConnectionManager(ConnectionConfig{Topology: testTopology}, func() (amqpConnection, error) {
+		return &fakeConnection{channel: channel}, nil
+	})
+	assert.NoError(t, err)
+	return NewRabbitMqPublisher(connections, testPublisherConfig), channel
+}
+
+func TestPublish_WaitsForTheBrokerAck(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "ack")
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	assert.Len(t, channel.published, 1)
+	assert.Equal(t, []bool{true}, channel.mandatory)
+}
+
+func TestPublish_RetriesNackedAndReturnedMessages(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "nack", "return", "ack")
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	assert.Len(t, channel.published, 3)
+}
+
+func TestPublish_SkipsLateConfirmationsOfTimedOutAttempts(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "timeout", "ack")
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	// the first message is acked once the second one was published
+	channel.confirmations <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	channel.replies = []string{"nack", "nack", "nack"}
+	err = publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.ErrorIs(t, err, ErrDeliveryUnavailable)
+}
+
+func TestPublish_ConfirmsArriveWhileOtherMessagesWait(t *testing.T) {
+	channel := &fakeChannel{replies: []string{"timeout"}, fallback: "ack"}
+	connections, err := newConnectionManager(ConnectionConfig{Topology: testTopology}, func() (amqpConnection, error) {
+		return &fakeConnection{channel: channel}, nil
+	})
+	assert.NoError(t, err)
+	publisher := NewRabbitMqPublisher(connections, PublisherConfig{ConfirmTimeout: time.Minute, MaxAttempts: 1})
+	published := make(chan struct{})
+	channel.onPublish = func() {
+		channel.onPublish = nil
+		close(published)
+	}
+	waiting := make(chan error, 1)
+	go func() {
+		waiting <- publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	}()
+	<-published
+	// the second message is confirmed while the first one still waits for its confirmation
+	err = publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567891"})
+	assert.NoError(t, err)
+	channel.confirmations <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	assert.NoError(t, <-waiting)
+}
+
+func TestPublish_DrainsConfirmationsNobodyWaitsFor(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "ack")
+	for tag := uint64(100); tag < 200; tag++ {
+		channel.confirmations <- amqp.Confirmation{DeliveryTag: tag, Ack: true}
+	}
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+}
+
+func TestPublish_DeliveryUnavailableAfterAllAttempts(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "nack", "error", "return")
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	var unavailable *DeliveryUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, 3, unavailable.Attempts)
+	assert.ErrorIs(t, err, ErrDeliveryUnavailable)
+	assert.ErrorIs(t, err, errReturned)
+	assert.Len(t, channel.published, 3)
+}
+
+func TestPublish_StopsRetryingCancelledRequests(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "timeout", "ack")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := publisher.Publish(ctx, &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, channel.published, 1)
+}
+
+func TestPublish_SendsTheEnvelopeWithEveryAttempt(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "nack", "ack")
+	ctx := WithMessageContext(context.Background(), MessageContext{CorrelationId: "rpc-1", TraceParent: testTraceParent})
+	err := publisher.Publish(ctx, &otp.GenerateOTPRequest{RequestId: "request-1", PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	if assert.Len(t, channel.published, 2) {
+		for _, message := range channel.published {
+			assert.Equal(t, "request-1", message.MessageId)
+			assert.Equal(t, "rpc-1", message.CorrelationId)
+			assert.Equal(t, "com.service.otp.GenerateOTPRequest", message.Type)
+			assert.Equal(t, EVENT_SOURCE, message.AppId)
+			assert.False(t, message.Timestamp.IsZero())
+			assert.Equal(t, "request-1", message.Headers["cloudEvents:id"])
+			assert.Equal(t, OTP_REQUEST_SCHEMA_VERSION, message.Headers["cloudEvents:schemaversion"])
+			assert.Equal(t, testTraceParent, message.Headers["cloudEvents:traceparent"])
+		}
+		assert.NotEqual(t, channel.published[0].Headers[publishTagHeader], channel.published[1].Headers[publishTagHeader])
+	}
+}
@@ -0,0 +1,73 @@
+// Package gatewaytest holds the contract tests every message transport has to pass
+package gatewaytest
+
+import (
+	"auth-service/internal/gateway"
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"testing"
+)
+
+// Transport is a publisher under test together with the receiving end of its backend
+type Transport struct {
+	Publisher gateway.IMessagePublisher
+	// Received waits until count otp requests arrived or it gave up, and returns the ones that arrived in order
+	Received func(t *testing.T, count int) []*otp.GenerateOTPRequest
+	// Break makes the backend unavailable, it is nil for transports that can not fail
+	Break func()
+}
+
+// Factory creates a transport backed by an empty backend for each test
+type Factory func(t *testing.T) Transport
+
+func otpRequests() []*otp.GenerateOTPRequest {
+	return []*otp.GenerateOTPRequest{
+		{CountryCode: 91, PhoneNumber: "9876543210", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_SMS},
+		{CountryCode: 91, PhoneNumber: "9876543210", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE},
+		{CountryCode: 91, PhoneNumber: "9876543210", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL, Email: "john@example.com"},
+	}
+}
+
+func assertRequests(t *testing.T, expected, actual []*otp.GenerateOTPRequest) {
+	t.Helper()
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for i := range expected {
+		assert.True(t, proto.Equal(expected[i], actual[i]), "request %d: expected %v, got %v", i, expected[i], actual[i])
+	}
+}
+
+func RunPublisherContractTests(t *testing.T, newTransport Factory) {
+	t.Run("DeliversRequestsInOrder", func(t *testing.T) {
+		transport := newTransport(t)
+		requests := otpRequests()
+		for _, request := range requests {
+			assert.NoError(t, transport.Publisher.Publish(context.Background(), request))
+		}
+		assertRequests(t, requests, transport.Received(t, len(requests)))
+	})
+
+	t.Run("DoesNotDeliverCancelledRequests", func(t *testing.T) {
+		transport := newTransport(t)
+		requests := otpRequests()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, transport.Publisher.Publish(ctx, requests[0]), context.Canceled)
+		// a request published afterwards has to be the first one to arrive
+		assert.NoError(t, transport.Publisher.Publish(context.Background(), requests[1]))
+		assertRequests(t, requests[1:2], transport.Received(t, 1))
+	})
+
+	t.Run("FailsWithDeliveryUnavailable", func(t *testing.T) {
+		transport := newTransport(t)
+		if transport.Break == nil {
+			t.Skip("the transport can not be unavailable")
+		}
+		transport.Break()
+		err := transport.Publisher.Publish(context.Background(), otpRequests()[0])
+		assert.ErrorIs(t, err, gateway.ErrDeliveryUnavailable)
+	})
+}
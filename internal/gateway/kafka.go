@@ -0,0 +1,131 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"time"
+)
+
+// ROUTING_KEY_HEADER carries the routing key of the delivery channel on transports without routing
+const ROUTING_KEY_HEADER = "otp-routing-key"
+
+type KafkaConfig struct {
+	Brokers     []string
+	Topic       string
+	RoutingKeys RoutingKeys
+}
+
+// kafkaWriter is the part of *kafka.Writer used by the publisher
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// NewKafkaPublisher publishes to one topic keyed by phone number, so the otps of a user stay in order. A publish
+// succeeds once all in sync replicas have the message
+func NewKafkaPublisher(config KafkaConfig, publisherConfig PublisherConfig) ITransport {
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		MaxAttempts:  1,
+		RequiredAcks: kafka.RequireAll,
+		// messages are written one at a time, waiting for a batch to fill up only delays them
+		BatchSize:    1,
+		WriteTimeout: publisherConfig.ConfirmTimeout,
+	}
+	return newKafkaPublisher(writer, config.RoutingKeys, publisherConfig)
+}
+
+func newKafkaPublisher(writer kafkaWriter, routingKeys RoutingKeys, config PublisherConfig) *kafkaPublisher {
+	return &kafkaPublisher{writer: writer, routingKeys: routingKeys, config: config}
+}
+
+type kafkaPublisher struct {
+	writer      kafkaWriter
+	routingKeys RoutingKeys
+	config      PublisherConfig
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	marshalledBytes, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	envelope, err := newEnvelope(ctx, request)
+	if err != nil {
+		return err
+	}
+	message := kafka.Message{
+		Key:   []byte(request.PhoneNumber),
+		Value: marshalledBytes,
+		Headers: append(envelope.kafkaHeaders(),
+			kafka.Header{Key: "content-type", Value: []byte("application/x-protobuf")},
+			kafka.Header{Key: ROUTING_KEY_HEADER, Value: []byte(k.routingKeys.of(request.Channel))}),
+	}
+	return k.config.retry(ctx, func(ctx context.Context) error {
+		return k.writer.WriteMessages(ctx, message)
+	})
+}
+
+func (k *kafkaPublisher) Close() error {
+	return k.writer.Close()
+}
+
+// kafkaReader is the part of *kafka.Reader used by the receipt consumer
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// NewKafkaReceiptConsumer consumes receipts from a topic in a consumer group, so the instances of the service share
+// its partitions. An offset is committed once its receipt is handled
+func NewKafkaReceiptConsumer(brokers []string, topic string, groupId string, config ReceiptConfig) IReceiptConsumer {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		GroupID: groupId,
+	})
+	return newKafkaReceiptConsumer(reader, config)
+}
+
+func newKafkaReceiptConsumer(reader kafkaReader, config ReceiptConfig) *kafkaReceiptConsumer {
+	return &kafkaReceiptConsumer{reader: reader, config: config}
+}
+
+type kafkaReceiptConsumer struct {
+	reader kafkaReader
+	config ReceiptConfig
+}
+
+func (k *kafkaReceiptConsumer) Run(ctx context.Context, handle ReceiptHandler) {
+	defer k.reader.Close()
+	for {
+		message, err := k.reader.FetchMessage(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("fetching delivery receipts failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(k.config.MinRetryDelay):
+			}
+			continue
+		}
+		if err = k.config.handleReceipt(ctx, message.Value, handle); err != nil {
+			return
+		}
+		if err = k.reader.CommitMessages(ctx, message); err != nil && ctx.Err() == nil {
+			slog.Warn("committing delivery receipt failed, it is handled again after a rebalance", "error", err)
+		}
+	}
+}
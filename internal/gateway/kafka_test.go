@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeKafkaWriter struct {
+	messages []kafka.Message
+	broken   bool
+	closed   bool
+}
+
+func (f *fakeKafkaWriter) WriteMessages(ctx context.Context, messages ...kafka.Message) error {
+	if f.broken {
+		return errors.New("leader not available")
+	}
+	f.messages = append(f.messages, messages...)
+	return ctx.Err()
+}
+
+func (f *fakeKafkaWriter) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestKafkaPublisher_KeysMessagesByPhoneNumber(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	publisher := newKafkaPublisher(writer, testTopology.RoutingKeys, testPublisherConfig)
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{RequestId: "request-1", PhoneNumber: "1234567890", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE})
+	assert.NoError(t, err)
+	if assert.Len(t, writer.messages, 1) {
+		assert.Equal(t, []byte("1234567890"), writer.messages[0].Key)
+		assert.Contains(t, writer.messages[0].Headers, kafka.Header{Key: ROUTING_KEY_HEADER, Value: []byte("otp.voice")})
+		assert.Contains(t, writer.messages[0].Headers, kafka.Header{Key: "ce_id", Value: []byte("request-1")})
+	}
+	assert.NoError(t, publisher.Close())
+	assert.True(t, writer.closed)
+}
@@ -0,0 +1,35 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"google.golang.org/protobuf/encoding/protojson"
+	"log"
+)
+
+// NewLogPublisher logs otp requests instead of sending them, for local development and CI without a broker.
+// The log contains phone numbers and emails, so it must not be used in production
+func NewLogPublisher(logger *log.Logger, routingKeys RoutingKeys) ITransport {
+	return &logPublisher{logger: logger, routingKeys: routingKeys}
+}
+
+type logPublisher struct {
+	logger      *log.Logger
+	routingKeys RoutingKeys
+}
+
+func (l *logPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	marshalled, err := protojson.Marshal(request)
+	if err != nil {
+		return err
+	}
+	l.logger.Printf("otp request for %s: %s", l.routingKeys.of(request.Channel), marshalled)
+	return nil
+}
+
+func (l *logPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,123 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+type NatsConfig struct {
+	Url string
+	// JetStream publishes to a stream and waits for its ack, otherwise a publish only waits for the server to receive it
+	JetStream bool
+	// RoutingKeys are the subjects per delivery channel
+	RoutingKeys RoutingKeys
+}
+
+// NewNatsPublisher connects to nats, publishing every otp request to the subject of its delivery channel
+func NewNatsPublisher(config NatsConfig, publisherConfig PublisherConfig) (ITransport, error) {
+	connection, err := nats.Connect(config.Url, nats.Name("auth-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	publish := func(ctx context.Context, message *nats.Msg) error {
+		if err := connection.PublishMsg(message); err != nil {
+			return err
+		}
+		return connection.FlushWithContext(ctx)
+	}
+	if config.JetStream {
+		stream, err := connection.JetStream()
+		if err != nil {
+			connection.Close()
+			return nil, err
+		}
+		publish = func(ctx context.Context, message *nats.Msg) error {
+			_, err := stream.PublishMsg(message, nats.Context(ctx))
+			return err
+		}
+	}
+	return newNatsPublisher(publish, func() error {
+		return connection.Drain()
+	}, config.RoutingKeys, publisherConfig), nil
+}
+
+func newNatsPublisher(publish func(ctx context.Context, message *nats.Msg) error, close func() error, routingKeys RoutingKeys, config PublisherConfig) *natsPublisher {
+	return &natsPublisher{publish: publish, close: close, routingKeys: routingKeys, config: config}
+}
+
+type natsPublisher struct {
+	publish     func(ctx context.Context, message *nats.Msg) error
+	close       func() error
+	routingKeys RoutingKeys
+	config      PublisherConfig
+}
+
+func (n *natsPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	marshalledBytes, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	envelope, err := newEnvelope(ctx, request)
+	if err != nil {
+		return err
+	}
+	return n.config.retry(ctx, func(ctx context.Context) error {
+		// the message is sent again on every attempt, it can not be reused once published
+		message := nats.NewMsg(n.routingKeys.of(request.Channel))
+		message.Data = marshalledBytes
+		message.Header.Set("content-type", "application/x-protobuf")
+		envelope.setHeaders(message.Header.Set)
+		return n.publish(ctx, message)
+	})
+}
+
+// Close flushes pending messages and closes the connection
+func (n *natsPublisher) Close() error {
+	return n.close()
+}
+
+// NewNatsReceiptConsumer subscribes to the receipt subject in a queue group, so each receipt reaches one instance of
+// the service. Core nats does not redeliver, receipts published while no instance is subscribed are lost
+func NewNatsReceiptConsumer(url string, config ReceiptConfig) (IReceiptConsumer, error) {
+	connection, err := nats.Connect(url, nats.Name("auth-service"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan *nats.Msg, 64)
+	if _, err = connection.ChanQueueSubscribe(config.RoutingKey, config.Queue, messages); err != nil {
+		connection.Close()
+		return nil, err
+	}
+	return newNatsReceiptConsumer(messages, func() error {
+		return connection.Drain()
+	}, config), nil
+}
+
+func newNatsReceiptConsumer(messages <-chan *nats.Msg, close func() error, config ReceiptConfig) *natsReceiptConsumer {
+	return &natsReceiptConsumer{messages: messages, close: close, config: config}
+}
+
+type natsReceiptConsumer struct {
+	messages <-chan *nats.Msg
+	close    func() error
+	config   ReceiptConfig
+}
+
+func (n *natsReceiptConsumer) Run(ctx context.Context, handle ReceiptHandler) {
+	defer n.close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message := <-n.messages:
+			if err := n.config.handleReceipt(ctx, message.Data, handle); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type fakeNats struct {
+	messages []*nats.Msg
+	broken   bool
+}
+
+func (f *fakeNats) publish(ctx context.Context, message *nats.Msg) error {
+	if f.broken {
+		return nats.ErrNoResponders
+	}
+	f.messages = append(f.messages, message)
+	return ctx.Err()
+}
+
+func TestNatsPublisher_PublishesToTheSubjectOfTheChannel(t *testing.T) {
+	backend := &fakeNats{}
+	publisher := newNatsPublisher(backend.publish, func() error { return nil }, testTopology.RoutingKeys, testPublisherConfig)
+	assert.NoError(t, publisher.Publish(context.Background(), &otp.GenerateOTPRequest{RequestId: "request-1", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL}))
+	if assert.Len(t, backend.messages, 1) {
+		assert.Equal(t, "otp.email", backend.messages[0].Subject)
+		assert.Equal(t, "request-1", backend.messages[0].Header.Get("ce-id"))
+	}
+}
+
+func TestNatsPublisher_RetriesWithoutAck(t *testing.T) {
+	backend := &fakeNats{broken: true}
+	publisher := newNatsPublisher(backend.publish, func() error { return nil }, testTopology.RoutingKeys, testPublisherConfig)
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{})
+	var unavailable *DeliveryUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, testPublisherConfig.MaxAttempts, unavailable.Attempts)
+	assert.True(t, errors.Is(err, nats.ErrNoResponders))
+}
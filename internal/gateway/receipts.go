@@ -0,0 +1,130 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"time"
+)
+
+// ReceiptHandler stores a delivery receipt of the otp service, receipts are redelivered to it while it fails
+type ReceiptHandler func(ctx context.Context, receipt *otp.DeliveryReceipt) error
+
+// IReceiptConsumer hands the delivery receipts published by the otp service to a handler, one at a time
+type IReceiptConsumer interface {
+	// Run consumes receipts until the context is cancelled and releases the connection of the consumer
+	Run(ctx context.Context, handle ReceiptHandler)
+}
+
+type ReceiptConfig struct {
+	// Queue is the queue receipts are consumed from, on nats it is the queue group sharing them between instances
+	Queue string
+	// RoutingKey is the routing key of receipts on the otp exchange, on nats it is their subject
+	RoutingKey string
+	// MinRetryDelay doubles with every failure to handle a receipt up to MaxRetryDelay
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// handleReceipt decodes a receipt and hands it to handle until it succeeds, so it fails only once ctx is cancelled.
+// Receipts that can not be decoded are dropped, delivering them again would not help
+func (c ReceiptConfig) handleReceipt(ctx context.Context, body []byte, handle ReceiptHandler) error {
+	receipt := &otp.DeliveryReceipt{}
+	if err := proto.Unmarshal(body, receipt); err != nil {
+		slog.Warn("dropping delivery receipt that can not be decoded", "error", err)
+		return nil
+	}
+	delay := c.MinRetryDelay
+	for {
+		err := handle(ctx, receipt)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.Warn("handling delivery receipt failed, retrying", "request_id", receipt.RequestId, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.MaxRetryDelay {
+			delay = c.MaxRetryDelay
+		}
+	}
+}
+
+// NewRabbitMqReceiptConsumer consumes receipts from a queue bound to the otp exchange, it opens its own channel on
+// the connection of the manager and opens it again after the manager reconnected
+func NewRabbitMqReceiptConsumer(connections *ConnectionManager, config ReceiptConfig) IReceiptConsumer {
+	return &rabbitMqReceiptConsumer{connections: connections, config: config}
+}
+
+type rabbitMqReceiptConsumer struct {
+	connections *ConnectionManager
+	config      ReceiptConfig
+}
+
+func (r *rabbitMqReceiptConsumer) Run(ctx context.Context, handle ReceiptHandler) {
+	for {
+		err := r.consume(ctx, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("consuming delivery receipts failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.config.MinRetryDelay):
+		}
+	}
+}
+
+// consume handles receipts until the channel closes, acking each one once it is handled
+func (r *rabbitMqReceiptConsumer) consume(ctx context.Context, handle ReceiptHandler) error {
+	current, err := r.connections.session()
+	if err != nil {
+		return err
+	}
+	channel, err := current.connection.Channel()
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+	topology := r.connections.config.Topology
+	if _, err = channel.QueueDeclare(r.config.Queue, topology.Durable, false, false, false, nil); err != nil {
+		return err
+	}
+	if err = channel.QueueBind(r.config.Queue, r.config.RoutingKey, topology.Exchange, false, nil); err != nil {
+		return err
+	}
+	// receipts are handled one at a time, prefetching a few saves a round trip per receipt
+	if err = channel.Qos(16, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := channel.Consume(r.config.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return errNotConnected
+			}
+			if err = r.config.handleReceipt(ctx, delivery.Body, handle); err != nil {
+				// the receipt is redelivered once the channel is closed
+				return err
+			}
+			if err = delivery.Ack(false); err != nil {
+				return err
+			}
+		}
+	}
+}
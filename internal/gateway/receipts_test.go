@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"errors"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testReceiptConfig = ReceiptConfig{
+	Queue:         "otp.receipts",
+	RoutingKey:    "otp.receipt",
+	MinRetryDelay: time.Millisecond,
+	MaxRetryDelay: 2 * time.Millisecond,
+}
+
+// receiptRecorder records handled receipts after failing as often as failures
+type receiptRecorder struct {
+	mu       sync.Mutex
+	failures int
+	handled  []string
+}
+
+func (r *receiptRecorder) handle(_ context.Context, receipt *otp.DeliveryReceipt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("database down")
+	}
+	r.handled = append(r.handled, receipt.RequestId)
+	return nil
+}
+
+func (r *receiptRecorder) requestIds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.handled...)
+}
+
+type fakeAcknowledger struct {
+	acked chan uint64
+}
+
+func (f *fakeAcknowledger) Ack(tag uint64, _ bool) error {
+	f.acked <- tag
+	return nil
+}
+
+func (f *fakeAcknowledger) Nack(uint64, bool, bool) error {
+	return nil
+}
+
+func (f *fakeAcknowledger) Reject(uint64, bool) error {
+	return nil
+}
+
+func marshalReceipt(t *testing.T, requestId string) []byte {
+	body, err := proto.Marshal(&otp.DeliveryReceipt{RequestId: requestId, Status: otp.DeliveryStatus_DELIVERY_STATUS_SENT})
+	assert.NoError(t, err)
+	return body
+}
+
+func runConsumer(consumer IReceiptConsumer, handle ReceiptHandler) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx, handle)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestHandleReceipt_RetriesUntilHandled(t *testing.T) {
+	recorder := &receiptRecorder{failures: 2}
+	err := testReceiptConfig.handleReceipt(context.Background(), marshalReceipt(t, "request-1"), recorder.handle)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"request-1"}, recorder.requestIds())
+}
+
+func TestHandleReceipt_DropsReceiptsThatCanNotBeDecoded(t *testing.T) {
+	recorder := &receiptRecorder{}
+	err := testReceiptConfig.handleReceipt(context.Background(), []byte{0xff}, recorder.handle)
+	assert.NoError(t, err)
+	assert.Empty(t, recorder.requestIds())
+}
+
+func TestHandleReceipt_StopsRetryingOnceCancelled(t *testing.T) {
+	recorder := &receiptRecorder{failures: 1000}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := testReceiptConfig.handleReceipt(ctx, marshalReceipt(t, "request-1"), recorder.handle)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRabbitMqReceiptConsumer_AcksHandledReceipts(t *testing.T) {
+	channel := &fakeChannel{receipts: make(chan amqp.Delivery, 2)}
+	connections, err := newConnectionManager(ConnectionConfig{Topology: testTopology}, func() (amqpConnection, error) {
+		return &fakeConnection{channel: channel}, nil
+	})
+	assert.NoError(t, err)
+	acknowledger := &fakeAcknowledger{acked: make(chan uint64, 2)}
+	channel.receipts <- amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 1, Body: marshalReceipt(t, "request-1")}
+	channel.receipts <- amqp.Delivery{Acknowledger: acknowledger, DeliveryTag: 2, Body: marshalReceipt(t, "request-2")}
+	recorder := &receiptRecorder{failures: 1}
+
+	stop := runConsumer(NewRabbitMqReceiptConsumer(connections, testReceiptConfig), recorder.handle)
+	assert.Equal(t, uint64(1), <-acknowledger.acked)
+	assert.Equal(t, uint64(2), <-acknowledger.acked)
+	stop()
+	assert.Equal(t, []string{"request-1", "request-2"}, recorder.requestIds())
+	assert.Equal(t, []string{"otp.receipts"}, channel.consumed)
+	assert.Contains(t, channel.bindings, "otp.receipts <- otp otp.receipt")
+}
+
+type fakeKafkaReader struct {
+	messages  chan kafka.Message
+	committed chan int64
+	closed    bool
+}
+
+func (f *fakeKafkaReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	case message := <-f.messages:
+		return message, nil
+	}
+}
+
+func (f *fakeKafkaReader) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	for _, message := range messages {
+		f.committed <- message.Offset
+	}
+	return nil
+}
+
+func (f *fakeKafkaReader) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestKafkaReceiptConsumer_CommitsHandledReceipts(t *testing.T) {
+	reader := &fakeKafkaReader{messages: make(chan kafka.Message, 1), committed: make(chan int64, 1)}
+	reader.messages <- kafka.Message{Offset: 7, Value: marshalReceipt(t, "request-1")}
+	recorder := &receiptRecorder{failures: 1}
+
+	stop := runConsumer(newKafkaReceiptConsumer(reader, testReceiptConfig), recorder.handle)
+	assert.Equal(t, int64(7), <-reader.committed)
+	stop()
+	assert.Equal(t, []string{"request-1"}, recorder.requestIds())
+	assert.True(t, reader.closed)
+}
+
+func TestNatsReceiptConsumer_HandlesReceiptsUntilStopped(t *testing.T) {
+	messages := make(chan *nats.Msg, 1)
+	messages <- &nats.Msg{Subject: "otp.receipt", Data: marshalReceipt(t, "request-1")}
+	closed := make(chan struct{})
+	recorder := &receiptRecorder{}
+
+	stop := runConsumer(newNatsReceiptConsumer(messages, func() error {
+		close(closed)
+		return nil
+	}, testReceiptConfig), recorder.handle)
+	assert.Eventually(t, func() bool { return len(recorder.requestIds()) == 1 }, time.Second, time.Millisecond)
+	stop()
+	<-closed
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"fmt"
+	"github.com/streadway/amqp"
+	"time"
+)
+
+// RoutingKeys are the routing keys of otp requests per delivery channel, consumers bind their queues with them
+type RoutingKeys struct {
+	Sms   string
+	Voice string
+	Email string
+}
+
+// QueueBinding is a queue declared by the service and bound to the exchange with the given routing keys
+type QueueBinding struct {
+	Name        string
+	RoutingKeys []string
+}
+
+// Topology is declared on every new connection, declaring it again with different settings fails on the broker
+type Topology struct {
+	// Exchange receives every otp request, routed by the routing key of its delivery channel
+	Exchange     string
+	ExchangeType string
+	RoutingKeys  RoutingKeys
+	// Durable exchanges and queues survive broker restarts, Persistent stores messages on disk so they do too
+	Durable    bool
+	Persistent bool
+	Queues     []QueueBinding
+	// MessageTTL drops messages that were not consumed in time from the declared queues, zero keeps them
+	MessageTTL time.Duration
+	// DeadLetterExchange receives expired and rejected messages of the declared queues, empty disables dead lettering
+	DeadLetterExchange string
+	// DeadLetterQueue is bound to the dead letter exchange so dead letters are kept, empty leaves binding to consumers
+	DeadLetterQueue string
+}
+
+// declare declares the exchanges and queues, and binds the queues
+func (t Topology) declare(channel amqpChannel) error {
+	if t.DeadLetterExchange != "" {
+		if err := channel.ExchangeDeclare(t.DeadLetterExchange, amqp.ExchangeFanout, t.Durable, false, false, false, nil); err != nil {
+			return err
+		}
+		if t.DeadLetterQueue != "" {
+			if _, err := channel.QueueDeclare(t.DeadLetterQueue, t.Durable, false, false, false, nil); err != nil {
+				return err
+			}
+			if err := channel.QueueBind(t.DeadLetterQueue, "", t.DeadLetterExchange, false, nil); err != nil {
+				return err
+			}
+		}
+	}
+	if err := channel.ExchangeDeclare(t.Exchange, t.ExchangeType, t.Durable, false, false, false, nil); err != nil {
+		return err
+	}
+	for _, queue := range t.Queues {
+		if _, err := channel.QueueDeclare(queue.Name, t.Durable, false, false, false, t.queueArguments()); err != nil {
+			return err
+		}
+		for _, key := range queue.RoutingKeys {
+			if err := channel.QueueBind(queue.Name, key, t.Exchange, false, nil); err != nil {
+				return fmt.Errorf("binding queue %s to %s: %w", queue.Name, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (t Topology) queueArguments() amqp.Table {
+	arguments := amqp.Table{}
+	if t.MessageTTL > 0 {
+		arguments["x-message-ttl"] = t.MessageTTL.Milliseconds()
+	}
+	if t.DeadLetterExchange != "" {
+		arguments["x-dead-letter-exchange"] = t.DeadLetterExchange
+	}
+	if len(arguments) == 0 {
+		return nil
+	}
+	return arguments
+}
+
+// of returns the routing key of the delivery channel, requests without a channel are sent as sms
+func (k RoutingKeys) of(channel otp.DeliveryChannel) string {
+	switch channel {
+	case otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE:
+		return k.Voice
+	case otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL:
+		return k.Email
+	default:
+		return k.Sms
+	}
+}
+
+func (t Topology) deliveryMode() uint8 {
+	if t.Persistent {
+		return amqp.Persistent
+	}
+	return amqp.Transient
+}
@@ -0,0 +1,73 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var testTopology = Topology{
+	Exchange:     "otp",
+	ExchangeType: amqp.ExchangeDirect,
+	RoutingKeys:  RoutingKeys{Sms: "otp.sms", Voice: "otp.voice", Email: "otp.email"},
+	Queues:       []QueueBinding{{Name: "otp.requests", RoutingKeys: []string{"otp.sms", "otp.voice", "otp.email"}}},
+}
+
+func TestTopology_DeclaresDurableQueuesWithDeadLettering(t *testing.T) {
+	topology := testTopology
+	topology.Durable = true
+	topology.MessageTTL = 10 * time.Minute
+	topology.DeadLetterExchange = "otp.dead-letter"
+	topology.DeadLetterQueue = "otp.dead-letter"
+	channel := &fakeChannel{}
+	assert.NoError(t, topology.declare(channel))
+	assert.Equal(t, []string{"otp.dead-letter fanout durable=true", "otp direct durable=true"}, channel.exchanges)
+	assert.Equal(t, []string{"otp.dead-letter", "otp.requests"}, channel.declared)
+	assert.Equal(t, amqp.Table{"x-message-ttl": int64(600000), "x-dead-letter-exchange": "otp.dead-letter"}, channel.arguments["otp.requests"])
+	assert.Nil(t, channel.arguments["otp.dead-letter"])
+	assert.Equal(t, []string{
+		"otp.dead-letter <- otp.dead-letter ",
+		"otp.requests <- otp otp.sms",
+		"otp.requests <- otp otp.voice",
+		"otp.requests <- otp otp.email",
+	}, channel.bindings)
+}
+
+func TestTopology_LeavesQueuesToConsumers(t *testing.T) {
+	topology := Topology{Exchange: "otp", ExchangeType: amqp.ExchangeTopic}
+	channel := &fakeChannel{}
+	assert.NoError(t, topology.declare(channel))
+	assert.Equal(t, []string{"otp topic durable=false"}, channel.exchanges)
+	assert.Empty(t, channel.declared)
+	assert.Empty(t, channel.bindings)
+}
+
+func TestPublish_RoutesByDeliveryChannel(t *testing.T) {
+	publisher, channel := newTestPublisher(t, "ack", "ack", "ack", "ack")
+	for _, deliveryChannel := range []otp.DeliveryChannel{
+		otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED,
+		otp.DeliveryChannel_DELIVERY_CHANNEL_SMS,
+		otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE,
+		otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL,
+	} {
+		assert.NoError(t, publisher.Publish(context.Background(), &otp.GenerateOTPRequest{Channel: deliveryChannel}))
+	}
+	assert.Equal(t, []string{"otp otp.sms", "otp otp.sms", "otp otp.voice", "otp otp.email"}, channel.routingKeys)
+	assert.Equal(t, amqp.Transient, channel.published[0].DeliveryMode)
+}
+
+func TestPublish_PersistentMessages(t *testing.T) {
+	channel := &fakeChannel{replies: []string{"ack"}}
+	topology := testTopology
+	topology.Persistent = true
+	connections, err := newConnectionManager(ConnectionConfig{Topology: topology}, func() (amqpConnection, error) {
+		return &fakeConnection{channel: channel}, nil
+	})
+	assert.NoError(t, err)
+	publisher := NewRabbitMqPublisher(connections, testPublisherConfig)
+	assert.NoError(t, publisher.Publish(context.Background(), &otp.GenerateOTPRequest{}))
+	assert.Equal(t, amqp.Persistent, channel.published[0].DeliveryMode)
+}
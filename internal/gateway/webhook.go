@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+)
+
+// Headers of webhook requests, the body is the GenerateOTPRequest in protobuf encoding
+const (
+	WEBHOOK_ROUTING_KEY_HEADER = "X-Otp-Routing-Key"
+	// WEBHOOK_SIGNATURE_HEADER is "sha256=" followed by the hex HMAC-SHA256 of the body with the shared secret
+	WEBHOOK_SIGNATURE_HEADER = "X-Otp-Signature"
+)
+
+type WebhookConfig struct {
+	Url string
+	// Secret signs every request so the receiver can verify it, empty sends unsigned requests
+	Secret      string
+	RoutingKeys RoutingKeys
+}
+
+// NewWebhookPublisher posts otp requests to a url, a publish succeeds once the receiver answered with a 2xx status
+func NewWebhookPublisher(config WebhookConfig, publisherConfig PublisherConfig) ITransport {
+	return newWebhookPublisher(&http.Client{}, config, publisherConfig)
+}
+
+func newWebhookPublisher(client *http.Client, config WebhookConfig, publisherConfig PublisherConfig) *webhookPublisher {
+	return &webhookPublisher{client: client, config: config, publisherConfig: publisherConfig}
+}
+
+type webhookPublisher struct {
+	client          *http.Client
+	config          WebhookConfig
+	publisherConfig PublisherConfig
+}
+
+func (w *webhookPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	marshalledBytes, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	envelope, err := newEnvelope(ctx, request)
+	if err != nil {
+		return err
+	}
+	routingKey := w.config.RoutingKeys.of(request.Channel)
+	return w.publisherConfig.retry(ctx, func(ctx context.Context) error {
+		return w.post(ctx, routingKey, marshalledBytes, envelope)
+	})
+}
+
+func (w *webhookPublisher) post(ctx context.Context, routingKey string, body []byte, envelope Envelope) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set(WEBHOOK_ROUTING_KEY_HEADER, routingKey)
+	envelope.setHeaders(request.Header.Set)
+	// the trace context is sent as plain W3C headers as well, so tracing middleware of the receiver picks it up
+	if envelope.TraceParent != "" {
+		request.Header.Set("traceparent", envelope.TraceParent)
+		request.Header.Set("tracestate", envelope.TraceState)
+	}
+	if w.config.Secret != "" {
+		request.Header.Set(WEBHOOK_SIGNATURE_HEADER, SignWebhook(w.config.Secret, body))
+	}
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook answered with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+func (w *webhookPublisher) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// SignWebhook returns the signature header value of a webhook body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
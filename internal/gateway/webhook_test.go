@@ -0,0 +1,59 @@
+package gateway
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookPublisher_SignsRequests(t *testing.T) {
+	var headers http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		headers = request.Header
+		body, _ = io.ReadAll(request.Body)
+	}))
+	defer server.Close()
+	publisher := NewWebhookPublisher(WebhookConfig{Url: server.URL, Secret: "secret", RoutingKeys: testTopology.RoutingKeys}, testPublisherConfig)
+	err := publisher.Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	assert.Equal(t, "otp.voice", headers.Get(WEBHOOK_ROUTING_KEY_HEADER))
+	assert.Equal(t, SignWebhook("secret", body), headers.Get(WEBHOOK_SIGNATURE_HEADER))
+	assert.NotEqual(t, SignWebhook("other", body), headers.Get(WEBHOOK_SIGNATURE_HEADER))
+}
+
+func TestWebhookPublisher_PropagatesTheTraceContext(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		headers = request.Header
+	}))
+	defer server.Close()
+	publisher := NewWebhookPublisher(WebhookConfig{Url: server.URL, RoutingKeys: testTopology.RoutingKeys}, testPublisherConfig)
+	ctx := WithMessageContext(context.Background(), MessageContext{CorrelationId: "rpc-1", TraceParent: testTraceParent, TraceState: "vendor=1"})
+	err := publisher.Publish(ctx, &otp.GenerateOTPRequest{RequestId: "request-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "request-1", headers.Get("ce-id"))
+	assert.Equal(t, "rpc-1", headers.Get("ce-correlationid"))
+	assert.Equal(t, testTraceParent, headers.Get("ce-traceparent"))
+	assert.Equal(t, testTraceParent, headers.Get("traceparent"))
+	assert.Equal(t, "vendor=1", headers.Get("tracestate"))
+}
+
+func TestWebhookPublisher_RetriesFailedRequests(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		calls++
+		if calls < 3 {
+			writer.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	publisher := NewWebhookPublisher(WebhookConfig{Url: server.URL, RoutingKeys: testTopology.RoutingKeys}, testPublisherConfig)
+	assert.NoError(t, publisher.Publish(context.Background(), &otp.GenerateOTPRequest{}))
+	assert.Equal(t, 3, calls)
+}
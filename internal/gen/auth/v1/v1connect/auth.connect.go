@@ -50,17 +50,60 @@ const (
 	// AuthServiceGetProfileByPhoneNumberProcedure is the fully-qualified name of the AuthService's
 	// getProfileByPhoneNumber RPC.
 	AuthServiceGetProfileByPhoneNumberProcedure = "/com.service.auth.AuthService/getProfileByPhoneNumber"
+	// AuthServiceCheckUsernameAvailabilityProcedure is the fully-qualified name of the AuthService's
+	// checkUsernameAvailability RPC.
+	AuthServiceCheckUsernameAvailabilityProcedure = "/com.service.auth.AuthService/checkUsernameAvailability"
+	// AuthServiceResendOtpProcedure is the fully-qualified name of the AuthService's resendOtp RPC.
+	AuthServiceResendOtpProcedure = "/com.service.auth.AuthService/resendOtp"
+	// AuthServiceUpdateProfileProcedure is the fully-qualified name of the AuthService's updateProfile
+	// RPC.
+	AuthServiceUpdateProfileProcedure = "/com.service.auth.AuthService/updateProfile"
+	// AuthServiceConfirmEmailChangeProcedure is the fully-qualified name of the AuthService's
+	// confirmEmailChange RPC.
+	AuthServiceConfirmEmailChangeProcedure = "/com.service.auth.AuthService/confirmEmailChange"
+	// AuthServiceStartPhoneChangeProcedure is the fully-qualified name of the AuthService's
+	// startPhoneChange RPC.
+	AuthServiceStartPhoneChangeProcedure = "/com.service.auth.AuthService/startPhoneChange"
+	// AuthServiceConfirmPhoneChangeProcedure is the fully-qualified name of the AuthService's
+	// confirmPhoneChange RPC.
+	AuthServiceConfirmPhoneChangeProcedure = "/com.service.auth.AuthService/confirmPhoneChange"
+	// AuthServiceDeleteAccountProcedure is the fully-qualified name of the AuthService's deleteAccount
+	// RPC.
+	AuthServiceDeleteAccountProcedure = "/com.service.auth.AuthService/deleteAccount"
+	// AuthServiceRestoreAccountProcedure is the fully-qualified name of the AuthService's
+	// restoreAccount RPC.
+	AuthServiceRestoreAccountProcedure = "/com.service.auth.AuthService/restoreAccount"
+	// AuthServiceExportMyDataProcedure is the fully-qualified name of the AuthService's exportMyData
+	// RPC.
+	AuthServiceExportMyDataProcedure = "/com.service.auth.AuthService/exportMyData"
+	// AuthServiceDownloadMyDataProcedure is the fully-qualified name of the AuthService's
+	// downloadMyData RPC.
+	AuthServiceDownloadMyDataProcedure = "/com.service.auth.AuthService/downloadMyData"
+	// AuthServiceGetOtpDeliveryStatusProcedure is the fully-qualified name of the AuthService's
+	// getOtpDeliveryStatus RPC.
+	AuthServiceGetOtpDeliveryStatusProcedure = "/com.service.auth.AuthService/getOtpDeliveryStatus"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
 var (
-	authServiceServiceDescriptor                        = v1.File_auth_v1_auth_proto.Services().ByName("AuthService")
-	authServiceSignupWithPhoneNumberMethodDescriptor    = authServiceServiceDescriptor.Methods().ByName("signupWithPhoneNumber")
-	authServiceLoginWithPhoneNumberMethodDescriptor     = authServiceServiceDescriptor.Methods().ByName("loginWithPhoneNumber")
-	authServiceVerifyPhoneNumberMethodDescriptor        = authServiceServiceDescriptor.Methods().ByName("verifyPhoneNumber")
-	authServiceValidatePhoneNumberLoginMethodDescriptor = authServiceServiceDescriptor.Methods().ByName("validatePhoneNumberLogin")
-	authServiceGetProfileMethodDescriptor               = authServiceServiceDescriptor.Methods().ByName("getProfile")
-	authServiceGetProfileByPhoneNumberMethodDescriptor  = authServiceServiceDescriptor.Methods().ByName("getProfileByPhoneNumber")
+	authServiceServiceDescriptor                         = v1.File_auth_v1_auth_proto.Services().ByName("AuthService")
+	authServiceSignupWithPhoneNumberMethodDescriptor     = authServiceServiceDescriptor.Methods().ByName("signupWithPhoneNumber")
+	authServiceLoginWithPhoneNumberMethodDescriptor      = authServiceServiceDescriptor.Methods().ByName("loginWithPhoneNumber")
+	authServiceVerifyPhoneNumberMethodDescriptor         = authServiceServiceDescriptor.Methods().ByName("verifyPhoneNumber")
+	authServiceValidatePhoneNumberLoginMethodDescriptor  = authServiceServiceDescriptor.Methods().ByName("validatePhoneNumberLogin")
+	authServiceGetProfileMethodDescriptor                = authServiceServiceDescriptor.Methods().ByName("getProfile")
+	authServiceGetProfileByPhoneNumberMethodDescriptor   = authServiceServiceDescriptor.Methods().ByName("getProfileByPhoneNumber")
+	authServiceCheckUsernameAvailabilityMethodDescriptor = authServiceServiceDescriptor.Methods().ByName("checkUsernameAvailability")
+	authServiceResendOtpMethodDescriptor                 = authServiceServiceDescriptor.Methods().ByName("resendOtp")
+	authServiceUpdateProfileMethodDescriptor             = authServiceServiceDescriptor.Methods().ByName("updateProfile")
+	authServiceConfirmEmailChangeMethodDescriptor        = authServiceServiceDescriptor.Methods().ByName("confirmEmailChange")
+	authServiceStartPhoneChangeMethodDescriptor          = authServiceServiceDescriptor.Methods().ByName("startPhoneChange")
+	authServiceConfirmPhoneChangeMethodDescriptor        = authServiceServiceDescriptor.Methods().ByName("confirmPhoneChange")
+	authServiceDeleteAccountMethodDescriptor             = authServiceServiceDescriptor.Methods().ByName("deleteAccount")
+	authServiceRestoreAccountMethodDescriptor            = authServiceServiceDescriptor.Methods().ByName("restoreAccount")
+	authServiceExportMyDataMethodDescriptor              = authServiceServiceDescriptor.Methods().ByName("exportMyData")
+	authServiceDownloadMyDataMethodDescriptor            = authServiceServiceDescriptor.Methods().ByName("downloadMyData")
+	authServiceGetOtpDeliveryStatusMethodDescriptor      = authServiceServiceDescriptor.Methods().ByName("getOtpDeliveryStatus")
 )
 
 // AuthServiceClient is a client for the com.service.auth.AuthService service.
@@ -73,6 +116,25 @@ type AuthServiceClient interface {
 	// Additional methods
 	// We might want to get profile based on mobile nUmber as well
 	GetProfileByPhoneNumber(context.Context, *connect.Request[v1.GetProfileByPhoneNumberRequest]) (*connect.Response[v1.GetProfileByPhoneNumberResponse], error)
+	// Lets the signup form check a user name before submitting it
+	CheckUsernameAvailability(context.Context, *connect.Request[v1.CheckUsernameAvailabilityRequest]) (*connect.Response[v1.CheckUsernameAvailabilityResponse], error)
+	// Resends the otp of a pending signup or login when the first one did not arrive
+	ResendOtp(context.Context, *connect.Request[v1.ResendOtpRequest]) (*connect.Response[v1.ResendOtpResponse], error)
+	// Updates name, user name or email, a new email is pending until confirmed with confirmEmailChange
+	UpdateProfile(context.Context, *connect.Request[v1.UpdateProfileRequest]) (*connect.Response[v1.UpdateProfileResponse], error)
+	ConfirmEmailChange(context.Context, *connect.Request[v1.ConfirmEmailChangeRequest]) (*connect.Response[v1.ConfirmEmailChangeResponse], error)
+	// Changes the login phone number, needs a recent login on the current number and the otp sent to the new one
+	StartPhoneChange(context.Context, *connect.Request[v1.StartPhoneChangeRequest]) (*connect.Response[v1.StartPhoneChangeResponse], error)
+	ConfirmPhoneChange(context.Context, *connect.Request[v1.ConfirmPhoneChangeRequest]) (*connect.Response[v1.ConfirmPhoneChangeResponse], error)
+	// Deletes the account after a grace period, restoreAccount undoes it until then
+	DeleteAccount(context.Context, *connect.Request[v1.DeleteAccountRequest]) (*connect.Response[v1.DeleteAccountResponse], error)
+	RestoreAccount(context.Context, *connect.Request[v1.RestoreAccountRequest]) (*connect.Response[v1.RestoreAccountResponse], error)
+	// Requests an archive of all data stored about the user, it is assembled in the background and downloaded with the
+	// returned token
+	ExportMyData(context.Context, *connect.Request[v1.ExportMyDataRequest]) (*connect.Response[v1.ExportMyDataResponse], error)
+	DownloadMyData(context.Context, *connect.Request[v1.DownloadMyDataRequest]) (*connect.Response[v1.DownloadMyDataResponse], error)
+	// Reports whether the last otp reached the phone, so clients can offer resending it by call when it failed
+	GetOtpDeliveryStatus(context.Context, *connect.Request[v1.GetOtpDeliveryStatusRequest]) (*connect.Response[v1.GetOtpDeliveryStatusResponse], error)
 }
 
 // NewAuthServiceClient constructs a client for the com.service.auth.AuthService service. By
@@ -121,17 +183,94 @@ func NewAuthServiceClient(httpClient connect.HTTPClient, baseURL string, opts ..
 			connect.WithSchema(authServiceGetProfileByPhoneNumberMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		checkUsernameAvailability: connect.NewClient[v1.CheckUsernameAvailabilityRequest, v1.CheckUsernameAvailabilityResponse](
+			httpClient,
+			baseURL+AuthServiceCheckUsernameAvailabilityProcedure,
+			connect.WithSchema(authServiceCheckUsernameAvailabilityMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		resendOtp: connect.NewClient[v1.ResendOtpRequest, v1.ResendOtpResponse](
+			httpClient,
+			baseURL+AuthServiceResendOtpProcedure,
+			connect.WithSchema(authServiceResendOtpMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		updateProfile: connect.NewClient[v1.UpdateProfileRequest, v1.UpdateProfileResponse](
+			httpClient,
+			baseURL+AuthServiceUpdateProfileProcedure,
+			connect.WithSchema(authServiceUpdateProfileMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		confirmEmailChange: connect.NewClient[v1.ConfirmEmailChangeRequest, v1.ConfirmEmailChangeResponse](
+			httpClient,
+			baseURL+AuthServiceConfirmEmailChangeProcedure,
+			connect.WithSchema(authServiceConfirmEmailChangeMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		startPhoneChange: connect.NewClient[v1.StartPhoneChangeRequest, v1.StartPhoneChangeResponse](
+			httpClient,
+			baseURL+AuthServiceStartPhoneChangeProcedure,
+			connect.WithSchema(authServiceStartPhoneChangeMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		confirmPhoneChange: connect.NewClient[v1.ConfirmPhoneChangeRequest, v1.ConfirmPhoneChangeResponse](
+			httpClient,
+			baseURL+AuthServiceConfirmPhoneChangeProcedure,
+			connect.WithSchema(authServiceConfirmPhoneChangeMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		deleteAccount: connect.NewClient[v1.DeleteAccountRequest, v1.DeleteAccountResponse](
+			httpClient,
+			baseURL+AuthServiceDeleteAccountProcedure,
+			connect.WithSchema(authServiceDeleteAccountMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		restoreAccount: connect.NewClient[v1.RestoreAccountRequest, v1.RestoreAccountResponse](
+			httpClient,
+			baseURL+AuthServiceRestoreAccountProcedure,
+			connect.WithSchema(authServiceRestoreAccountMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		exportMyData: connect.NewClient[v1.ExportMyDataRequest, v1.ExportMyDataResponse](
+			httpClient,
+			baseURL+AuthServiceExportMyDataProcedure,
+			connect.WithSchema(authServiceExportMyDataMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		downloadMyData: connect.NewClient[v1.DownloadMyDataRequest, v1.DownloadMyDataResponse](
+			httpClient,
+			baseURL+AuthServiceDownloadMyDataProcedure,
+			connect.WithSchema(authServiceDownloadMyDataMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
+		getOtpDeliveryStatus: connect.NewClient[v1.GetOtpDeliveryStatusRequest, v1.GetOtpDeliveryStatusResponse](
+			httpClient,
+			baseURL+AuthServiceGetOtpDeliveryStatusProcedure,
+			connect.WithSchema(authServiceGetOtpDeliveryStatusMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
 // authServiceClient implements AuthServiceClient.
 type authServiceClient struct {
-	signupWithPhoneNumber    *connect.Client[v1.SignupWithPhoneNumberRequest, v1.SignupWithPhoneNumberResponse]
-	loginWithPhoneNumber     *connect.Client[v1.LoginWithPhoneNumberRequest, v1.LoginWithPhoneNumberResponse]
-	verifyPhoneNumber        *connect.Client[v1.VerifyPhoneNumberRequest, v1.VerifyPhoneNumberResponse]
-	validatePhoneNumberLogin *connect.Client[v1.ValidatePhoneNumberLoginRequest, v1.ValidatePhoneNumberLoginResponse]
-	getProfile               *connect.Client[v1.GetProfileRequest, v1.GetProfileResponse]
-	getProfileByPhoneNumber  *connect.Client[v1.GetProfileByPhoneNumberRequest, v1.GetProfileByPhoneNumberResponse]
+	signupWithPhoneNumber     *connect.Client[v1.SignupWithPhoneNumberRequest, v1.SignupWithPhoneNumberResponse]
+	loginWithPhoneNumber      *connect.Client[v1.LoginWithPhoneNumberRequest, v1.LoginWithPhoneNumberResponse]
+	verifyPhoneNumber         *connect.Client[v1.VerifyPhoneNumberRequest, v1.VerifyPhoneNumberResponse]
+	validatePhoneNumberLogin  *connect.Client[v1.ValidatePhoneNumberLoginRequest, v1.ValidatePhoneNumberLoginResponse]
+	getProfile                *connect.Client[v1.GetProfileRequest, v1.GetProfileResponse]
+	getProfileByPhoneNumber   *connect.Client[v1.GetProfileByPhoneNumberRequest, v1.GetProfileByPhoneNumberResponse]
+	checkUsernameAvailability *connect.Client[v1.CheckUsernameAvailabilityRequest, v1.CheckUsernameAvailabilityResponse]
+	resendOtp                 *connect.Client[v1.ResendOtpRequest, v1.ResendOtpResponse]
+	updateProfile             *connect.Client[v1.UpdateProfileRequest, v1.UpdateProfileResponse]
+	confirmEmailChange        *connect.Client[v1.ConfirmEmailChangeRequest, v1.ConfirmEmailChangeResponse]
+	startPhoneChange          *connect.Client[v1.StartPhoneChangeRequest, v1.StartPhoneChangeResponse]
+	confirmPhoneChange        *connect.Client[v1.ConfirmPhoneChangeRequest, v1.ConfirmPhoneChangeResponse]
+	deleteAccount             *connect.Client[v1.DeleteAccountRequest, v1.DeleteAccountResponse]
+	restoreAccount            *connect.Client[v1.RestoreAccountRequest, v1.RestoreAccountResponse]
+	exportMyData              *connect.Client[v1.ExportMyDataRequest, v1.ExportMyDataResponse]
+	downloadMyData            *connect.Client[v1.DownloadMyDataRequest, v1.DownloadMyDataResponse]
+	getOtpDeliveryStatus      *connect.Client[v1.GetOtpDeliveryStatusRequest, v1.GetOtpDeliveryStatusResponse]
 }
 
 // SignupWithPhoneNumber calls com.service.auth.AuthService.signupWithPhoneNumber.
@@ -164,6 +303,61 @@ func (c *authServiceClient) GetProfileByPhoneNumber(ctx context.Context, req *co
 	return c.getProfileByPhoneNumber.CallUnary(ctx, req)
 }
 
+// CheckUsernameAvailability calls com.service.auth.AuthService.checkUsernameAvailability.
+func (c *authServiceClient) CheckUsernameAvailability(ctx context.Context, req *connect.Request[v1.CheckUsernameAvailabilityRequest]) (*connect.Response[v1.CheckUsernameAvailabilityResponse], error) {
+	return c.checkUsernameAvailability.CallUnary(ctx, req)
+}
+
+// ResendOtp calls com.service.auth.AuthService.resendOtp.
+func (c *authServiceClient) ResendOtp(ctx context.Context, req *connect.Request[v1.ResendOtpRequest]) (*connect.Response[v1.ResendOtpResponse], error) {
+	return c.resendOtp.CallUnary(ctx, req)
+}
+
+// UpdateProfile calls com.service.auth.AuthService.updateProfile.
+func (c *authServiceClient) UpdateProfile(ctx context.Context, req *connect.Request[v1.UpdateProfileRequest]) (*connect.Response[v1.UpdateProfileResponse], error) {
+	return c.updateProfile.CallUnary(ctx, req)
+}
+
+// ConfirmEmailChange calls com.service.auth.AuthService.confirmEmailChange.
+func (c *authServiceClient) ConfirmEmailChange(ctx context.Context, req *connect.Request[v1.ConfirmEmailChangeRequest]) (*connect.Response[v1.ConfirmEmailChangeResponse], error) {
+	return c.confirmEmailChange.CallUnary(ctx, req)
+}
+
+// StartPhoneChange calls com.service.auth.AuthService.startPhoneChange.
+func (c *authServiceClient) StartPhoneChange(ctx context.Context, req *connect.Request[v1.StartPhoneChangeRequest]) (*connect.Response[v1.StartPhoneChangeResponse], error) {
+	return c.startPhoneChange.CallUnary(ctx, req)
+}
+
+// ConfirmPhoneChange calls com.service.auth.AuthService.confirmPhoneChange.
+func (c *authServiceClient) ConfirmPhoneChange(ctx context.Context, req *connect.Request[v1.ConfirmPhoneChangeRequest]) (*connect.Response[v1.ConfirmPhoneChangeResponse], error) {
+	return c.confirmPhoneChange.CallUnary(ctx, req)
+}
+
+// DeleteAccount calls com.service.auth.AuthService.deleteAccount.
+func (c *authServiceClient) DeleteAccount(ctx context.Context, req *connect.Request[v1.DeleteAccountRequest]) (*connect.Response[v1.DeleteAccountResponse], error) {
+	return c.deleteAccount.CallUnary(ctx, req)
+}
+
+// RestoreAccount calls com.service.auth.AuthService.restoreAccount.
+func (c *authServiceClient) RestoreAccount(ctx context.Context, req *connect.Request[v1.RestoreAccountRequest]) (*connect.Response[v1.RestoreAccountResponse], error) {
+	return c.restoreAccount.CallUnary(ctx, req)
+}
+
+// ExportMyData calls com.service.auth.AuthService.exportMyData.
+func (c *authServiceClient) ExportMyData(ctx context.Context, req *connect.Request[v1.ExportMyDataRequest]) (*connect.Response[v1.ExportMyDataResponse], error) {
+	return c.exportMyData.CallUnary(ctx, req)
+}
+
+// DownloadMyData calls com.service.auth.AuthService.downloadMyData.
+func (c *authServiceClient) DownloadMyData(ctx context.Context, req *connect.Request[v1.DownloadMyDataRequest]) (*connect.Response[v1.DownloadMyDataResponse], error) {
+	return c.downloadMyData.CallUnary(ctx, req)
+}
+
+// GetOtpDeliveryStatus calls com.service.auth.AuthService.getOtpDeliveryStatus.
+func (c *authServiceClient) GetOtpDeliveryStatus(ctx context.Context, req *connect.Request[v1.GetOtpDeliveryStatusRequest]) (*connect.Response[v1.GetOtpDeliveryStatusResponse], error) {
+	return c.getOtpDeliveryStatus.CallUnary(ctx, req)
+}
+
 // AuthServiceHandler is an implementation of the com.service.auth.AuthService service.
 type AuthServiceHandler interface {
 	SignupWithPhoneNumber(context.Context, *connect.Request[v1.SignupWithPhoneNumberRequest]) (*connect.Response[v1.SignupWithPhoneNumberResponse], error)
@@ -174,6 +368,25 @@ type AuthServiceHandler interface {
 	// Additional methods
 	// We might want to get profile based on mobile nUmber as well
 	GetProfileByPhoneNumber(context.Context, *connect.Request[v1.GetProfileByPhoneNumberRequest]) (*connect.Response[v1.GetProfileByPhoneNumberResponse], error)
+	// Lets the signup form check a user name before submitting it
+	CheckUsernameAvailability(context.Context, *connect.Request[v1.CheckUsernameAvailabilityRequest]) (*connect.Response[v1.CheckUsernameAvailabilityResponse], error)
+	// Resends the otp of a pending signup or login when the first one did not arrive
+	ResendOtp(context.Context, *connect.Request[v1.ResendOtpRequest]) (*connect.Response[v1.ResendOtpResponse], error)
+	// Updates name, user name or email, a new email is pending until confirmed with confirmEmailChange
+	UpdateProfile(context.Context, *connect.Request[v1.UpdateProfileRequest]) (*connect.Response[v1.UpdateProfileResponse], error)
+	ConfirmEmailChange(context.Context, *connect.Request[v1.ConfirmEmailChangeRequest]) (*connect.Response[v1.ConfirmEmailChangeResponse], error)
+	// Changes the login phone number, needs a recent login on the current number and the otp sent to the new one
+	StartPhoneChange(context.Context, *connect.Request[v1.StartPhoneChangeRequest]) (*connect.Response[v1.StartPhoneChangeResponse], error)
+	ConfirmPhoneChange(context.Context, *connect.Request[v1.ConfirmPhoneChangeRequest]) (*connect.Response[v1.ConfirmPhoneChangeResponse], error)
+	// Deletes the account after a grace period, restoreAccount undoes it until then
+	DeleteAccount(context.Context, *connect.Request[v1.DeleteAccountRequest]) (*connect.Response[v1.DeleteAccountResponse], error)
+	RestoreAccount(context.Context, *connect.Request[v1.RestoreAccountRequest]) (*connect.Response[v1.RestoreAccountResponse], error)
+	// Requests an archive of all data stored about the user, it is assembled in the background and downloaded with the
+	// returned token
+	ExportMyData(context.Context, *connect.Request[v1.ExportMyDataRequest]) (*connect.Response[v1.ExportMyDataResponse], error)
+	DownloadMyData(context.Context, *connect.Request[v1.DownloadMyDataRequest]) (*connect.Response[v1.DownloadMyDataResponse], error)
+	// Reports whether the last otp reached the phone, so clients can offer resending it by call when it failed
+	GetOtpDeliveryStatus(context.Context, *connect.Request[v1.GetOtpDeliveryStatusRequest]) (*connect.Response[v1.GetOtpDeliveryStatusResponse], error)
 }
 
 // NewAuthServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -218,6 +431,72 @@ func NewAuthServiceHandler(svc AuthServiceHandler, opts ...connect.HandlerOption
 		connect.WithSchema(authServiceGetProfileByPhoneNumberMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	authServiceCheckUsernameAvailabilityHandler := connect.NewUnaryHandler(
+		AuthServiceCheckUsernameAvailabilityProcedure,
+		svc.CheckUsernameAvailability,
+		connect.WithSchema(authServiceCheckUsernameAvailabilityMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceResendOtpHandler := connect.NewUnaryHandler(
+		AuthServiceResendOtpProcedure,
+		svc.ResendOtp,
+		connect.WithSchema(authServiceResendOtpMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceUpdateProfileHandler := connect.NewUnaryHandler(
+		AuthServiceUpdateProfileProcedure,
+		svc.UpdateProfile,
+		connect.WithSchema(authServiceUpdateProfileMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceConfirmEmailChangeHandler := connect.NewUnaryHandler(
+		AuthServiceConfirmEmailChangeProcedure,
+		svc.ConfirmEmailChange,
+		connect.WithSchema(authServiceConfirmEmailChangeMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceStartPhoneChangeHandler := connect.NewUnaryHandler(
+		AuthServiceStartPhoneChangeProcedure,
+		svc.StartPhoneChange,
+		connect.WithSchema(authServiceStartPhoneChangeMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceConfirmPhoneChangeHandler := connect.NewUnaryHandler(
+		AuthServiceConfirmPhoneChangeProcedure,
+		svc.ConfirmPhoneChange,
+		connect.WithSchema(authServiceConfirmPhoneChangeMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceDeleteAccountHandler := connect.NewUnaryHandler(
+		AuthServiceDeleteAccountProcedure,
+		svc.DeleteAccount,
+		connect.WithSchema(authServiceDeleteAccountMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceRestoreAccountHandler := connect.NewUnaryHandler(
+		AuthServiceRestoreAccountProcedure,
+		svc.RestoreAccount,
+		connect.WithSchema(authServiceRestoreAccountMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceExportMyDataHandler := connect.NewUnaryHandler(
+		AuthServiceExportMyDataProcedure,
+		svc.ExportMyData,
+		connect.WithSchema(authServiceExportMyDataMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceDownloadMyDataHandler := connect.NewUnaryHandler(
+		AuthServiceDownloadMyDataProcedure,
+		svc.DownloadMyData,
+		connect.WithSchema(authServiceDownloadMyDataMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
+	authServiceGetOtpDeliveryStatusHandler := connect.NewUnaryHandler(
+		AuthServiceGetOtpDeliveryStatusProcedure,
+		svc.GetOtpDeliveryStatus,
+		connect.WithSchema(authServiceGetOtpDeliveryStatusMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/com.service.auth.AuthService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case AuthServiceSignupWithPhoneNumberProcedure:
@@ -232,6 +511,28 @@ func NewAuthServiceHandler(svc AuthServiceHandler, opts ...connect.HandlerOption
 			authServiceGetProfileHandler.ServeHTTP(w, r)
 		case AuthServiceGetProfileByPhoneNumberProcedure:
 			authServiceGetProfileByPhoneNumberHandler.ServeHTTP(w, r)
+		case AuthServiceCheckUsernameAvailabilityProcedure:
+			authServiceCheckUsernameAvailabilityHandler.ServeHTTP(w, r)
+		case AuthServiceResendOtpProcedure:
+			authServiceResendOtpHandler.ServeHTTP(w, r)
+		case AuthServiceUpdateProfileProcedure:
+			authServiceUpdateProfileHandler.ServeHTTP(w, r)
+		case AuthServiceConfirmEmailChangeProcedure:
+			authServiceConfirmEmailChangeHandler.ServeHTTP(w, r)
+		case AuthServiceStartPhoneChangeProcedure:
+			authServiceStartPhoneChangeHandler.ServeHTTP(w, r)
+		case AuthServiceConfirmPhoneChangeProcedure:
+			authServiceConfirmPhoneChangeHandler.ServeHTTP(w, r)
+		case AuthServiceDeleteAccountProcedure:
+			authServiceDeleteAccountHandler.ServeHTTP(w, r)
+		case AuthServiceRestoreAccountProcedure:
+			authServiceRestoreAccountHandler.ServeHTTP(w, r)
+		case AuthServiceExportMyDataProcedure:
+			authServiceExportMyDataHandler.ServeHTTP(w, r)
+		case AuthServiceDownloadMyDataProcedure:
+			authServiceDownloadMyDataHandler.ServeHTTP(w, r)
+		case AuthServiceGetOtpDeliveryStatusProcedure:
+			authServiceGetOtpDeliveryStatusHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -264,3 +565,47 @@ func (UnimplementedAuthServiceHandler) GetProfile(context.Context, *connect.Requ
 func (UnimplementedAuthServiceHandler) GetProfileByPhoneNumber(context.Context, *connect.Request[v1.GetProfileByPhoneNumberRequest]) (*connect.Response[v1.GetProfileByPhoneNumberResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.getProfileByPhoneNumber is not implemented"))
 }
+
+func (UnimplementedAuthServiceHandler) CheckUsernameAvailability(context.Context, *connect.Request[v1.CheckUsernameAvailabilityRequest]) (*connect.Response[v1.CheckUsernameAvailabilityResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.checkUsernameAvailability is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ResendOtp(context.Context, *connect.Request[v1.ResendOtpRequest]) (*connect.Response[v1.ResendOtpResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.resendOtp is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) UpdateProfile(context.Context, *connect.Request[v1.UpdateProfileRequest]) (*connect.Response[v1.UpdateProfileResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.updateProfile is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ConfirmEmailChange(context.Context, *connect.Request[v1.ConfirmEmailChangeRequest]) (*connect.Response[v1.ConfirmEmailChangeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.confirmEmailChange is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) StartPhoneChange(context.Context, *connect.Request[v1.StartPhoneChangeRequest]) (*connect.Response[v1.StartPhoneChangeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.startPhoneChange is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ConfirmPhoneChange(context.Context, *connect.Request[v1.ConfirmPhoneChangeRequest]) (*connect.Response[v1.ConfirmPhoneChangeResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.confirmPhoneChange is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) DeleteAccount(context.Context, *connect.Request[v1.DeleteAccountRequest]) (*connect.Response[v1.DeleteAccountResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.deleteAccount is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) RestoreAccount(context.Context, *connect.Request[v1.RestoreAccountRequest]) (*connect.Response[v1.RestoreAccountResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.restoreAccount is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) ExportMyData(context.Context, *connect.Request[v1.ExportMyDataRequest]) (*connect.Response[v1.ExportMyDataResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.exportMyData is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) DownloadMyData(context.Context, *connect.Request[v1.DownloadMyDataRequest]) (*connect.Response[v1.DownloadMyDataResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.downloadMyData is not implemented"))
+}
+
+func (UnimplementedAuthServiceHandler) GetOtpDeliveryStatus(context.Context, *connect.Request[v1.GetOtpDeliveryStatusRequest]) (*connect.Response[v1.GetOtpDeliveryStatusResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("com.service.auth.AuthService.getOtpDeliveryStatus is not implemented"))
+}
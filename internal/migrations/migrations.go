@@ -0,0 +1,221 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// advisoryLockKey serializes migrations between service instances starting at the same time
+const advisoryLockKey int64 = 7_362_019_417
+
+const (
+	CREATE_MIGRATIONS_TABLE = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`
+	APPLIED_VERSIONS = "SELECT version FROM schema_migrations ORDER BY version"
+	INSERT_VERSION   = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+	DELETE_VERSION   = "DELETE FROM schema_migrations WHERE version = $1"
+	LOCK             = "SELECT pg_advisory_lock($1)"
+	UNLOCK           = "SELECT pg_advisory_unlock($1)"
+)
+
+// migration files are named <version>_<name>.<up|down>.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for the migrations embedded in the binary
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load(embedded, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the migrations in dir ordered by version, every version needs an up and a down file
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("unexpected file %s in migrations, expected <version>_<name>.<up|down>.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has different names %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies all pending migrations in order
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]bool) error {
+		for _, migration := range m.migrations {
+			if applied[migration.Version] {
+				continue
+			}
+			if err := apply(ctx, conn, migration.Up, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, INSERT_VERSION, migration.Version, migration.Name)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %d_%s", migration.Version, migration.Name)
+		}
+		return nil
+	})
+}
+
+// Down reverts the last steps applied migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]bool) error {
+		for i := len(m.migrations) - 1; i >= 0 && steps > 0; i-- {
+			migration := m.migrations[i]
+			if !applied[migration.Version] {
+				continue
+			}
+			if err := apply(ctx, conn, migration.Down, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, DELETE_VERSION, migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %d_%s", migration.Version, migration.Name)
+			steps--
+		}
+		return nil
+	})
+}
+
+// Status lists all known migrations and whether they are applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn, applied map[int64]bool) error {
+		for _, migration := range m.migrations {
+			statuses = append(statuses, Status{Migration: migration, Applied: applied[migration.Version]})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock holds a session level advisory lock on a dedicated connection while fn runs
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]bool) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err = conn.ExecContext(ctx, LOCK, advisoryLockKey); err != nil {
+		return fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// the lock is released with the session anyway, so a failed unlock is only logged
+		if _, err := conn.ExecContext(context.Background(), UNLOCK, advisoryLockKey); err != nil {
+			slog.Warn("unable to release migration lock", "error", err)
+		}
+	}()
+	if _, err = conn.ExecContext(ctx, CREATE_MIGRATIONS_TABLE); err != nil {
+		return err
+	}
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, APPLIED_VERSIONS)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// apply runs the statements and the bookkeeping in one transaction, postgres supports transactional DDL
+func apply(ctx context.Context, conn *sql.Conn, statements string, record func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, statements); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err = record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
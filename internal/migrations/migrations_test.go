@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadOrdersMigrationsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0002_second.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"sql/0002_second.down.sql": {Data: []byte("DROP TABLE b;")},
+		"sql/0001_first.up.sql":    {Data: []byte("CREATE TABLE a ();")},
+		"sql/0001_first.down.sql":  {Data: []byte("DROP TABLE a;")},
+	}
+	migrations, err := Load(fsys, "sql")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, Migration{Version: 1, Name: "first", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"}, migrations[0])
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestLoadRejectsIncompleteMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/0001_first.up.sql": {Data: []byte("CREATE TABLE a ();")},
+	}
+	_, err := Load(fsys, "sql")
+	assert.EqualError(t, err, "migration 1_first needs both an up and a down file")
+}
+
+func TestLoadRejectsUnexpectedFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/tables.sql": {Data: []byte("CREATE TABLE a ();")},
+	}
+	_, err := Load(fsys, "sql")
+	assert.Error(t, err)
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	migrations, err := Load(embedded, "sql")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrations)
+	assert.Equal(t, int64(1), migrations[0].Version)
+}
+
+// TestMigratorAgainstPostgres runs all migrations up and down, it needs TEST_DATABASE_URL pointing to a disposable database
+func TestMigratorAgainstPostgres(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	assert.NoError(t, err)
+	defer db.Close()
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	assert.NoError(t, migrator.Up(ctx))
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+	}
+	assert.NoError(t, migrator.Down(ctx, len(statuses)))
+	assert.NoError(t, migrator.Up(ctx))
+}
@@ -1,4 +1,4 @@
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
                        id SERIAL PRIMARY KEY,
                        name VARCHAR(255) NOT NULL,
                        user_name VARCHAR(255) NOT NULL UNIQUE,
@@ -9,9 +9,9 @@ CREATE TABLE users (
                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE user_events (
+CREATE TABLE IF NOT EXISTS user_events (
                              id SERIAL PRIMARY KEY,
                              phone_number VARCHAR NOT NULL,
                              event VARCHAR NOT NULL,
                              created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
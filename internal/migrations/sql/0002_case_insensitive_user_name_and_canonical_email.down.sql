@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_canonical_email_key;
+ALTER TABLE users DROP COLUMN IF EXISTS canonical_email;
+DROP INDEX IF EXISTS users_user_name_lower_key;
@@ -0,0 +1,8 @@
+-- user names are unique regardless of case
+CREATE UNIQUE INDEX IF NOT EXISTS users_user_name_lower_key ON users (lower(user_name));
+
+-- normalized email, catches case and gmail alias duplicates
+ALTER TABLE users ADD COLUMN IF NOT EXISTS canonical_email VARCHAR(255);
+UPDATE users SET canonical_email = lower(email) WHERE canonical_email IS NULL;
+ALTER TABLE users ALTER COLUMN canonical_email SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_canonical_email_key ON users (canonical_email);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outbox_messages (
+                                 id BIGSERIAL PRIMARY KEY,
+                                 topic VARCHAR(255) NOT NULL,
+                                 payload BYTEA NOT NULL,
+                                 attempts INT NOT NULL DEFAULT 0,
+                                 last_error TEXT,
+                                 -- messages are picked up once available_at has passed, claiming or failing a message moves it forward
+                                 available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                 created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                 delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_messages_pending_idx ON outbox_messages (available_at) WHERE delivered_at IS NULL;
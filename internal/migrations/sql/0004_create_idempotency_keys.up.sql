@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+                                 scope VARCHAR(64) NOT NULL,
+                                 key VARCHAR(255) NOT NULL,
+                                 request_hash BYTEA NOT NULL,
+                                 -- NULL until the first request with the key completed
+                                 response BYTEA,
+                                 created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                                 expires_at TIMESTAMP NOT NULL,
+                                 PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+-- lets the reaper find abandoned signups without scanning verified users
+CREATE INDEX IF NOT EXISTS users_unverified_created_at_idx ON users (created_at) WHERE is_verified = false;
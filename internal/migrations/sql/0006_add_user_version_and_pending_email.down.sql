@@ -0,0 +1,4 @@
+ALTER TABLE users DROP COLUMN IF EXISTS pending_canonical_email;
+ALTER TABLE users DROP COLUMN IF EXISTS pending_email;
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
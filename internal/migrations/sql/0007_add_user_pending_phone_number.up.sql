@@ -0,0 +1,3 @@
+-- pending phone number holds a new login number until the otp sent to it is confirmed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_country_code INT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_phone_number VARCHAR(20);
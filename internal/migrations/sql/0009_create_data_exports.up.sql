@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS data_exports (
+                              id BIGSERIAL PRIMARY KEY,
+                              -- exports of purged users are removed with them
+                              user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                              token_hash BYTEA NOT NULL,
+                              status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+                              archive BYTEA,
+                              last_error TEXT,
+                              -- pending exports are picked up once available_at has passed, claiming one moves it forward
+                              available_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                              created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                              completed_at TIMESTAMP,
+                              expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_pending_idx ON data_exports (available_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS data_exports_expires_at_idx ON data_exports (expires_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS otp_deliveries (
+                              request_id VARCHAR(64) PRIMARY KEY,
+                              country_code INT NOT NULL,
+                              phone_number VARCHAR(20) NOT NULL,
+                              channel VARCHAR(10) NOT NULL,
+                              status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+                              reason TEXT,
+                              created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                              updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS otp_deliveries_phone_number_idx ON otp_deliveries (phone_number, created_at);
+CREATE INDEX IF NOT EXISTS otp_deliveries_created_at_idx ON otp_deliveries (created_at);
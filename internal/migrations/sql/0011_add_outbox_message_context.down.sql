@@ -0,0 +1,3 @@
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS trace_state;
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS trace_parent;
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS correlation_id;
@@ -0,0 +1,4 @@
+-- the correlation id and trace context of the rpc that wrote a message, the relay publishes the message with them
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255);
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS trace_parent VARCHAR(55);
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS trace_state VARCHAR(512);
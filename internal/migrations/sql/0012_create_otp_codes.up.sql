@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS otp_codes (
+                              subject VARCHAR(255) PRIMARY KEY,
+                              code_hash BYTEA NOT NULL,
+                              attempts INT NOT NULL DEFAULT 0,
+                              created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                              expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS otp_codes_expires_at_idx ON otp_codes (expires_at);
@@ -0,0 +1,5 @@
+-- messages the relay gave up on, they expired or failed too often, stay in the table for inspection but are not claimed
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS failed_at TIMESTAMP;
+
+DROP INDEX IF EXISTS outbox_messages_pending_idx;
+CREATE INDEX IF NOT EXISTS outbox_messages_pending_idx ON outbox_messages (available_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
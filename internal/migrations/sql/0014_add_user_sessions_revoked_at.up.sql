@@ -0,0 +1,2 @@
+-- sessions issued before a phone number change are revoked, consumers reject tokens issued before this time
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMP;
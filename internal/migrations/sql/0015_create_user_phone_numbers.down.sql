@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS outbox_messages_phone_number_idx;
+ALTER TABLE outbox_messages DROP COLUMN IF EXISTS phone_number;
+DROP TABLE IF EXISTS user_phone_numbers;
@@ -0,0 +1,13 @@
+-- phone numbers a user replaced with a phone number change, purging the user removes what is stored under them
+CREATE TABLE IF NOT EXISTS user_phone_numbers (
+                              user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+                              phone_number VARCHAR(20) NOT NULL,
+                              acquired_at TIMESTAMP,
+                              replaced_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS user_phone_numbers_user_id_idx ON user_phone_numbers (user_id);
+
+-- the phone number an outbox message is about, so purging a user removes its undelivered otp requests
+ALTER TABLE outbox_messages ADD COLUMN IF NOT EXISTS phone_number VARCHAR(20);
+CREATE INDEX IF NOT EXISTS outbox_messages_phone_number_idx ON outbox_messages (phone_number);
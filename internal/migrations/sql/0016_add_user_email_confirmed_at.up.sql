@@ -0,0 +1,2 @@
+-- when the email was confirmed, otps are only resent to emails confirmed long enough ago
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_confirmed_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_country_code_phone_number_key;
+ALTER TABLE users ADD CONSTRAINT users_phone_number_key UNIQUE (phone_number);
@@ -0,0 +1,3 @@
+-- a phone number is unique within its country code, the same digits can be registered under another country code
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_number_key;
+CREATE UNIQUE INDEX IF NOT EXISTS users_country_code_phone_number_key ON users (country_code, phone_number);
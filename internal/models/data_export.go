@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Data export statuses, pending exports are assembled by the data exporter
+const (
+	DATA_EXPORT_PENDING = "PENDING"
+	DATA_EXPORT_READY   = "READY"
+	DATA_EXPORT_FAILED  = "FAILED"
+)
+
+// DataExport is an archive of everything stored about a user, downloadable with the token it was requested with
+type DataExport struct {
+	Id     int64
+	UserId int32
+	// TokenHash is the SHA-256 of the download token, the token itself is only returned to the requester
+	TokenHash   []byte
+	Status      string
+	Archive     []byte
+	LastError   string
+	CreatedAt   time.Time
+	CompletedAt time.Time
+	ExpiresAt   time.Time
+}
+
+// DataExportTicket identifies a requested export, the token is not stored and only known to the requester
+type DataExportTicket struct {
+	ExportId  int64
+	Token     string
+	ExpiresAt time.Time
+}
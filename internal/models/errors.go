@@ -0,0 +1,25 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Fields reported by AlreadyExistsError
+const (
+	FIELD_USER_NAME    = "user name"
+	FIELD_EMAIL        = "email"
+	FIELD_PHONE_NUMBER = "phone number"
+)
+
+// AlreadyExistsError is returned when a user can not be stored because another user holds a unique field
+type AlreadyExistsError struct {
+	Field string
+}
+
+func (e *AlreadyExistsError) Error() string {
+	return fmt.Sprintf("a user with this %s already exists", e.Field)
+}
+
+// ErrStaleVersion is returned when a user changed after the version an update was based on
+var ErrStaleVersion = errors.New("the profile was changed by another request, please reload it and try again")
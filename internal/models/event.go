@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type UserEvent struct {
+	Id          int64
+	PhoneNumber string
+	Event       string
+	CreatedAt   time.Time
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// IdempotencyRecord remembers the outcome of a request so retries with the same key get the same response
+type IdempotencyRecord struct {
+	// Scope is the operation the key belongs to, the same key may be used for different operations
+	Scope       string
+	Key         string
+	RequestHash []byte
+	// Response is nil while the first request is still being processed
+	Response  []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
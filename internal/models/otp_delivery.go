@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Otp delivery statuses, a delivery is pending until the otp service reports on it. Delivered and failed are final
+const (
+	OTP_DELIVERY_PENDING   = "PENDING"
+	OTP_DELIVERY_QUEUED    = "QUEUED"
+	OTP_DELIVERY_SENT      = "SENT"
+	OTP_DELIVERY_DELIVERED = "DELIVERED"
+	OTP_DELIVERY_FAILED    = "FAILED"
+)
+
+// OtpDelivery tracks one otp request published to the otp service
+type OtpDelivery struct {
+	// RequestId is the requestId of the GenerateOTPRequest, delivery receipts refer to it
+	RequestId   string
+	CountryCode int32
+	PhoneNumber string
+	// Channel is SMS, VOICE or EMAIL
+	Channel string
+	Status  string
+	// Reason is why the delivery failed
+	Reason    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OtpDeliveryStatusRank orders the statuses, a delivery never moves back to a lower rank
+func OtpDeliveryStatusRank(status string) int {
+	switch status {
+	case OTP_DELIVERY_PENDING:
+		return 0
+	case OTP_DELIVERY_QUEUED:
+		return 1
+	case OTP_DELIVERY_SENT:
+		return 2
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// OutboxMessage is a message stored together with the change that caused it and delivered afterwards
+type OutboxMessage struct {
+	Id      int64
+	Topic   string
+	Payload []byte
+	// CorrelationId, TraceParent and TraceState are the message context of the rpc that wrote the message
+	CorrelationId string
+	TraceParent   string
+	TraceState    string
+	// PhoneNumber is the phone number the message is about, the message is purged with the user holding it
+	PhoneNumber string
+	Attempts    int32
+	LastError   string
+	AvailableAt time.Time
+	CreatedAt   time.Time
+}
@@ -2,17 +2,53 @@ package models
 
 import (
 	v1 "auth-service/internal/gen/auth/v1"
+	"time"
+)
+
+// User statuses, deleted users are purged after the deletion grace period
+const (
+	USER_STATUS_ACTIVE  = "ACTIVE"
+	USER_STATUS_DELETED = "DELETED"
 )
 
 type User struct {
-	Id          int32
-	Name        string
-	UserName    string
-	Email       string
-	CreatedAt   string
-	Verified    bool
-	CountryCode int32
+	Id       int32
+	Name     string
+	UserName string
+	Email    string
+	// CanonicalEmail is the normalized form of Email used to enforce uniqueness
+	CanonicalEmail string
+	CreatedAt      time.Time
+	Verified       bool
+	CountryCode    int32
+	PhoneNumber    string
+	// Version is incremented on every update, updates based on an older version are rejected
+	Version   int64
+	UpdatedAt time.Time
+	// PendingEmail is a new address that is used once it is confirmed
+	PendingEmail          string
+	PendingCanonicalEmail string
+	// PendingCountryCode and PendingPhoneNumber are a new login number that is used once it is confirmed
+	PendingCountryCode int32
+	PendingPhoneNumber string
+	Status             string
+	// DeletedAt is when the user deleted the account, zero unless Status is USER_STATUS_DELETED
+	DeletedAt time.Time
+	// SessionsRevokedAt is when the phone number was last changed, logins before it are no longer valid
+	SessionsRevokedAt time.Time
+	// EmailConfirmedAt is when Email was confirmed, zero when it was only given at signup
+	EmailConfirmedAt time.Time
+}
+
+// HeldPhoneNumber is a phone number a user held from AcquiredAt until ReplacedAt, which is zero while it still holds it
+type HeldPhoneNumber struct {
 	PhoneNumber string
+	AcquiredAt  time.Time
+	ReplacedAt  time.Time
+}
+
+func (u *User) IsDeleted() bool {
+	return u.Status == USER_STATUS_DELETED
 }
 
 func ToUser(request *v1.SignupWithPhoneNumberRequest) *User {
@@ -29,13 +65,22 @@ func ToUser(request *v1.SignupWithPhoneNumberRequest) *User {
 }
 
 func ToProto(user *User) *v1.User {
+	var sessionsRevokedAt int64
+	if !user.SessionsRevokedAt.IsZero() {
+		sessionsRevokedAt = user.SessionsRevokedAt.Unix()
+	}
 	return &v1.User{
-		Id:          user.Id,
-		Name:        user.Name,
-		UserName:    user.UserName,
-		Email:       user.Email,
-		IsVerified:  user.Verified,
-		CountryCode: user.CountryCode,
-		PhoneNumber: user.PhoneNumber,
+		Id:                 user.Id,
+		Name:               user.Name,
+		UserName:           user.UserName,
+		Email:              user.Email,
+		IsVerified:         user.Verified,
+		CountryCode:        user.CountryCode,
+		PhoneNumber:        user.PhoneNumber,
+		Version:            user.Version,
+		PendingEmail:       user.PendingEmail,
+		PendingCountryCode: user.PendingCountryCode,
+		PendingPhoneNumber: user.PendingPhoneNumber,
+		SessionsRevokedAt:  sessionsRevokedAt,
 	}
 }
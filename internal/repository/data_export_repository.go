@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	DATA_EXPORT_COLUMNS = "id, user_id, token_hash, status, archive, COALESCE(last_error, ''), created_at, completed_at, expires_at"
+	INSERT_DATA_EXPORT  = `
+		INSERT INTO data_exports (user_id, token_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+		RETURNING ` + DATA_EXPORT_COLUMNS
+	GET_DATA_EXPORT = "SELECT " + DATA_EXPORT_COLUMNS + " FROM data_exports WHERE id = $1"
+	// CLAIM_DATA_EXPORTS leases pending exports to one exporter like CLAIM_OUTBOX_MESSAGES, an export whose
+	// exporter died before completing it is claimed again once the lease expires
+	CLAIM_DATA_EXPORTS = `
+		UPDATE data_exports SET available_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = 'PENDING' AND available_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + DATA_EXPORT_COLUMNS
+	COMPLETE_DATA_EXPORT        = "UPDATE data_exports SET status = 'READY', archive = $2, completed_at = CURRENT_TIMESTAMP WHERE id = $1"
+	FAIL_DATA_EXPORT            = "UPDATE data_exports SET status = 'FAILED', last_error = $2, completed_at = CURRENT_TIMESTAMP WHERE id = $1"
+	DELETE_EXPIRED_DATA_EXPORTS = "DELETE FROM data_exports WHERE expires_at <= CURRENT_TIMESTAMP"
+)
+
+type IDataExportRepository interface {
+	// Create stores a pending export of the user that can be downloaded until ttl after it was requested
+	Create(ctx context.Context, userId int32, tokenHash []byte, ttl time.Duration) (*models.DataExport, error)
+	GetExport(ctx context.Context, id int64) (*models.DataExport, error)
+	// ClaimPending returns up to limit pending exports and hides them from other exporters for the lease duration
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.DataExport, error)
+	Complete(ctx context.Context, id int64, archive []byte) error
+	// Fail gives up on an export that can not be assembled
+	Fail(ctx context.Context, id int64, reason string) error
+	// DeleteExpired removes expired exports with their archives and returns how many were removed
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func NewDataExportRepository(db *sql.DB) IDataExportRepository {
+	return &psqlDataExportRepository{db: db}
+}
+
+type psqlDataExportRepository struct {
+	db *sql.DB
+}
+
+func scanDataExport(row rowScanner) (*models.DataExport, error) {
+	var export models.DataExport
+	var completedAt sql.NullTime
+	err := row.Scan(&export.Id, &export.UserId, &export.TokenHash, &export.Status, &export.Archive, &export.LastError,
+		&export.CreatedAt, &completedAt, &export.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	export.CompletedAt = completedAt.Time
+	return &export, nil
+}
+
+func (p *psqlDataExportRepository) Create(ctx context.Context, userId int32, tokenHash []byte, ttl time.Duration) (*models.DataExport, error) {
+	return scanDataExport(p.db.QueryRowContext(ctx, INSERT_DATA_EXPORT, userId, tokenHash, ttl.Seconds()))
+}
+
+func (p *psqlDataExportRepository) GetExport(ctx context.Context, id int64) (*models.DataExport, error) {
+	export, err := scanDataExport(p.db.QueryRowContext(ctx, GET_DATA_EXPORT, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("data export with id %d not found", id)
+	}
+	return export, err
+}
+
+func (p *psqlDataExportRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.DataExport, error) {
+	rows, err := p.db.QueryContext(ctx, CLAIM_DATA_EXPORTS, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var exports []models.DataExport
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, *export)
+	}
+	return exports, rows.Err()
+}
+
+func (p *psqlDataExportRepository) Complete(ctx context.Context, id int64, archive []byte) error {
+	_, err := p.db.ExecContext(ctx, COMPLETE_DATA_EXPORT, id, archive)
+	return err
+}
+
+func (p *psqlDataExportRepository) Fail(ctx context.Context, id int64, reason string) error {
+	_, err := p.db.ExecContext(ctx, FAIL_DATA_EXPORT, id, reason)
+	return err
+}
+
+func (p *psqlDataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := p.db.ExecContext(ctx, DELETE_EXPIRED_DATA_EXPORTS)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"errors"
+	"github.com/lib/pq"
+	"log/slog"
+)
+
+const uniqueViolation = "23505"
+
+// uniqueConstraintFields maps the unique constraints and indexes on users to the field they protect
+var uniqueConstraintFields = map[string]string{
+	"users_user_name_key":       models.FIELD_USER_NAME,
+	"users_user_name_lower_key": models.FIELD_USER_NAME,
+	"users_email_key":           models.FIELD_EMAIL,
+	"users_canonical_email_key": models.FIELD_EMAIL,
+	"users_phone_number_key":    models.FIELD_PHONE_NUMBER,
+	// users_country_code_phone_number_key replaced users_phone_number_key
+	"users_country_code_phone_number_key": models.FIELD_PHONE_NUMBER,
+}
+
+var errUnableToSaveUser = errors.New("unable to save user, please try again after some time")
+
+// translateUserWriteError turns postgres errors into errors that are safe to return to clients.
+// The original error is logged as it carries table, constraint and value details.
+func translateUserWriteError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	slog.Warn("user write failed", "error", pqErr, "code", pqErr.Code, "constraint", pqErr.Constraint)
+	if pqErr.Code != uniqueViolation {
+		return errUnableToSaveUser
+	}
+	field, ok := uniqueConstraintFields[pqErr.Constraint]
+	if !ok {
+		return errUnableToSaveUser
+	}
+	return &models.AlreadyExistsError{Field: field}
+}
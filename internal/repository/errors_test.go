@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTranslateUserWriteError_UniqueViolations(t *testing.T) {
+	cases := map[string]string{
+		"users_user_name_key":       models.FIELD_USER_NAME,
+		"users_user_name_lower_key": models.FIELD_USER_NAME,
+		"users_email_key":           models.FIELD_EMAIL,
+		"users_canonical_email_key": models.FIELD_EMAIL,
+		"users_phone_number_key":    models.FIELD_PHONE_NUMBER,
+	}
+	for constraint, field := range cases {
+		err := translateUserWriteError(&pq.Error{Code: uniqueViolation, Constraint: constraint, Message: "duplicate key value violates unique constraint"})
+		var alreadyExists *models.AlreadyExistsError
+		assert.True(t, errors.As(err, &alreadyExists), constraint)
+		assert.Equal(t, field, alreadyExists.Field)
+		assert.NotContains(t, err.Error(), constraint)
+	}
+}
+
+func TestTranslateUserWriteError_HidesOtherDatabaseErrors(t *testing.T) {
+	err := translateUserWriteError(&pq.Error{Code: "23502", Message: `null value in column "name" violates not-null constraint`})
+	assert.Equal(t, errUnableToSaveUser, err)
+
+	err = translateUserWriteError(&pq.Error{Code: uniqueViolation, Constraint: "some_other_key"})
+	assert.Equal(t, errUnableToSaveUser, err)
+}
+
+func TestTranslateUserWriteError_PassesThroughNonDatabaseErrors(t *testing.T) {
+	original := errors.New("connection refused")
+	assert.Equal(t, original, translateUserWriteError(original))
+}
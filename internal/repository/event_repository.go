@@ -1,16 +1,34 @@
 package repository
 
 import (
+	"auth-service/internal/models"
+	"context"
 	"database/sql"
-	"log"
+	"log/slog"
+	"time"
 )
 
 type IEventRepository interface {
-	InsertEvent(event string, phoneNumber string)
+	InsertEvent(ctx context.Context, event string, phoneNumber string)
+	// ListRecentEvents returns the events of the phone number recorded within window, oldest first
+	ListRecentEvents(ctx context.Context, phoneNumber string, window time.Duration) ([]models.UserEvent, error)
+	// ListEvents returns the events of the phone number recorded from since until until, oldest first. A zero since or
+	// until leaves that end open
+	ListEvents(ctx context.Context, phoneNumber string, since time.Time, until time.Time) ([]models.UserEvent, error)
 }
 
 const (
-	query = "INSERT INTO user_events (phone_number, event) VALUES ($1, $2)"
+	INSERT_EVENT_QUERY  = "INSERT INTO user_events (phone_number, event) VALUES ($1, $2)"
+	RECENT_EVENTS_QUERY = `
+		SELECT id, phone_number, event, created_at FROM user_events
+		WHERE phone_number = $1 AND created_at >= CURRENT_TIMESTAMP - make_interval(secs => $2)
+		ORDER BY id
+		`
+	EVENTS_QUERY = `
+		SELECT id, phone_number, event, created_at FROM user_events
+		WHERE phone_number = $1 AND created_at BETWEEN COALESCE($2, '-infinity'::TIMESTAMP) AND COALESCE($3, 'infinity'::TIMESTAMP)
+		ORDER BY id
+		`
 )
 
 func NewEventRepository(db *sql.DB) IEventRepository {
@@ -21,10 +39,44 @@ type eventRepository struct {
 	db *sql.DB
 }
 
-func (r *eventRepository) InsertEvent(event string, phoneNumber string) {
-	_, err := r.db.Exec(query, phoneNumber, event)
+func (r *eventRepository) InsertEvent(ctx context.Context, event string, phoneNumber string) {
+	_, err := r.db.ExecContext(ctx, INSERT_EVENT_QUERY, phoneNumber, event)
 	if err != nil {
 		// ignoring event db query errors as they are of low priority
-		log.Println(err)
+		slog.Warn("inserting event failed", "event", event, "error", err)
 	}
 }
+
+func (r *eventRepository) ListRecentEvents(ctx context.Context, phoneNumber string, window time.Duration) ([]models.UserEvent, error) {
+	rows, err := r.db.QueryContext(ctx, RECENT_EVENTS_QUERY, phoneNumber, window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+func (r *eventRepository) ListEvents(ctx context.Context, phoneNumber string, since time.Time, until time.Time) ([]models.UserEvent, error) {
+	rows, err := r.db.QueryContext(ctx, EVENTS_QUERY, phoneNumber, nullTime(since), nullTime(until))
+	if err != nil {
+		return nil, err
+	}
+	return scanEvents(rows)
+}
+
+// nullTime stores the zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func scanEvents(rows *sql.Rows) ([]models.UserEvent, error) {
+	defer rows.Close()
+	var events []models.UserEvent
+	for rows.Next() {
+		var event models.UserEvent
+		if err := rows.Scan(&event.Id, &event.PhoneNumber, &event.Event, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	// RESERVE_IDEMPOTENCY_KEY inserts the key or takes over an expired record, a live record makes it return no rows
+	RESERVE_IDEMPOTENCY_KEY = `
+		INSERT INTO idempotency_keys (scope, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		ON CONFLICT (scope, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash, response = NULL, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= CURRENT_TIMESTAMP
+		RETURNING key
+		`
+	GET_IDEMPOTENCY_KEY = `
+		SELECT scope, key, request_hash, response, created_at, expires_at FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND expires_at > CURRENT_TIMESTAMP
+		`
+	COMPLETE_IDEMPOTENCY_KEY = `
+		UPDATE idempotency_keys SET response = $3, expires_at = CURRENT_TIMESTAMP + make_interval(secs => $4)
+		WHERE scope = $1 AND key = $2
+		`
+	RELEASE_IDEMPOTENCY_KEY        = "DELETE FROM idempotency_keys WHERE scope = $1 AND key = $2"
+	DELETE_EXPIRED_IDEMPOTENCY_KEY = "DELETE FROM idempotency_keys WHERE expires_at <= CURRENT_TIMESTAMP"
+)
+
+// maxReserveAttempts bounds retries when the record holding a key expires or is released between the insert and the read
+const maxReserveAttempts = 3
+
+var errUnableToReserveKey = errors.New("unable to reserve idempotency key, please try again")
+
+type IIdempotencyRepository interface {
+	// Reserve claims the key for a new request for the lease and returns nil, or returns the unexpired record already
+	// holding the key. A request that never completes frees the key once the lease passed
+	Reserve(ctx context.Context, scope string, key string, requestHash []byte, lease time.Duration) (*models.IdempotencyRecord, error)
+	// Complete stores the response replayed to later requests with the key for ttl
+	Complete(ctx context.Context, scope string, key string, response []byte, ttl time.Duration) error
+	// Release forgets the key so the next request with it is processed again
+	Release(ctx context.Context, scope string, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func NewIdempotencyRepository(db *sql.DB) IIdempotencyRepository {
+	return &psqlIdempotencyRepository{db: db}
+}
+
+type psqlIdempotencyRepository struct {
+	db *sql.DB
+}
+
+func (p *psqlIdempotencyRepository) Reserve(ctx context.Context, scope string, key string, requestHash []byte, lease time.Duration) (*models.IdempotencyRecord, error) {
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		var reserved string
+		err := p.db.QueryRowContext(ctx, RESERVE_IDEMPOTENCY_KEY, scope, key, requestHash, lease.Seconds()).Scan(&reserved)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		record := &models.IdempotencyRecord{}
+		err = p.db.QueryRowContext(ctx, GET_IDEMPOTENCY_KEY, scope, key).
+			Scan(&record.Scope, &record.Key, &record.RequestHash, &record.Response, &record.CreatedAt, &record.ExpiresAt)
+		if err == nil {
+			return record, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+	}
+	return nil, errUnableToReserveKey
+}
+
+func (p *psqlIdempotencyRepository) Complete(ctx context.Context, scope string, key string, response []byte, ttl time.Duration) error {
+	_, err := p.db.ExecContext(ctx, COMPLETE_IDEMPOTENCY_KEY, scope, key, response, ttl.Seconds())
+	return err
+}
+
+func (p *psqlIdempotencyRepository) Release(ctx context.Context, scope string, key string) error {
+	_, err := p.db.ExecContext(ctx, RELEASE_IDEMPOTENCY_KEY, scope, key)
+	return err
+}
+
+func (p *psqlIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	result, err := p.db.ExecContext(ctx, DELETE_EXPIRED_IDEMPOTENCY_KEY)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
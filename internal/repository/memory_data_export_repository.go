@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+func NewMemoryDataExportRepository(store *MemoryStore) IDataExportRepository {
+	return &memoryDataExportRepository{store: store}
+}
+
+type memoryDataExportRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryDataExportRepository) Create(ctx context.Context, userId int32, tokenHash []byte, ttl time.Duration) (*models.DataExport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if _, ok := m.store.users[userId]; !ok {
+		// mirrors the foreign key of data_exports.user_id
+		return nil, fmt.Errorf("user with id %d not found", userId)
+	}
+	now := time.Now().UTC()
+	m.store.lastExportId++
+	export := &memoryDataExport{
+		DataExport: models.DataExport{
+			Id:        m.store.lastExportId,
+			UserId:    userId,
+			TokenHash: append([]byte(nil), tokenHash...),
+			Status:    models.DATA_EXPORT_PENDING,
+			CreatedAt: now,
+			ExpiresAt: now.Add(ttl),
+		},
+		availableAt: now,
+	}
+	m.store.exports[export.Id] = export
+	result := export.DataExport
+	return &result, nil
+}
+
+func (m *memoryDataExportRepository) GetExport(ctx context.Context, id int64) (*models.DataExport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	export, ok := m.store.exports[id]
+	if !ok {
+		return nil, fmt.Errorf("data export with id %d not found", id)
+	}
+	result := export.DataExport
+	return &result, nil
+}
+
+func (m *memoryDataExportRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.DataExport, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	var claimed []models.DataExport
+	for id := int64(1); id <= m.store.lastExportId && len(claimed) < limit; id++ {
+		export, ok := m.store.exports[id]
+		if !ok || export.Status != models.DATA_EXPORT_PENDING || export.availableAt.After(now) {
+			continue
+		}
+		export.availableAt = now.Add(lease)
+		claimed = append(claimed, export.DataExport)
+	}
+	return claimed, nil
+}
+
+func (m *memoryDataExportRepository) Complete(ctx context.Context, id int64, archive []byte) error {
+	return m.finish(ctx, id, func(export *models.DataExport) {
+		export.Status = models.DATA_EXPORT_READY
+		export.Archive = append([]byte(nil), archive...)
+	})
+}
+
+func (m *memoryDataExportRepository) Fail(ctx context.Context, id int64, reason string) error {
+	return m.finish(ctx, id, func(export *models.DataExport) {
+		export.Status = models.DATA_EXPORT_FAILED
+		export.LastError = reason
+	})
+}
+
+func (m *memoryDataExportRepository) finish(ctx context.Context, id int64, update func(export *models.DataExport)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if export, ok := m.store.exports[id]; ok {
+		update(&export.DataExport)
+		export.CompletedAt = time.Now().UTC()
+	}
+	return nil
+}
+
+func (m *memoryDataExportRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	var deleted int64
+	for id, export := range m.store.exports {
+		if !export.ExpiresAt.After(now) {
+			delete(m.store.exports, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"time"
+)
+
+func NewMemoryEventRepository(store *MemoryStore) IEventRepository {
+	return &memoryEventRepository{store: store}
+}
+
+type memoryEventRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryEventRepository) InsertEvent(ctx context.Context, event string, phoneNumber string) {
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	m.store.appendEvent(event, phoneNumber, time.Now().UTC())
+}
+
+func (m *memoryEventRepository) ListRecentEvents(ctx context.Context, phoneNumber string, window time.Duration) ([]models.UserEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	since := time.Now().UTC().Add(-window)
+	var events []models.UserEvent
+	for _, event := range m.store.events {
+		if event.PhoneNumber == phoneNumber && !event.CreatedAt.Before(since) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (m *memoryEventRepository) ListEvents(ctx context.Context, phoneNumber string, since time.Time, until time.Time) ([]models.UserEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	var events []models.UserEvent
+	for _, event := range m.store.events {
+		if event.PhoneNumber == phoneNumber && !event.CreatedAt.Before(since) && (until.IsZero() || !event.CreatedAt.After(until)) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"time"
+)
+
+func NewMemoryIdempotencyRepository(store *MemoryStore) IIdempotencyRepository {
+	return &memoryIdempotencyRepository{store: store}
+}
+
+type memoryIdempotencyRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryIdempotencyRepository) Reserve(ctx context.Context, scope string, key string, requestHash []byte, lease time.Duration) (*models.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	id := idempotencyKey{scope: scope, key: key}
+	if record, ok := m.store.idempotency[id]; ok && record.ExpiresAt.After(now) {
+		found := *record
+		return &found, nil
+	}
+	m.store.idempotency[id] = &models.IdempotencyRecord{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: append([]byte(nil), requestHash...),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(lease),
+	}
+	return nil, nil
+}
+
+func (m *memoryIdempotencyRepository) Complete(ctx context.Context, scope string, key string, response []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if record, ok := m.store.idempotency[idempotencyKey{scope: scope, key: key}]; ok {
+		record.Response = append([]byte{}, response...)
+		record.ExpiresAt = time.Now().UTC().Add(ttl)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) Release(ctx context.Context, scope string, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	delete(m.store.idempotency, idempotencyKey{scope: scope, key: key})
+	return nil
+}
+
+func (m *memoryIdempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	var deleted int64
+	for id, record := range m.store.idempotency {
+		if !record.ExpiresAt.After(now) {
+			delete(m.store.idempotency, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
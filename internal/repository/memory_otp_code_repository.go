@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+func NewMemoryOtpCodeRepository(store *MemoryStore) IOtpCodeRepository {
+	return &memoryOtpCodeRepository{store: store}
+}
+
+type memoryOtpCodeRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryOtpCodeRepository) SaveCode(ctx context.Context, subject string, codeHash []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	m.store.codes[subject] = &memoryOtpCode{
+		codeHash:  append([]byte(nil), codeHash...),
+		createdAt: now,
+		expiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+func (m *memoryOtpCodeRepository) ConsumeCode(ctx context.Context, subject string, codeHashes [][]byte, maxAttempts int) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	code, ok := m.store.codes[subject]
+	if !ok || !code.expiresAt.After(time.Now().UTC()) {
+		return false, nil
+	}
+	code.attempts++
+	if code.attempts > maxAttempts || !matchesCode(code.codeHash, codeHashes) {
+		return false, nil
+	}
+	delete(m.store.codes, subject)
+	return true, nil
+}
+
+func (m *memoryOtpCodeRepository) DeleteExpiredCodes(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	var deleted int64
+	for subject, code := range m.store.codes {
+		if !code.expiresAt.After(now) {
+			delete(m.store.codes, subject)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
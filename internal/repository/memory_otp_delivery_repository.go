@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+func NewMemoryOtpDeliveryRepository(store *MemoryStore) IOtpDeliveryRepository {
+	return &memoryOtpDeliveryRepository{store: store}
+}
+
+type memoryOtpDeliveryRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryOtpDeliveryRepository) CreateDelivery(ctx context.Context, delivery models.OtpDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if _, ok := m.store.deliveries[delivery.RequestId]; ok {
+		return nil
+	}
+	now := time.Now().UTC()
+	delivery.Status = models.OTP_DELIVERY_PENDING
+	delivery.Reason = ""
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+	m.store.deliveries[delivery.RequestId] = &delivery
+	return nil
+}
+
+func (m *memoryOtpDeliveryRepository) UpdateDeliveryStatus(ctx context.Context, requestId string, status string, reason string, at time.Time) (*models.OtpDelivery, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	delivery, ok := m.store.deliveries[requestId]
+	if !ok {
+		return nil, false, nil
+	}
+	changed := models.OtpDeliveryStatusRank(delivery.Status) < models.OtpDeliveryStatusRank(status)
+	if changed {
+		delivery.Status = status
+		delivery.Reason = reason
+		delivery.UpdatedAt = at.UTC()
+	}
+	result := *delivery
+	return &result, changed, nil
+}
+
+func (m *memoryOtpDeliveryRepository) GetDelivery(ctx context.Context, requestId string, countryCode int32, phoneNumber string) (*models.OtpDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	delivery, ok := m.store.deliveries[requestId]
+	if !ok || delivery.CountryCode != countryCode || delivery.PhoneNumber != phoneNumber {
+		return nil, fmt.Errorf("no otp was sent to phone number %s", phoneNumber)
+	}
+	result := *delivery
+	return &result, nil
+}
+
+func (m *memoryOtpDeliveryRepository) DeleteOldDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	cutoff := time.Now().UTC().Add(-retention)
+	var deleted int64
+	for requestId, delivery := range m.store.deliveries {
+		if !delivery.CreatedAt.After(cutoff) {
+			delete(m.store.deliveries, requestId)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"sort"
+	"time"
+)
+
+func NewMemoryOutboxRepository(store *MemoryStore) IOutboxRepository {
+	return &memoryOutboxRepository{store: store}
+}
+
+type memoryOutboxRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryOutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	var due []*memoryOutboxMessage
+	for _, message := range m.store.outbox {
+		if !message.delivered && !message.failed && !message.AvailableAt.After(now) {
+			due = append(due, message)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Id < due[j].Id })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]models.OutboxMessage, 0, len(due))
+	for _, message := range due {
+		message.AvailableAt = now.Add(lease)
+		claimed = append(claimed, message.OutboxMessage)
+	}
+	return claimed, nil
+}
+
+func (m *memoryOutboxRepository) MarkDelivered(ctx context.Context, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if message, ok := m.store.outbox[id]; ok {
+		message.delivered = true
+	}
+	return nil
+}
+
+func (m *memoryOutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, retryAfter time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if message, ok := m.store.outbox[id]; ok {
+		message.Attempts++
+		message.LastError = reason
+		message.AvailableAt = time.Now().UTC().Add(retryAfter)
+	}
+	return nil
+}
+
+func (m *memoryOutboxRepository) DeadLetter(ctx context.Context, id int64, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if message, ok := m.store.outbox[id]; ok {
+		message.Attempts++
+		message.LastError = reason
+		message.failed = true
+	}
+	return nil
+}
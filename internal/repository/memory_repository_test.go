@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"auth-service/internal/repository"
+	"auth-service/internal/repository/repositorytest"
+	"testing"
+)
+
+func newMemoryRepositories(t *testing.T) repositorytest.Repositories {
+	store := repository.NewMemoryStore()
+	return repositorytest.Repositories{
+		Users:       repository.NewMemoryUserRepository(store),
+		Events:      repository.NewMemoryEventRepository(store),
+		Outbox:      repository.NewMemoryOutboxRepository(store),
+		Idempotency: repository.NewMemoryIdempotencyRepository(store),
+		Exports:     repository.NewMemoryDataExportRepository(store),
+		Deliveries:  repository.NewMemoryOtpDeliveryRepository(store),
+		Codes:       repository.NewMemoryOtpCodeRepository(store),
+	}
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryUserUpdates(t *testing.T) {
+	repositorytest.RunUserUpdateTests(t, newMemoryRepositories)
+}
+
+func TestMemoryPhoneNumberChanges(t *testing.T) {
+	repositorytest.RunPhoneNumberChangeTests(t, newMemoryRepositories)
+}
+
+func TestMemoryUserDeletion(t *testing.T) {
+	repositorytest.RunUserDeletionTests(t, newMemoryRepositories)
+}
+
+func TestMemoryEventRepository(t *testing.T) {
+	repositorytest.RunEventRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryRegistration(t *testing.T) {
+	repositorytest.RunRegistrationTests(t, newMemoryRepositories)
+}
+
+func TestMemoryOutboxRepository(t *testing.T) {
+	repositorytest.RunOutboxRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryDataExportRepository(t *testing.T) {
+	repositorytest.RunDataExportRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryOtpDeliveryRepository(t *testing.T) {
+	repositorytest.RunOtpDeliveryRepositoryTests(t, newMemoryRepositories)
+}
+
+func TestMemoryOtpCodeRepository(t *testing.T) {
+	repositorytest.RunOtpCodeRepositoryTests(t, newMemoryRepositories)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the data of the in-memory repositories. Repositories created from the same store
+// see each other's writes, like repositories sharing a database.
+type MemoryStore struct {
+	mu           sync.RWMutex
+	users        map[int32]*models.User
+	lastUserId   int32
+	phoneNumbers []memoryPhoneNumber
+	events       []models.UserEvent
+	lastEventId  int64
+	outbox       map[int64]*memoryOutboxMessage
+	lastOutbox   int64
+	idempotency  map[idempotencyKey]*models.IdempotencyRecord
+	exports      map[int64]*memoryDataExport
+	lastExportId int64
+	deliveries   map[string]*models.OtpDelivery
+	codes        map[string]*memoryOtpCode
+}
+
+type idempotencyKey struct {
+	scope string
+	key   string
+}
+
+type memoryDataExport struct {
+	models.DataExport
+	availableAt time.Time
+}
+
+type memoryOtpCode struct {
+	codeHash  []byte
+	attempts  int
+	createdAt time.Time
+	expiresAt time.Time
+}
+
+// memoryPhoneNumber is a phone number a user held from acquiredAt until replacedAt, zero when it still holds it
+type memoryPhoneNumber struct {
+	userId      int32
+	phoneNumber string
+	acquiredAt  time.Time
+	replacedAt  time.Time
+}
+
+func (n memoryPhoneNumber) heldAt(at time.Time) bool {
+	return !at.Before(n.acquiredAt) && (n.replacedAt.IsZero() || !at.After(n.replacedAt))
+}
+
+type memoryOutboxMessage struct {
+	models.OutboxMessage
+	delivered bool
+	failed    bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       map[int32]*models.User{},
+		outbox:      map[int64]*memoryOutboxMessage{},
+		idempotency: map[idempotencyKey]*models.IdempotencyRecord{},
+		exports:     map[int64]*memoryDataExport{},
+		deliveries:  map[string]*models.OtpDelivery{},
+		codes:       map[string]*memoryOtpCode{},
+	}
+}
+
+// appendEvent and appendOutbox expect the caller to hold the write lock
+func (s *MemoryStore) appendEvent(event string, phoneNumber string, at time.Time) {
+	s.lastEventId++
+	s.events = append(s.events, models.UserEvent{Id: s.lastEventId, PhoneNumber: phoneNumber, Event: event, CreatedAt: at})
+}
+
+func (s *MemoryStore) appendOutbox(message models.OutboxMessage, at time.Time) {
+	s.lastOutbox++
+	message.Id = s.lastOutbox
+	message.Attempts = 0
+	message.LastError = ""
+	message.AvailableAt = at
+	message.CreatedAt = at
+	s.outbox[message.Id] = &memoryOutboxMessage{OutboxMessage: message}
+}
@@ -0,0 +1,371 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NewMemoryUserRepository returns a thread-safe IUserRepository enforcing the same unique fields as the users table
+func NewMemoryUserRepository(store *MemoryStore) IUserRepository {
+	return &memoryUserRepository{store: store}
+}
+
+type memoryUserRepository struct {
+	store *MemoryStore
+}
+
+func (m *memoryUserRepository) SaveUser(ctx context.Context, user *models.User) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if err := m.checkUnique(user); err != nil {
+		return nil, err
+	}
+	m.store.lastUserId++
+	saved := *user
+	saved.Id = m.store.lastUserId
+	saved.CreatedAt = time.Now().UTC()
+	saved.UpdatedAt = saved.CreatedAt
+	saved.Version = 1
+	saved.Status = models.USER_STATUS_ACTIVE
+	m.store.users[saved.Id] = &saved
+	result := saved
+	return &result, nil
+}
+
+func (m *memoryUserRepository) GetUser(ctx context.Context, userId int32) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	user, ok := m.store.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not found", userId)
+	}
+	result := *user
+	return &result, nil
+}
+
+func (m *memoryUserRepository) GetUserByPhoneNumberAndCountry(ctx context.Context, countryCode int32, phoneNumber string) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	for _, user := range m.store.users {
+		if user.CountryCode == countryCode && user.PhoneNumber == phoneNumber {
+			result := *user
+			return &result, nil
+		}
+	}
+	return nil, fmt.Errorf("user with country code %d and phone number %s not found", countryCode, phoneNumber)
+}
+
+func (m *memoryUserRepository) MarkVerified(ctx context.Context, id int32) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	if user, ok := m.store.users[id]; ok {
+		user.Verified = true
+	}
+	return nil
+}
+
+func (m *memoryUserRepository) IsPhoneNumberTaken(ctx context.Context, countryCode int32, phoneNumber string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	for _, user := range m.store.users {
+		if user.CountryCode == countryCode && user.PhoneNumber == phoneNumber {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryUserRepository) ListPhoneNumbers(ctx context.Context, userId int32) ([]models.HeldPhoneNumber, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	var numbers []models.HeldPhoneNumber
+	for _, number := range m.store.phoneNumbers {
+		if number.userId == userId {
+			numbers = append(numbers, models.HeldPhoneNumber{PhoneNumber: number.phoneNumber, AcquiredAt: number.acquiredAt, ReplacedAt: number.replacedAt})
+		}
+	}
+	if user, ok := m.store.users[userId]; ok && user.PhoneNumber != "" {
+		numbers = append(numbers, models.HeldPhoneNumber{PhoneNumber: user.PhoneNumber, AcquiredAt: phoneNumberAcquiredAt(user)})
+	}
+	return numbers, nil
+}
+
+func (m *memoryUserRepository) IsUserNameTaken(ctx context.Context, userName string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	for _, user := range m.store.users {
+		if strings.EqualFold(user.UserName, userName) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkUnique mirrors the unique constraints of the users table, the caller holds the write lock
+func (m *memoryUserRepository) checkUnique(candidate *models.User) error {
+	for _, user := range m.store.users {
+		if user.Id == candidate.Id {
+			continue
+		}
+		switch {
+		case strings.EqualFold(user.UserName, candidate.UserName):
+			return &models.AlreadyExistsError{Field: models.FIELD_USER_NAME}
+		case user.Email == candidate.Email || user.CanonicalEmail == candidate.CanonicalEmail:
+			return &models.AlreadyExistsError{Field: models.FIELD_EMAIL}
+		case candidate.PhoneNumber != "" && user.CountryCode == candidate.CountryCode && user.PhoneNumber == candidate.PhoneNumber:
+			return &models.AlreadyExistsError{Field: models.FIELD_PHONE_NUMBER}
+		}
+	}
+	return nil
+}
+
+func (m *memoryUserRepository) RegisterUser(ctx context.Context, registration Registration) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	now := time.Now().UTC()
+	if registration.ReclaimUnverifiedAfter > 0 {
+		m.reclaimUnverified(registration.User, now.Add(-registration.ReclaimUnverifiedAfter))
+	}
+	if err := m.checkUnique(registration.User); err != nil {
+		return nil, err
+	}
+	m.store.lastUserId++
+	saved := *registration.User
+	saved.Id = m.store.lastUserId
+	saved.CreatedAt = now
+	saved.UpdatedAt = now
+	saved.Version = 1
+	saved.Status = models.USER_STATUS_ACTIVE
+	m.store.users[saved.Id] = &saved
+	m.store.appendEvent(registration.Event, saved.PhoneNumber, now)
+	m.store.appendOutbox(registration.Outbox, now)
+	result := saved
+	return &result, nil
+}
+
+func (m *memoryUserRepository) UpdateUser(ctx context.Context, user *models.User, expectedVersion int64) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	stored, ok := m.store.users[user.Id]
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not found", user.Id)
+	}
+	if stored.Version != expectedVersion {
+		return nil, models.ErrStaleVersion
+	}
+	if err := m.checkUnique(user); err != nil {
+		return nil, err
+	}
+	stored.Name = user.Name
+	stored.UserName = user.UserName
+	stored.Email = user.Email
+	stored.CanonicalEmail = user.CanonicalEmail
+	stored.PendingEmail = user.PendingEmail
+	stored.PendingCanonicalEmail = user.PendingCanonicalEmail
+	stored.PendingCountryCode = user.PendingCountryCode
+	stored.PendingPhoneNumber = user.PendingPhoneNumber
+	stored.EmailConfirmedAt = user.EmailConfirmedAt
+	stored.Version++
+	stored.UpdatedAt = time.Now().UTC()
+	result := *stored
+	return &result, nil
+}
+
+func (m *memoryUserRepository) ChangePhoneNumber(ctx context.Context, change PhoneNumberChange) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	stored, ok := m.store.users[change.UserId]
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not found", change.UserId)
+	}
+	if stored.Version != change.ExpectedVersion {
+		return nil, models.ErrStaleVersion
+	}
+	if stored.PendingPhoneNumber == "" {
+		return nil, fmt.Errorf("user with id %d has no pending phone number", change.UserId)
+	}
+	changed := *stored
+	changed.CountryCode, changed.PhoneNumber = stored.PendingCountryCode, stored.PendingPhoneNumber
+	changed.PendingCountryCode, changed.PendingPhoneNumber = 0, ""
+	if err := m.checkUnique(&changed); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	if stored.PhoneNumber != "" {
+		m.store.phoneNumbers = append(m.store.phoneNumbers, memoryPhoneNumber{
+			userId: stored.Id, phoneNumber: stored.PhoneNumber, acquiredAt: phoneNumberAcquiredAt(stored), replacedAt: now,
+		})
+	}
+	changed.Version++
+	changed.UpdatedAt = now
+	changed.SessionsRevokedAt = now
+	for _, event := range change.OldNumberEvents {
+		m.store.appendEvent(event, stored.PhoneNumber, now)
+	}
+	for _, event := range change.NewNumberEvents {
+		m.store.appendEvent(event, changed.PhoneNumber, now)
+	}
+	*stored = changed
+	result := changed
+	return &result, nil
+}
+
+// reclaimUnverified purges unverified users created before the cutoff that conflict with the candidate,
+// the caller holds the write lock
+func (m *memoryUserRepository) reclaimUnverified(candidate *models.User, cutoff time.Time) {
+	for id, user := range m.store.users {
+		if user.Verified || !user.CreatedAt.Before(cutoff) {
+			continue
+		}
+		if strings.EqualFold(user.UserName, candidate.UserName) || user.Email == candidate.Email ||
+			user.CanonicalEmail == candidate.CanonicalEmail ||
+			(user.CountryCode == candidate.CountryCode && user.PhoneNumber == candidate.PhoneNumber) {
+			m.purgeUser(id)
+		}
+	}
+}
+
+// purgeUser deletes the user together with everything stored under the phone numbers it held while it held them,
+// its events are kept under "deleted-<user id>". The caller holds the write lock
+func (m *memoryUserRepository) purgeUser(id int32) {
+	user := m.store.users[id]
+	var held, kept []memoryPhoneNumber
+	if user.PhoneNumber != "" {
+		held = append(held, memoryPhoneNumber{userId: id, phoneNumber: user.PhoneNumber, acquiredAt: phoneNumberAcquiredAt(user)})
+	}
+	for _, number := range m.store.phoneNumbers {
+		if number.userId == id {
+			held = append(held, number)
+		} else {
+			kept = append(kept, number)
+		}
+	}
+	m.store.phoneNumbers = kept
+	for _, number := range held {
+		for i := range m.store.events {
+			if m.store.events[i].PhoneNumber == number.phoneNumber && number.heldAt(m.store.events[i].CreatedAt) {
+				m.store.events[i].PhoneNumber = fmt.Sprintf("deleted-%d", id)
+			}
+		}
+		for requestId, delivery := range m.store.deliveries {
+			if delivery.PhoneNumber == number.phoneNumber && number.heldAt(delivery.CreatedAt) {
+				delete(m.store.deliveries, requestId)
+			}
+		}
+		if code, ok := m.store.codes[number.phoneNumber]; ok && number.heldAt(code.createdAt) {
+			delete(m.store.codes, number.phoneNumber)
+		}
+		for messageId, message := range m.store.outbox {
+			if message.PhoneNumber == number.phoneNumber && number.heldAt(message.CreatedAt) {
+				delete(m.store.outbox, messageId)
+			}
+		}
+	}
+	for exportId, export := range m.store.exports {
+		if export.UserId == id {
+			delete(m.store.exports, exportId)
+		}
+	}
+	delete(m.store.users, id)
+}
+
+// phoneNumberAcquiredAt is when the user got its current phone number, at signup or with the last phone number change
+func phoneNumberAcquiredAt(user *models.User) time.Time {
+	if !user.SessionsRevokedAt.IsZero() {
+		return user.SessionsRevokedAt
+	}
+	return user.CreatedAt
+}
+
+func (m *memoryUserRepository) SetUserStatus(ctx context.Context, userId int32, status string, expectedVersion int64) (*models.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	stored, ok := m.store.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("user with id %d not found", userId)
+	}
+	if stored.Version != expectedVersion {
+		return nil, models.ErrStaleVersion
+	}
+	now := time.Now().UTC()
+	stored.Status = status
+	stored.DeletedAt = time.Time{}
+	if status == models.USER_STATUS_DELETED {
+		stored.DeletedAt = now
+	}
+	stored.Version++
+	stored.UpdatedAt = now
+	result := *stored
+	return &result, nil
+}
+
+func (m *memoryUserRepository) PurgeDeletedUsers(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	cutoff := time.Now().UTC().Add(-gracePeriod)
+	var purged int64
+	for id, user := range m.store.users {
+		if !user.IsDeleted() || !user.DeletedAt.Before(cutoff) {
+			continue
+		}
+		m.purgeUser(id)
+		purged++
+	}
+	return purged, nil
+}
+
+func (m *memoryUserRepository) DeleteExpiredUnverified(ctx context.Context, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.store.mu.Lock()
+	defer m.store.mu.Unlock()
+	cutoff := time.Now().UTC().Add(-ttl)
+	var deleted int64
+	for id, user := range m.store.users {
+		if !user.Verified && user.CreatedAt.Before(cutoff) {
+			m.purgeUser(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
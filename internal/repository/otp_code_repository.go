@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	// SAVE_OTP_CODE replaces the code of the subject, so only the last otp sent to it is accepted
+	SAVE_OTP_CODE = `
+		INSERT INTO otp_codes (subject, code_hash, expires_at)
+		VALUES ($1, $2, CURRENT_TIMESTAMP + make_interval(secs => $3))
+		ON CONFLICT (subject) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash, attempts = 0, created_at = CURRENT_TIMESTAMP, expires_at = EXCLUDED.expires_at
+		`
+	// COUNT_OTP_CODE_ATTEMPT counts an attempt to verify the unexpired code of the subject before it is compared
+	COUNT_OTP_CODE_ATTEMPT = `
+		UPDATE otp_codes SET attempts = attempts + 1
+		WHERE subject = $1 AND expires_at > CURRENT_TIMESTAMP
+		RETURNING code_hash, attempts
+		`
+	CONSUME_OTP_CODE         = "DELETE FROM otp_codes WHERE subject = $1 AND code_hash = $2 RETURNING subject"
+	DELETE_EXPIRED_OTP_CODES = "DELETE FROM otp_codes WHERE expires_at <= CURRENT_TIMESTAMP"
+)
+
+type IOtpCodeRepository interface {
+	// SaveCode stores the hash of the otp sent to the subject for ttl, replacing the code sent to it before
+	SaveCode(ctx context.Context, subject string, codeHash []byte, ttl time.Duration) error
+	// ConsumeCode reports if one of the hashes matches the unexpired code of the subject and removes the code when it
+	// does. Every attempt counts, the code is not accepted anymore after maxAttempts. There is a hash per secret key
+	// the code may have been hashed with
+	ConsumeCode(ctx context.Context, subject string, codeHashes [][]byte, maxAttempts int) (bool, error)
+	DeleteExpiredCodes(ctx context.Context) (int64, error)
+}
+
+func NewOtpCodeRepository(db *sql.DB) IOtpCodeRepository {
+	return &psqlOtpCodeRepository{db: db}
+}
+
+type psqlOtpCodeRepository struct {
+	db *sql.DB
+}
+
+func (p *psqlOtpCodeRepository) SaveCode(ctx context.Context, subject string, codeHash []byte, ttl time.Duration) error {
+	_, err := p.db.ExecContext(ctx, SAVE_OTP_CODE, subject, codeHash, ttl.Seconds())
+	return err
+}
+
+func (p *psqlOtpCodeRepository) ConsumeCode(ctx context.Context, subject string, codeHashes [][]byte, maxAttempts int) (bool, error) {
+	var storedHash []byte
+	var attempts int
+	err := p.db.QueryRowContext(ctx, COUNT_OTP_CODE_ATTEMPT, subject).Scan(&storedHash, &attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if attempts > maxAttempts || !matchesCode(storedHash, codeHashes) {
+		return false, nil
+	}
+	// a concurrent attempt with the same code may have consumed it meanwhile, only one of them succeeds
+	var consumed string
+	err = p.db.QueryRowContext(ctx, CONSUME_OTP_CODE, subject, storedHash).Scan(&consumed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func matchesCode(storedHash []byte, codeHashes [][]byte) bool {
+	for _, codeHash := range codeHashes {
+		if subtle.ConstantTimeCompare(storedHash, codeHash) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *psqlOtpCodeRepository) DeleteExpiredCodes(ctx context.Context) (int64, error) {
+	result, err := p.db.ExecContext(ctx, DELETE_EXPIRED_OTP_CODES)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	OTP_DELIVERY_COLUMNS = "request_id, country_code, phone_number, channel, status, COALESCE(reason, ''), created_at, updated_at"
+	INSERT_OTP_DELIVERY  = `
+		INSERT INTO otp_deliveries (request_id, country_code, phone_number, channel)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (request_id) DO NOTHING`
+	// UPDATE_OTP_DELIVERY_STATUS only moves a delivery forward, so receipts arriving out of order or twice change
+	// nothing. The CASE expressions mirror models.OtpDeliveryStatusRank
+	UPDATE_OTP_DELIVERY_STATUS = `
+		UPDATE otp_deliveries SET status = $2::VARCHAR, reason = NULLIF($3, ''), updated_at = $4
+		WHERE request_id = $1
+		AND (CASE status WHEN 'PENDING' THEN 0 WHEN 'QUEUED' THEN 1 WHEN 'SENT' THEN 2 ELSE 3 END)
+			< (CASE $2::VARCHAR WHEN 'PENDING' THEN 0 WHEN 'QUEUED' THEN 1 WHEN 'SENT' THEN 2 ELSE 3 END)
+		RETURNING ` + OTP_DELIVERY_COLUMNS
+	GET_OTP_DELIVERY              = "SELECT " + OTP_DELIVERY_COLUMNS + " FROM otp_deliveries WHERE request_id = $1"
+	GET_PHONE_NUMBER_OTP_DELIVERY = "SELECT " + OTP_DELIVERY_COLUMNS + " FROM otp_deliveries WHERE request_id = $1 AND country_code = $2 AND phone_number = $3"
+	DELETE_OLD_OTP_DELIVERIES     = "DELETE FROM otp_deliveries WHERE created_at <= CURRENT_TIMESTAMP - make_interval(secs => $1)"
+)
+
+type IOtpDeliveryRepository interface {
+	// CreateDelivery records a published otp request as pending, recording the same request again changes nothing
+	CreateDelivery(ctx context.Context, delivery models.OtpDelivery) error
+	// UpdateDeliveryStatus moves a delivery forward to the status of a receipt and reports if it changed. Receipts
+	// for an earlier status arriving late are ignored, the delivery is nil when the request id is unknown
+	UpdateDeliveryStatus(ctx context.Context, requestId string, status string, reason string, at time.Time) (*models.OtpDelivery, bool, error)
+	// GetDelivery returns the otp delivery of the request, only if it was sent to the phone number with the country code
+	GetDelivery(ctx context.Context, requestId string, countryCode int32, phoneNumber string) (*models.OtpDelivery, error)
+	// DeleteOldDeliveries removes deliveries created more than retention ago and returns how many were removed
+	DeleteOldDeliveries(ctx context.Context, retention time.Duration) (int64, error)
+}
+
+func NewOtpDeliveryRepository(db *sql.DB) IOtpDeliveryRepository {
+	return &psqlOtpDeliveryRepository{db: db}
+}
+
+type psqlOtpDeliveryRepository struct {
+	db *sql.DB
+}
+
+func scanOtpDelivery(row rowScanner) (*models.OtpDelivery, error) {
+	var delivery models.OtpDelivery
+	err := row.Scan(&delivery.RequestId, &delivery.CountryCode, &delivery.PhoneNumber, &delivery.Channel, &delivery.Status,
+		&delivery.Reason, &delivery.CreatedAt, &delivery.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (p *psqlOtpDeliveryRepository) CreateDelivery(ctx context.Context, delivery models.OtpDelivery) error {
+	_, err := p.db.ExecContext(ctx, INSERT_OTP_DELIVERY, delivery.RequestId, delivery.CountryCode, delivery.PhoneNumber, delivery.Channel)
+	return err
+}
+
+func (p *psqlOtpDeliveryRepository) UpdateDeliveryStatus(ctx context.Context, requestId string, status string, reason string, at time.Time) (*models.OtpDelivery, bool, error) {
+	delivery, err := scanOtpDelivery(p.db.QueryRowContext(ctx, UPDATE_OTP_DELIVERY_STATUS, requestId, status, reason, at.UTC()))
+	if err == nil {
+		return delivery, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, false, err
+	}
+	// nothing was updated, either the request id is unknown or the delivery is already further along
+	delivery, err = scanOtpDelivery(p.db.QueryRowContext(ctx, GET_OTP_DELIVERY, requestId))
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	return delivery, false, err
+}
+
+func (p *psqlOtpDeliveryRepository) GetDelivery(ctx context.Context, requestId string, countryCode int32, phoneNumber string) (*models.OtpDelivery, error) {
+	delivery, err := scanOtpDelivery(p.db.QueryRowContext(ctx, GET_PHONE_NUMBER_OTP_DELIVERY, requestId, countryCode, phoneNumber))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no otp was sent to phone number %s", phoneNumber)
+	}
+	return delivery, err
+}
+
+func (p *psqlOtpDeliveryRepository) DeleteOldDeliveries(ctx context.Context, retention time.Duration) (int64, error) {
+	result, err := p.db.ExecContext(ctx, DELETE_OLD_OTP_DELIVERIES, retention.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+const (
+	INSERT_OUTBOX_MESSAGE = `
+		INSERT INTO outbox_messages (topic, payload, correlation_id, trace_parent, trace_state, phone_number)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''))`
+	// CLAIM_OUTBOX_MESSAGES leases due messages to one relay, SKIP LOCKED lets concurrent relays claim other rows
+	CLAIM_OUTBOX_MESSAGES = `
+		UPDATE outbox_messages SET available_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE delivered_at IS NULL AND failed_at IS NULL AND available_at <= CURRENT_TIMESTAMP
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, topic, payload, COALESCE(correlation_id, ''), COALESCE(trace_parent, ''), COALESCE(trace_state, ''),
+			attempts, COALESCE(last_error, ''), available_at, created_at
+		`
+	MARK_OUTBOX_DELIVERED = "UPDATE outbox_messages SET delivered_at = CURRENT_TIMESTAMP WHERE id = $1"
+	MARK_OUTBOX_FAILED    = `
+		UPDATE outbox_messages
+		SET attempts = attempts + 1, last_error = $2, available_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		WHERE id = $1
+		`
+	DEAD_LETTER_OUTBOX_MESSAGE = `
+		UPDATE outbox_messages SET attempts = attempts + 1, last_error = $2, failed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		`
+)
+
+type IOutboxRepository interface {
+	// ClaimPending returns up to limit due messages and hides them from other relays for the lease duration
+	ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id int64) error
+	// MarkFailed records the failure and makes the message due again after retryAfter
+	MarkFailed(ctx context.Context, id int64, reason string, retryAfter time.Duration) error
+	// DeadLetter records the failure and stops the message from being claimed again, it is kept for inspection
+	DeadLetter(ctx context.Context, id int64, reason string) error
+}
+
+func NewOutboxRepository(db *sql.DB) IOutboxRepository {
+	return &psqlOutboxRepository{db: db}
+}
+
+type psqlOutboxRepository struct {
+	db *sql.DB
+}
+
+func (p *psqlOutboxRepository) ClaimPending(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMessage, error) {
+	rows, err := p.db.QueryContext(ctx, CLAIM_OUTBOX_MESSAGES, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var messages []models.OutboxMessage
+	for rows.Next() {
+		var message models.OutboxMessage
+		err = rows.Scan(&message.Id, &message.Topic, &message.Payload, &message.CorrelationId, &message.TraceParent, &message.TraceState, &message.Attempts, &message.LastError, &message.AvailableAt, &message.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (p *psqlOutboxRepository) MarkDelivered(ctx context.Context, id int64) error {
+	_, err := p.db.ExecContext(ctx, MARK_OUTBOX_DELIVERED, id)
+	return err
+}
+
+func (p *psqlOutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, retryAfter time.Duration) error {
+	_, err := p.db.ExecContext(ctx, MARK_OUTBOX_FAILED, id, reason, retryAfter.Seconds())
+	return err
+}
+
+func (p *psqlOutboxRepository) DeadLetter(ctx context.Context, id int64, reason string) error {
+	_, err := p.db.ExecContext(ctx, DEAD_LETTER_OUTBOX_MESSAGE, id, reason)
+	return err
+}
@@ -0,0 +1,88 @@
+package repository_test
+
+import (
+	"auth-service/internal/migrations"
+	"auth-service/internal/repository"
+	"auth-service/internal/repository/repositorytest"
+	"context"
+	"database/sql"
+	_ "github.com/lib/pq"
+	"os"
+	"testing"
+)
+
+// the postgres conformance tests need TEST_DATABASE_URL pointing to a disposable database, its tables are truncated
+func newPostgresRepositories(t *testing.T) repositorytest.Repositories {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec("TRUNCATE users, user_events, outbox_messages, idempotency_keys, data_exports, otp_deliveries, otp_codes RESTART IDENTITY CASCADE"); err != nil {
+		t.Fatal(err)
+	}
+	return repositorytest.Repositories{
+		Users:       repository.NewUserRepository(db),
+		Events:      repository.NewEventRepository(db),
+		Outbox:      repository.NewOutboxRepository(db),
+		Idempotency: repository.NewIdempotencyRepository(db),
+		Exports:     repository.NewDataExportRepository(db),
+		Deliveries:  repository.NewOtpDeliveryRepository(db),
+		Codes:       repository.NewOtpCodeRepository(db),
+	}
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	repositorytest.RunUserRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresUserUpdates(t *testing.T) {
+	repositorytest.RunUserUpdateTests(t, newPostgresRepositories)
+}
+
+func TestPostgresPhoneNumberChanges(t *testing.T) {
+	repositorytest.RunPhoneNumberChangeTests(t, newPostgresRepositories)
+}
+
+func TestPostgresUserDeletion(t *testing.T) {
+	repositorytest.RunUserDeletionTests(t, newPostgresRepositories)
+}
+
+func TestPostgresEventRepository(t *testing.T) {
+	repositorytest.RunEventRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresRegistration(t *testing.T) {
+	repositorytest.RunRegistrationTests(t, newPostgresRepositories)
+}
+
+func TestPostgresOutboxRepository(t *testing.T) {
+	repositorytest.RunOutboxRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresIdempotencyRepository(t *testing.T) {
+	repositorytest.RunIdempotencyRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresDataExportRepository(t *testing.T) {
+	repositorytest.RunDataExportRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresOtpDeliveryRepository(t *testing.T) {
+	repositorytest.RunOtpDeliveryRepositoryTests(t, newPostgresRepositories)
+}
+
+func TestPostgresOtpCodeRepository(t *testing.T) {
+	repositorytest.RunOtpCodeRepositoryTests(t, newPostgresRepositories)
+}
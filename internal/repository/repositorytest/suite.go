@@ -0,0 +1,1097 @@
+// Package repositorytest holds the conformance tests every repository implementation has to pass
+package repositorytest
+
+import (
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Repositories is a set of repositories sharing the same empty storage
+type Repositories struct {
+	Users       repository.IUserRepository
+	Events      repository.IEventRepository
+	Outbox      repository.IOutboxRepository
+	Idempotency repository.IIdempotencyRepository
+	Exports     repository.IDataExportRepository
+	Deliveries  repository.IOtpDeliveryRepository
+	Codes       repository.IOtpCodeRepository
+}
+
+// Factory creates repositories backed by empty storage for each test
+type Factory func(t *testing.T) Repositories
+
+func newUser(suffix string) *models.User {
+	return &models.User{
+		Name:           "John Doe",
+		UserName:       "johndoe" + suffix,
+		Email:          fmt.Sprintf("john%s@example.com", suffix),
+		CanonicalEmail: fmt.Sprintf("john%s@example.com", suffix),
+		CountryCode:    91,
+		PhoneNumber:    "98765432" + fmt.Sprintf("%02s", suffix),
+	}
+}
+
+func requireNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func assertAlreadyExists(t *testing.T, err error, field string) {
+	t.Helper()
+	var alreadyExists *models.AlreadyExistsError
+	if assert.True(t, errors.As(err, &alreadyExists), "expected AlreadyExistsError, got %v", err) {
+		assert.Equal(t, field, alreadyExists.Field)
+	}
+}
+
+// RunUserRepositoryTests checks the IUserRepository contract
+func RunUserRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("SaveUser populates id and created at", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		assert.NotZero(t, saved.Id)
+		assert.False(t, saved.CreatedAt.IsZero())
+		assert.Equal(t, "johndoe1", saved.UserName)
+		assert.Equal(t, "john1@example.com", saved.CanonicalEmail)
+		assert.Equal(t, int64(1), saved.Version)
+	})
+
+	t.Run("GetUser returns every field", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		found, err := users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.Equal(t, saved, found)
+	})
+
+	t.Run("GetUser fails for unknown ids", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.GetUser(ctx, 4242)
+		assert.EqualError(t, err, "user with id 4242 not found")
+	})
+
+	t.Run("GetUserByPhoneNumberAndCountry returns every field", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		found, err := users.GetUserByPhoneNumberAndCountry(ctx, saved.CountryCode, saved.PhoneNumber)
+		requireNoError(t, err)
+		assert.Equal(t, saved, found)
+	})
+
+	t.Run("GetUserByPhoneNumberAndCountry fails for unknown numbers", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.GetUserByPhoneNumberAndCountry(ctx, 91, "1111111111")
+		assert.EqualError(t, err, "user with country code 91 and phone number 1111111111 not found")
+	})
+
+	t.Run("SaveUser rejects duplicate phone numbers", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		duplicate := newUser("2")
+		duplicate.PhoneNumber = newUser("1").PhoneNumber
+		_, err = users.SaveUser(ctx, duplicate)
+		assertAlreadyExists(t, err, models.FIELD_PHONE_NUMBER)
+	})
+
+	t.Run("SaveUser accepts a phone number under another country code", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		other := newUser("2")
+		other.CountryCode, other.PhoneNumber = 1, saved.PhoneNumber
+		_, err = users.SaveUser(ctx, other)
+		requireNoError(t, err)
+	})
+
+	t.Run("SaveUser rejects user names differing only in case", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		duplicate := newUser("2")
+		duplicate.UserName = "JohnDoe1"
+		_, err = users.SaveUser(ctx, duplicate)
+		assertAlreadyExists(t, err, models.FIELD_USER_NAME)
+	})
+
+	t.Run("SaveUser rejects duplicate canonical emails", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		duplicate := newUser("2")
+		duplicate.Email = "john.1@example.com"
+		duplicate.CanonicalEmail = newUser("1").CanonicalEmail
+		_, err = users.SaveUser(ctx, duplicate)
+		assertAlreadyExists(t, err, models.FIELD_EMAIL)
+	})
+
+	t.Run("MarkVerified sets the verified flag", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		requireNoError(t, users.MarkVerified(ctx, saved.Id))
+		found, err := users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.True(t, found.Verified)
+	})
+
+	t.Run("IsUserNameTaken ignores case", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		taken, err := users.IsUserNameTaken(ctx, "JOHNDOE1")
+		requireNoError(t, err)
+		assert.True(t, taken)
+		taken, err = users.IsUserNameTaken(ctx, "janedoe")
+		requireNoError(t, err)
+		assert.False(t, taken)
+	})
+
+	t.Run("IsPhoneNumberTaken", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		taken, err := users.IsPhoneNumberTaken(ctx, saved.CountryCode, saved.PhoneNumber)
+		requireNoError(t, err)
+		assert.True(t, taken)
+		taken, err = users.IsPhoneNumberTaken(ctx, saved.CountryCode, "5559999999")
+		requireNoError(t, err)
+		assert.False(t, taken)
+		taken, err = users.IsPhoneNumberTaken(ctx, 1, saved.PhoneNumber)
+		requireNoError(t, err)
+		assert.False(t, taken)
+	})
+
+	t.Run("concurrent signups with the same phone number store one user", func(t *testing.T) {
+		users := factory(t).Users
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var saved int
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				user := newUser(fmt.Sprint(i + 10))
+				user.PhoneNumber = "9876543299"
+				if _, err := users.SaveUser(ctx, user); err == nil {
+					mu.Lock()
+					saved++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, 1, saved)
+	})
+}
+
+func newRegistration(suffix string) repository.Registration {
+	user := newUser(suffix)
+	return repository.Registration{
+		User:   user,
+		Event:  "SIGN_REQUEST_OTP",
+		Outbox: models.OutboxMessage{Topic: "otp.generate", Payload: []byte("payload-" + suffix), PhoneNumber: user.PhoneNumber},
+	}
+}
+
+// RunUserUpdateTests checks the optimistic concurrency of IUserRepository.UpdateUser
+func RunUserUpdateTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("UpdateUser stores the fields and increments the version", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		changed := *saved
+		changed.Name = "Jane Doe"
+		changed.UserName = "janedoe"
+		changed.PendingEmail, changed.PendingCanonicalEmail = "jane@example.com", "jane@example.com"
+		updated, err := users.UpdateUser(ctx, &changed, saved.Version)
+		requireNoError(t, err)
+		assert.Equal(t, saved.Version+1, updated.Version)
+		assert.Equal(t, "Jane Doe", updated.Name)
+		assert.Equal(t, "janedoe", updated.UserName)
+		assert.Equal(t, saved.Email, updated.Email)
+		assert.Equal(t, "jane@example.com", updated.PendingEmail)
+		found, err := users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.Equal(t, updated, found)
+
+		changed = *updated
+		changed.Email, changed.CanonicalEmail = updated.PendingEmail, updated.PendingCanonicalEmail
+		changed.PendingEmail, changed.PendingCanonicalEmail = "", ""
+		changed.EmailConfirmedAt = time.Now()
+		updated, err = users.UpdateUser(ctx, &changed, updated.Version)
+		requireNoError(t, err)
+		assert.Equal(t, "jane@example.com", updated.Email)
+		assert.Empty(t, updated.PendingEmail)
+		assert.WithinDuration(t, changed.EmailConfirmedAt, updated.EmailConfirmedAt, time.Second)
+	})
+
+	t.Run("UpdateUser rejects stale versions", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		first := *saved
+		first.Name = "First"
+		_, err = users.UpdateUser(ctx, &first, saved.Version)
+		requireNoError(t, err)
+		second := *saved
+		second.Name = "Second"
+		_, err = users.UpdateUser(ctx, &second, saved.Version)
+		assert.ErrorIs(t, err, models.ErrStaleVersion)
+		found, err := users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.Equal(t, "First", found.Name)
+	})
+
+	t.Run("UpdateUser enforces unique fields", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		saved, err := users.SaveUser(ctx, newUser("2"))
+		requireNoError(t, err)
+		changed := *saved
+		changed.UserName = "JohnDoe1"
+		_, err = users.UpdateUser(ctx, &changed, saved.Version)
+		assertAlreadyExists(t, err, models.FIELD_USER_NAME)
+	})
+
+	t.Run("UpdateUser fails for unknown ids", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.UpdateUser(ctx, &models.User{Id: 4242}, 1)
+		assert.EqualError(t, err, "user with id 4242 not found")
+	})
+}
+
+// RunPhoneNumberChangeTests checks that ChangePhoneNumber swaps the phone number and records the events of both numbers
+func RunPhoneNumberChangeTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	withPendingNumber := func(t *testing.T, users repository.IUserRepository, suffix string, pending string) *models.User {
+		saved, err := users.SaveUser(ctx, newUser(suffix))
+		requireNoError(t, err)
+		changed := *saved
+		changed.PendingCountryCode, changed.PendingPhoneNumber = 1, pending
+		updated, err := users.UpdateUser(ctx, &changed, saved.Version)
+		requireNoError(t, err)
+		assert.Equal(t, int32(1), updated.PendingCountryCode)
+		assert.Equal(t, pending, updated.PendingPhoneNumber)
+		return updated
+	}
+	change := func(user *models.User) repository.PhoneNumberChange {
+		return repository.PhoneNumberChange{
+			UserId:          user.Id,
+			ExpectedVersion: user.Version,
+			OldNumberEvents: []string{"PHONE_CHANGED", "LOGOUT"},
+			NewNumberEvents: []string{"PHONE_CHANGED"},
+		}
+	}
+
+	t.Run("ChangePhoneNumber moves the pending number into place", func(t *testing.T) {
+		repositories := factory(t)
+		user := withPendingNumber(t, repositories.Users, "1", "5550000001")
+		changed, err := repositories.Users.ChangePhoneNumber(ctx, change(user))
+		requireNoError(t, err)
+		assert.Equal(t, int32(1), changed.CountryCode)
+		assert.Equal(t, "5550000001", changed.PhoneNumber)
+		assert.Empty(t, changed.PendingPhoneNumber)
+		assert.Zero(t, changed.PendingCountryCode)
+		assert.Equal(t, user.Version+1, changed.Version)
+		assert.False(t, changed.SessionsRevokedAt.IsZero())
+		found, err := repositories.Users.GetUserByPhoneNumberAndCountry(ctx, 1, "5550000001")
+		requireNoError(t, err)
+		assert.Equal(t, changed, found)
+		_, err = repositories.Users.GetUserByPhoneNumberAndCountry(ctx, user.CountryCode, user.PhoneNumber)
+		assert.Error(t, err)
+
+		oldEvents, err := repositories.Events.ListRecentEvents(ctx, user.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, oldEvents, 2) {
+			assert.Equal(t, "PHONE_CHANGED", oldEvents[0].Event)
+			assert.Equal(t, "LOGOUT", oldEvents[1].Event)
+		}
+		newEvents, err := repositories.Events.ListRecentEvents(ctx, "5550000001", time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, newEvents, 1) {
+			assert.Equal(t, "PHONE_CHANGED", newEvents[0].Event)
+		}
+	})
+
+	t.Run("ListPhoneNumbers lists the replaced numbers before the current one", func(t *testing.T) {
+		repositories := factory(t)
+		user := withPendingNumber(t, repositories.Users, "1", "5550000001")
+		numbers, err := repositories.Users.ListPhoneNumbers(ctx, user.Id)
+		requireNoError(t, err)
+		if assert.Len(t, numbers, 1) {
+			assert.Equal(t, user.PhoneNumber, numbers[0].PhoneNumber)
+			assert.True(t, numbers[0].ReplacedAt.IsZero())
+		}
+		changed, err := repositories.Users.ChangePhoneNumber(ctx, change(user))
+		requireNoError(t, err)
+		numbers, err = repositories.Users.ListPhoneNumbers(ctx, user.Id)
+		requireNoError(t, err)
+		if assert.Len(t, numbers, 2) {
+			assert.Equal(t, user.PhoneNumber, numbers[0].PhoneNumber)
+			assert.WithinDuration(t, user.CreatedAt, numbers[0].AcquiredAt, time.Second)
+			assert.WithinDuration(t, changed.SessionsRevokedAt, numbers[0].ReplacedAt, time.Second)
+			assert.Equal(t, "5550000001", numbers[1].PhoneNumber)
+			assert.WithinDuration(t, changed.SessionsRevokedAt, numbers[1].AcquiredAt, time.Second)
+			assert.True(t, numbers[1].ReplacedAt.IsZero())
+		}
+	})
+
+	t.Run("ChangePhoneNumber rejects stale versions", func(t *testing.T) {
+		users := factory(t).Users
+		user := withPendingNumber(t, users, "1", "5550000001")
+		stale := change(user)
+		stale.ExpectedVersion--
+		_, err := users.ChangePhoneNumber(ctx, stale)
+		assert.ErrorIs(t, err, models.ErrStaleVersion)
+		found, err := users.GetUser(ctx, user.Id)
+		requireNoError(t, err)
+		assert.Equal(t, user.PhoneNumber, found.PhoneNumber)
+	})
+
+	t.Run("ChangePhoneNumber fails without a pending number", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		_, err = users.ChangePhoneNumber(ctx, change(saved))
+		assert.EqualError(t, err, fmt.Sprintf("user with id %d has no pending phone number", saved.Id))
+	})
+
+	t.Run("ChangePhoneNumber fails when the number was registered meanwhile", func(t *testing.T) {
+		repositories := factory(t)
+		registered := newUser("2")
+		registered.CountryCode = 1
+		other, err := repositories.Users.SaveUser(ctx, registered)
+		requireNoError(t, err)
+		user := withPendingNumber(t, repositories.Users, "1", other.PhoneNumber)
+		_, err = repositories.Users.ChangePhoneNumber(ctx, change(user))
+		assertAlreadyExists(t, err, models.FIELD_PHONE_NUMBER)
+		events, err := repositories.Events.ListRecentEvents(ctx, user.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		assert.Empty(t, events)
+	})
+
+	t.Run("ChangePhoneNumber fails for unknown ids", func(t *testing.T) {
+		users := factory(t).Users
+		_, err := users.ChangePhoneNumber(ctx, repository.PhoneNumberChange{UserId: 4242, ExpectedVersion: 1})
+		assert.EqualError(t, err, "user with id 4242 not found")
+	})
+}
+
+// RunUserDeletionTests checks soft deletion, restoring and purging of users
+func RunUserDeletionTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("SetUserStatus deletes and restores users", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		assert.Equal(t, models.USER_STATUS_ACTIVE, saved.Status)
+		deleted, err := users.SetUserStatus(ctx, saved.Id, models.USER_STATUS_DELETED, saved.Version)
+		requireNoError(t, err)
+		assert.True(t, deleted.IsDeleted())
+		assert.False(t, deleted.DeletedAt.IsZero())
+		assert.Equal(t, saved.Version+1, deleted.Version)
+		found, err := users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.Equal(t, deleted, found)
+
+		restored, err := users.SetUserStatus(ctx, saved.Id, models.USER_STATUS_ACTIVE, deleted.Version)
+		requireNoError(t, err)
+		assert.False(t, restored.IsDeleted())
+		assert.True(t, restored.DeletedAt.IsZero())
+	})
+
+	t.Run("SetUserStatus rejects stale versions", func(t *testing.T) {
+		users := factory(t).Users
+		saved, err := users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		_, err = users.SetUserStatus(ctx, saved.Id, models.USER_STATUS_DELETED, saved.Version+1)
+		assert.ErrorIs(t, err, models.ErrStaleVersion)
+		_, err = users.SetUserStatus(ctx, 4242, models.USER_STATUS_DELETED, 1)
+		assert.EqualError(t, err, "user with id 4242 not found")
+	})
+
+	t.Run("PurgeDeletedUsers purges users past the grace period and anonymizes their events", func(t *testing.T) {
+		repositories := factory(t)
+		expired, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		_, err = repositories.Users.SetUserStatus(ctx, expired.Id, models.USER_STATUS_DELETED, expired.Version)
+		requireNoError(t, err)
+		active, err := repositories.Users.SaveUser(ctx, newUser("2"))
+		requireNoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+		recent, err := repositories.Users.SaveUser(ctx, newUser("3"))
+		requireNoError(t, err)
+		_, err = repositories.Users.SetUserStatus(ctx, recent.Id, models.USER_STATUS_DELETED, recent.Version)
+		requireNoError(t, err)
+
+		purged, err := repositories.Users.PurgeDeletedUsers(ctx, 50*time.Millisecond)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		_, err = repositories.Users.GetUser(ctx, expired.Id)
+		assert.Error(t, err)
+		for _, id := range []int32{active.Id, recent.Id} {
+			_, err = repositories.Users.GetUser(ctx, id)
+			assert.NoError(t, err)
+		}
+		events, err := repositories.Events.ListRecentEvents(ctx, expired.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		assert.Empty(t, events)
+		anonymized, err := repositories.Events.ListRecentEvents(ctx, fmt.Sprintf("deleted-%d", expired.Id), time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, anonymized, 1)
+	})
+
+	// changeNumber moves the user to newNumber and returns it at its new version
+	changeNumber := func(t *testing.T, users repository.IUserRepository, user *models.User, newNumber string) *models.User {
+		changed := *user
+		changed.PendingCountryCode, changed.PendingPhoneNumber = 1, newNumber
+		pending, err := users.UpdateUser(ctx, &changed, user.Version)
+		requireNoError(t, err)
+		current, err := users.ChangePhoneNumber(ctx, repository.PhoneNumberChange{
+			UserId:          user.Id,
+			ExpectedVersion: pending.Version,
+			OldNumberEvents: []string{"PHONE_CHANGED"},
+			NewNumberEvents: []string{"PHONE_CHANGED"},
+		})
+		requireNoError(t, err)
+		return current
+	}
+
+	t.Run("PurgeDeletedUsers removes what is stored under the old and the current phone number", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		oldNumber := user.PhoneNumber
+		requireNoError(t, repositories.Deliveries.CreateDelivery(ctx, models.OtpDelivery{RequestId: "old", CountryCode: 91, PhoneNumber: oldNumber, Channel: "SMS"}))
+		requireNoError(t, repositories.Codes.SaveCode(ctx, oldNumber, []byte("hash"), time.Minute))
+		current := changeNumber(t, repositories.Users, user, "5550000001")
+		requireNoError(t, repositories.Deliveries.CreateDelivery(ctx, models.OtpDelivery{RequestId: "new", CountryCode: 1, PhoneNumber: "5550000001", Channel: "SMS"}))
+		_, err = repositories.Users.SetUserStatus(ctx, user.Id, models.USER_STATUS_DELETED, current.Version)
+		requireNoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		purged, err := repositories.Users.PurgeDeletedUsers(ctx, 0)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		for _, number := range []string{oldNumber, "5550000001"} {
+			events, err := repositories.Events.ListRecentEvents(ctx, number, time.Minute)
+			requireNoError(t, err)
+			assert.Empty(t, events, number)
+		}
+		_, err = repositories.Deliveries.GetDelivery(ctx, "new", 1, "5550000001")
+		assert.Error(t, err)
+		consumed, err := repositories.Codes.ConsumeCode(ctx, oldNumber, [][]byte{[]byte("hash")}, 5)
+		requireNoError(t, err)
+		assert.False(t, consumed)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		assert.Empty(t, messages)
+		anonymized, err := repositories.Events.ListRecentEvents(ctx, fmt.Sprintf("deleted-%d", user.Id), time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, anonymized, 3)
+	})
+
+	t.Run("PurgeDeletedUsers leaves an old phone number to its new owner", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		current := changeNumber(t, repositories.Users, user, "5550000001")
+		owner := newUser("2")
+		owner.PhoneNumber = user.PhoneNumber
+		_, err = repositories.Users.SaveUser(ctx, owner)
+		requireNoError(t, err)
+		repositories.Events.InsertEvent(ctx, "LOGIN", user.PhoneNumber)
+		_, err = repositories.Users.SetUserStatus(ctx, user.Id, models.USER_STATUS_DELETED, current.Version)
+		requireNoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		_, err = repositories.Users.PurgeDeletedUsers(ctx, 0)
+		requireNoError(t, err)
+		events, err := repositories.Events.ListRecentEvents(ctx, user.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "LOGIN", events[0].Event)
+		}
+	})
+}
+
+// RunRegistrationTests checks that RegisterUser writes the user and its outbox message atomically
+func RunRegistrationTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("RegisterUser stores the user and the outbox message", func(t *testing.T) {
+		repositories := factory(t)
+		saved, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		assert.NotZero(t, saved.Id)
+		found, err := repositories.Users.GetUser(ctx, saved.Id)
+		requireNoError(t, err)
+		assert.Equal(t, saved, found)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, "otp.generate", messages[0].Topic)
+			assert.Equal(t, []byte("payload-1"), messages[0].Payload)
+		}
+	})
+
+	t.Run("RegisterUser writes nothing when the user conflicts", func(t *testing.T) {
+		repositories := factory(t)
+		_, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		duplicate := newRegistration("2")
+		duplicate.User.PhoneNumber = newUser("1").PhoneNumber
+		_, err = repositories.Users.RegisterUser(ctx, duplicate)
+		assertAlreadyExists(t, err, models.FIELD_PHONE_NUMBER)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, messages, 1)
+	})
+
+	t.Run("RegisterUser takes over expired unverified users", func(t *testing.T) {
+		repositories := factory(t)
+		abandoned, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		retry := newRegistration("2")
+		retry.User.PhoneNumber = newUser("1").PhoneNumber
+		retry.ReclaimUnverifiedAfter = time.Millisecond
+		saved, err := repositories.Users.RegisterUser(ctx, retry)
+		requireNoError(t, err)
+		assert.NotEqual(t, abandoned.Id, saved.Id)
+		_, err = repositories.Users.GetUser(ctx, abandoned.Id)
+		assert.Error(t, err)
+		found, err := repositories.Users.GetUserByPhoneNumberAndCountry(ctx, 91, saved.PhoneNumber)
+		requireNoError(t, err)
+		assert.Equal(t, saved.UserName, found.UserName)
+		// the abandoned signup is purged, only the retry is left under the phone number
+		events, err := repositories.Events.ListRecentEvents(ctx, saved.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, events, 1)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, []byte("payload-2"), messages[0].Payload)
+		}
+	})
+
+	t.Run("RegisterUser keeps verified and recent unverified users", func(t *testing.T) {
+		repositories := factory(t)
+		verified, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		requireNoError(t, repositories.Users.MarkVerified(ctx, verified.Id))
+		_, err = repositories.Users.RegisterUser(ctx, newRegistration("2"))
+		requireNoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+
+		sameEmail := newRegistration("3")
+		sameEmail.User.Email, sameEmail.User.CanonicalEmail = newUser("1").Email, newUser("1").CanonicalEmail
+		sameEmail.ReclaimUnverifiedAfter = time.Millisecond
+		_, err = repositories.Users.RegisterUser(ctx, sameEmail)
+		assertAlreadyExists(t, err, models.FIELD_EMAIL)
+
+		sameUserName := newRegistration("4")
+		sameUserName.User.UserName = newUser("2").UserName
+		sameUserName.ReclaimUnverifiedAfter = time.Hour
+		_, err = repositories.Users.RegisterUser(ctx, sameUserName)
+		assertAlreadyExists(t, err, models.FIELD_USER_NAME)
+	})
+
+	t.Run("DeleteExpiredUnverified deletes only expired unverified users", func(t *testing.T) {
+		repositories := factory(t)
+		verified, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		requireNoError(t, repositories.Users.MarkVerified(ctx, verified.Id))
+		expired, err := repositories.Users.RegisterUser(ctx, newRegistration("2"))
+		requireNoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+		recent, err := repositories.Users.RegisterUser(ctx, newRegistration("3"))
+		requireNoError(t, err)
+		deleted, err := repositories.Users.DeleteExpiredUnverified(ctx, 50*time.Millisecond)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = repositories.Users.GetUser(ctx, expired.Id)
+		assert.Error(t, err)
+		events, err := repositories.Events.ListRecentEvents(ctx, expired.PhoneNumber, time.Minute)
+		requireNoError(t, err)
+		assert.Empty(t, events)
+		for _, id := range []int32{verified.Id, recent.Id} {
+			_, err = repositories.Users.GetUser(ctx, id)
+			assert.NoError(t, err)
+		}
+	})
+}
+
+// RunOutboxRepositoryTests checks the IOutboxRepository contract
+func RunOutboxRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("claimed messages are hidden until the lease expires", func(t *testing.T) {
+		repositories := factory(t)
+		for _, suffix := range []string{"1", "2", "3"} {
+			_, err := repositories.Users.RegisterUser(ctx, newRegistration(suffix))
+			requireNoError(t, err)
+		}
+		first, err := repositories.Outbox.ClaimPending(ctx, 2, time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, first, 2)
+		second, err := repositories.Outbox.ClaimPending(ctx, 2, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, second, 1) {
+			assert.Equal(t, []byte("payload-3"), second[0].Payload)
+		}
+	})
+
+	t.Run("messages keep the context of the rpc that wrote them", func(t *testing.T) {
+		repositories := factory(t)
+		registration := newRegistration("1")
+		registration.Outbox.CorrelationId = "rpc-1"
+		registration.Outbox.TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+		registration.Outbox.TraceState = "vendor=1"
+		_, err := repositories.Users.RegisterUser(ctx, registration)
+		requireNoError(t, err)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, "rpc-1", messages[0].CorrelationId)
+			assert.Equal(t, registration.Outbox.TraceParent, messages[0].TraceParent)
+			assert.Equal(t, "vendor=1", messages[0].TraceState)
+		}
+	})
+
+	t.Run("delivered messages are not claimed again", func(t *testing.T) {
+		repositories := factory(t)
+		_, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, 0)
+		requireNoError(t, err)
+		requireNoError(t, repositories.Outbox.MarkDelivered(ctx, messages[0].Id))
+		messages, err = repositories.Outbox.ClaimPending(ctx, 10, 0)
+		requireNoError(t, err)
+		assert.Empty(t, messages)
+	})
+
+	t.Run("failed messages are retried with the recorded error", func(t *testing.T) {
+		repositories := factory(t)
+		_, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		requireNoError(t, repositories.Outbox.MarkFailed(ctx, messages[0].Id, "broker unavailable", 0))
+		messages, err = repositories.Outbox.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, int32(1), messages[0].Attempts)
+			assert.Equal(t, "broker unavailable", messages[0].LastError)
+		}
+	})
+
+	t.Run("dead lettered messages are not claimed again", func(t *testing.T) {
+		repositories := factory(t)
+		_, err := repositories.Users.RegisterUser(ctx, newRegistration("1"))
+		requireNoError(t, err)
+		messages, err := repositories.Outbox.ClaimPending(ctx, 10, 0)
+		requireNoError(t, err)
+		requireNoError(t, repositories.Outbox.DeadLetter(ctx, messages[0].Id, "expired"))
+		messages, err = repositories.Outbox.ClaimPending(ctx, 10, 0)
+		requireNoError(t, err)
+		assert.Empty(t, messages)
+	})
+}
+
+// RunIdempotencyRepositoryTests checks the IIdempotencyRepository contract
+func RunIdempotencyRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("the first request reserves the key and later ones see it", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		record, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		assert.Nil(t, record)
+		record, err = keys.Reserve(ctx, "signup", "key-1", []byte("other"), time.Minute)
+		requireNoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, []byte("hash"), record.RequestHash)
+			assert.Nil(t, record.Response)
+		}
+	})
+
+	t.Run("completed keys return the stored response", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		_, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		requireNoError(t, keys.Complete(ctx, "signup", "key-1", []byte("response"), time.Minute))
+		record, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, []byte("response"), record.Response)
+		}
+	})
+
+	t.Run("completing a key extends its lease", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		_, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), -time.Second)
+		requireNoError(t, err)
+		requireNoError(t, keys.Complete(ctx, "signup", "key-1", []byte("response"), time.Minute))
+		record, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.Equal(t, []byte("response"), record.Response)
+		}
+	})
+
+	t.Run("keys are scoped to the operation", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		_, err := keys.Reserve(ctx, "signup", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		record, err := keys.Reserve(ctx, "login", "key-1", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("released and expired keys can be reserved again", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		_, err := keys.Reserve(ctx, "signup", "released", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		requireNoError(t, keys.Release(ctx, "signup", "released"))
+		record, err := keys.Reserve(ctx, "signup", "released", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		assert.Nil(t, record)
+
+		_, err = keys.Reserve(ctx, "signup", "expired", []byte("hash"), -time.Second)
+		requireNoError(t, err)
+		record, err = keys.Reserve(ctx, "signup", "expired", []byte("new"), time.Minute)
+		requireNoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("DeleteExpired removes only expired keys", func(t *testing.T) {
+		keys := factory(t).Idempotency
+		_, err := keys.Reserve(ctx, "signup", "expired", []byte("hash"), -time.Second)
+		requireNoError(t, err)
+		_, err = keys.Reserve(ctx, "signup", "live", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		deleted, err := keys.DeleteExpired(ctx)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		record, err := keys.Reserve(ctx, "signup", "live", []byte("hash"), time.Minute)
+		requireNoError(t, err)
+		assert.NotNil(t, record)
+	})
+}
+
+// RunEventRepositoryTests checks the IEventRepository contract
+func RunEventRepositoryTests(t *testing.T, factory Factory) {
+	t.Run("InsertEvent never fails the caller", func(t *testing.T) {
+		events := factory(t).Events
+		events.InsertEvent(context.Background(), "LOGIN_REQUEST", "9876543210")
+		cancelled, cancel := context.WithCancel(context.Background())
+		cancel()
+		events.InsertEvent(cancelled, "LOGIN_REQUEST", "9876543210")
+	})
+
+	t.Run("ListRecentEvents returns the events of the phone number oldest first", func(t *testing.T) {
+		events := factory(t).Events
+		ctx := context.Background()
+		events.InsertEvent(ctx, "LOGIN_REQUEST", "9876543210")
+		events.InsertEvent(ctx, "LOGIN_REQUEST", "1111111111")
+		events.InsertEvent(ctx, "OTP_RESENT", "9876543210")
+		recent, err := events.ListRecentEvents(ctx, "9876543210", time.Minute)
+		requireNoError(t, err)
+		if assert.Len(t, recent, 2) {
+			assert.Equal(t, "LOGIN_REQUEST", recent[0].Event)
+			assert.Equal(t, "OTP_RESENT", recent[1].Event)
+			assert.Equal(t, "9876543210", recent[1].PhoneNumber)
+			assert.False(t, recent[1].CreatedAt.IsZero())
+		}
+	})
+
+	t.Run("ListEvents returns every event of the phone number oldest first", func(t *testing.T) {
+		events := factory(t).Events
+		ctx := context.Background()
+		events.InsertEvent(ctx, "LOGIN_REQUEST", "9876543210")
+		events.InsertEvent(ctx, "LOGIN_REQUEST", "1111111111")
+		events.InsertEvent(ctx, "LOGIN", "9876543210")
+		all, err := events.ListEvents(ctx, "9876543210", time.Time{}, time.Time{})
+		requireNoError(t, err)
+		if assert.Len(t, all, 2) {
+			assert.Equal(t, "LOGIN_REQUEST", all[0].Event)
+			assert.Equal(t, "LOGIN", all[1].Event)
+		}
+		none, err := events.ListEvents(ctx, "2222222222", time.Time{}, time.Time{})
+		requireNoError(t, err)
+		assert.Empty(t, none)
+	})
+
+	t.Run("ListEvents returns the events within since and until", func(t *testing.T) {
+		events := factory(t).Events
+		ctx := context.Background()
+		events.InsertEvent(ctx, "LOGIN_REQUEST", "9876543210")
+		all, err := events.ListEvents(ctx, "9876543210", time.Time{}, time.Time{})
+		requireNoError(t, err)
+		recordedAt := all[0].CreatedAt
+		within, err := events.ListEvents(ctx, "9876543210", recordedAt.Add(-time.Minute), recordedAt.Add(time.Minute))
+		requireNoError(t, err)
+		assert.Len(t, within, 1)
+		later, err := events.ListEvents(ctx, "9876543210", recordedAt.Add(time.Minute), time.Time{})
+		requireNoError(t, err)
+		assert.Empty(t, later)
+		earlier, err := events.ListEvents(ctx, "9876543210", time.Time{}, recordedAt.Add(-time.Minute))
+		requireNoError(t, err)
+		assert.Empty(t, earlier)
+	})
+}
+
+// RunDataExportRepositoryTests checks the IDataExportRepository contract
+func RunDataExportRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("exports are pending until completed", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		created, err := repositories.Exports.Create(ctx, user.Id, []byte("hash"), time.Hour)
+		requireNoError(t, err)
+		assert.Equal(t, models.DATA_EXPORT_PENDING, created.Status)
+		assert.Equal(t, user.Id, created.UserId)
+		assert.Equal(t, []byte("hash"), created.TokenHash)
+		assert.True(t, created.ExpiresAt.After(created.CreatedAt))
+
+		requireNoError(t, repositories.Exports.Complete(ctx, created.Id, []byte(`{"profile":{}}`)))
+		found, err := repositories.Exports.GetExport(ctx, created.Id)
+		requireNoError(t, err)
+		assert.Equal(t, models.DATA_EXPORT_READY, found.Status)
+		assert.Equal(t, []byte(`{"profile":{}}`), found.Archive)
+		assert.False(t, found.CompletedAt.IsZero())
+	})
+
+	t.Run("claimed exports are hidden until the lease expires", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err = repositories.Exports.Create(ctx, user.Id, []byte("hash"), time.Hour)
+			requireNoError(t, err)
+		}
+		claimed, err := repositories.Exports.ClaimPending(ctx, 2, 100*time.Millisecond)
+		requireNoError(t, err)
+		assert.Len(t, claimed, 2)
+		rest, err := repositories.Exports.ClaimPending(ctx, 10, 100*time.Millisecond)
+		requireNoError(t, err)
+		assert.Len(t, rest, 1)
+		requireNoError(t, repositories.Exports.Fail(ctx, rest[0].Id, "user not found"))
+		time.Sleep(200 * time.Millisecond)
+		reclaimed, err := repositories.Exports.ClaimPending(ctx, 10, time.Minute)
+		requireNoError(t, err)
+		assert.Len(t, reclaimed, 2)
+		failed, err := repositories.Exports.GetExport(ctx, rest[0].Id)
+		requireNoError(t, err)
+		assert.Equal(t, models.DATA_EXPORT_FAILED, failed.Status)
+		assert.Equal(t, "user not found", failed.LastError)
+	})
+
+	t.Run("DeleteExpired removes only expired exports", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		expired, err := repositories.Exports.Create(ctx, user.Id, []byte("hash"), 50*time.Millisecond)
+		requireNoError(t, err)
+		live, err := repositories.Exports.Create(ctx, user.Id, []byte("hash"), time.Hour)
+		requireNoError(t, err)
+		time.Sleep(100 * time.Millisecond)
+		deleted, err := repositories.Exports.DeleteExpired(ctx)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = repositories.Exports.GetExport(ctx, expired.Id)
+		assert.EqualError(t, err, fmt.Sprintf("data export with id %d not found", expired.Id))
+		_, err = repositories.Exports.GetExport(ctx, live.Id)
+		assert.NoError(t, err)
+	})
+
+	t.Run("purging a user removes its exports", func(t *testing.T) {
+		repositories := factory(t)
+		user, err := repositories.Users.SaveUser(ctx, newUser("1"))
+		requireNoError(t, err)
+		export, err := repositories.Exports.Create(ctx, user.Id, []byte("hash"), time.Hour)
+		requireNoError(t, err)
+		_, err = repositories.Users.SetUserStatus(ctx, user.Id, models.USER_STATUS_DELETED, user.Version)
+		requireNoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		_, err = repositories.Users.PurgeDeletedUsers(ctx, 0)
+		requireNoError(t, err)
+		_, err = repositories.Exports.GetExport(ctx, export.Id)
+		assert.Error(t, err)
+	})
+}
+
+// RunOtpDeliveryRepositoryTests checks the IOtpDeliveryRepository contract
+func RunOtpDeliveryRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+	delivery := func(requestId string) models.OtpDelivery {
+		return models.OtpDelivery{RequestId: requestId, CountryCode: 91, PhoneNumber: "9876543210", Channel: "SMS"}
+	}
+
+	t.Run("deliveries are pending until a receipt arrives", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		found, err := deliveries.GetDelivery(ctx, "request-1", 91, "9876543210")
+		requireNoError(t, err)
+		assert.Equal(t, "request-1", found.RequestId)
+		assert.Equal(t, int32(91), found.CountryCode)
+		assert.Equal(t, "SMS", found.Channel)
+		assert.Equal(t, models.OTP_DELIVERY_PENDING, found.Status)
+	})
+
+	t.Run("receipts only move a delivery forward", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		updated, changed, err := deliveries.UpdateDeliveryStatus(ctx, "request-1", models.OTP_DELIVERY_SENT, "", time.Now())
+		requireNoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, models.OTP_DELIVERY_SENT, updated.Status)
+
+		late, changed, err := deliveries.UpdateDeliveryStatus(ctx, "request-1", models.OTP_DELIVERY_QUEUED, "", time.Now())
+		requireNoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, models.OTP_DELIVERY_SENT, late.Status)
+		_, changed, err = deliveries.UpdateDeliveryStatus(ctx, "request-1", models.OTP_DELIVERY_SENT, "", time.Now())
+		requireNoError(t, err)
+		assert.False(t, changed)
+
+		failed, changed, err := deliveries.UpdateDeliveryStatus(ctx, "request-1", models.OTP_DELIVERY_FAILED, "carrier rejected", time.Now())
+		requireNoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, "carrier rejected", failed.Reason)
+		final, changed, err := deliveries.UpdateDeliveryStatus(ctx, "request-1", models.OTP_DELIVERY_DELIVERED, "", time.Now())
+		requireNoError(t, err)
+		assert.False(t, changed)
+		assert.Equal(t, models.OTP_DELIVERY_FAILED, final.Status)
+	})
+
+	t.Run("receipts for unknown requests are ignored", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		updated, changed, err := deliveries.UpdateDeliveryStatus(ctx, "unknown", models.OTP_DELIVERY_SENT, "", time.Now())
+		requireNoError(t, err)
+		assert.Nil(t, updated)
+		assert.False(t, changed)
+	})
+
+	t.Run("GetDelivery returns the delivery of the request", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		_, err := deliveries.GetDelivery(ctx, "request-1", 91, "9876543210")
+		assert.EqualError(t, err, "no otp was sent to phone number 9876543210")
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-2")))
+		found, err := deliveries.GetDelivery(ctx, "request-1", 91, "9876543210")
+		requireNoError(t, err)
+		assert.Equal(t, "request-1", found.RequestId)
+	})
+
+	t.Run("GetDelivery only returns the delivery with the phone number and country code", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		_, err := deliveries.GetDelivery(ctx, "request-1", 44, "9876543210")
+		assert.EqualError(t, err, "no otp was sent to phone number 9876543210")
+		_, err = deliveries.GetDelivery(ctx, "request-1", 91, "1234567890")
+		assert.EqualError(t, err, "no otp was sent to phone number 1234567890")
+	})
+
+	t.Run("DeleteOldDeliveries removes only deliveries past the retention", func(t *testing.T) {
+		deliveries := factory(t).Deliveries
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-1")))
+		time.Sleep(100 * time.Millisecond)
+		requireNoError(t, deliveries.CreateDelivery(ctx, delivery("request-2")))
+		deleted, err := deliveries.DeleteOldDeliveries(ctx, 50*time.Millisecond)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		_, err = deliveries.GetDelivery(ctx, "request-1", 91, "9876543210")
+		assert.Error(t, err)
+		found, err := deliveries.GetDelivery(ctx, "request-2", 91, "9876543210")
+		requireNoError(t, err)
+		assert.Equal(t, "request-2", found.RequestId)
+	})
+}
+
+// RunOtpCodeRepositoryTests checks the IOtpCodeRepository contract
+func RunOtpCodeRepositoryTests(t *testing.T, factory Factory) {
+	ctx := context.Background()
+
+	t.Run("codes are consumed once", func(t *testing.T) {
+		codes := factory(t).Codes
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-1"), time.Minute))
+		consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-1")}, 5)
+		requireNoError(t, err)
+		assert.True(t, consumed)
+		consumed, err = codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-1")}, 5)
+		requireNoError(t, err)
+		assert.False(t, consumed)
+	})
+
+	t.Run("saving a code replaces the previous one", func(t *testing.T) {
+		codes := factory(t).Codes
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-1"), time.Minute))
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-2"), time.Minute))
+		consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-1")}, 5)
+		requireNoError(t, err)
+		assert.False(t, consumed)
+		consumed, err = codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-2")}, 5)
+		requireNoError(t, err)
+		assert.True(t, consumed)
+	})
+
+	t.Run("codes are rejected after too many attempts", func(t *testing.T) {
+		codes := factory(t).Codes
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-1"), time.Minute))
+		for attempt := 0; attempt < 2; attempt++ {
+			consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("wrong")}, 2)
+			requireNoError(t, err)
+			assert.False(t, consumed)
+		}
+		consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-1")}, 2)
+		requireNoError(t, err)
+		assert.False(t, consumed)
+	})
+
+	t.Run("any of the hashes can match the code", func(t *testing.T) {
+		codes := factory(t).Codes
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-1"), time.Minute))
+		consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-2"), []byte("hash-1")}, 5)
+		requireNoError(t, err)
+		assert.True(t, consumed)
+	})
+
+	t.Run("expired codes are rejected and deleted", func(t *testing.T) {
+		codes := factory(t).Codes
+		requireNoError(t, codes.SaveCode(ctx, "9876543210", []byte("hash-1"), -time.Second))
+		requireNoError(t, codes.SaveCode(ctx, "john@example.com", []byte("hash-2"), time.Minute))
+		consumed, err := codes.ConsumeCode(ctx, "9876543210", [][]byte{[]byte("hash-1")}, 5)
+		requireNoError(t, err)
+		assert.False(t, consumed)
+		deleted, err := codes.DeleteExpiredCodes(ctx)
+		requireNoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		consumed, err = codes.ConsumeCode(ctx, "john@example.com", [][]byte{[]byte("hash-2")}, 5)
+		requireNoError(t, err)
+		assert.True(t, consumed)
+	})
+}
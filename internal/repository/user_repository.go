@@ -2,27 +2,156 @@ package repository
 
 import (
 	"auth-service/internal/models"
+	"context"
 	"database/sql"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"time"
 )
 
 const (
 	INSERT_QUERY = `
-		INSERT INTO users (name,user_name, email, is_verified, country_code, phone_number)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		INSERT INTO users (name,user_name, email, canonical_email, is_verified, country_code, phone_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + USER_COLUMNS
+	GET_QUERY          = "SELECT " + USER_COLUMNS + " FROM users WHERE id = $1"
+	GET_USER_BY_PH     = "SELECT " + USER_COLUMNS + " FROM users WHERE country_code = $1 AND phone_number = $2"
+	UPDATE_VERIFIED    = "UPDATE users SET is_verified = true WHERE id = $1"
+	USER_NAME_TAKEN    = "SELECT EXISTS (SELECT 1 FROM users WHERE lower(user_name) = lower($1))"
+	PHONE_NUMBER_TAKEN = "SELECT EXISTS (SELECT 1 FROM users WHERE country_code = $1 AND phone_number = $2)"
+	// RECLAIM_UNVERIFIED selects the abandoned signups holding unique fields of a new one, they are purged to free them
+	RECLAIM_UNVERIFIED = `
+		SELECT id FROM users
+		WHERE is_verified = false AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $5)
+		AND (lower(user_name) = lower($1) OR email = $2 OR canonical_email = $3 OR (country_code = $6 AND phone_number = $4))
+		FOR UPDATE
 		`
-	GET_QUERY       = "SELECT * FROM users WHERE id = $1"
-	GET_USER_BY_PH  = "SELECT id, name, email, is_verified, country_code, phone_number FROM users WHERE country_code = $1 AND phone_number = $2"
-	UPDATE_VERIFIED = "UPDATE users SET is_verified = true WHERE id = $1"
+	// UPDATE_USER applies only when the stored version is the one the update was based on
+	UPDATE_USER = `
+		UPDATE users
+		SET name = $2, user_name = $3, email = $4, canonical_email = $5,
+		pending_email = NULLIF($6, ''), pending_canonical_email = NULLIF($7, ''),
+		pending_country_code = NULLIF($8, 0), pending_phone_number = NULLIF($9, ''), email_confirmed_at = $11,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND version = $10
+		RETURNING ` + USER_COLUMNS
+	GET_USER_FOR_UPDATE = GET_QUERY + " FOR UPDATE"
+	// RECORD_REPLACED_PHONE_NUMBER keeps the number a phone number change replaces, so purging the user covers it
+	RECORD_REPLACED_PHONE_NUMBER = `
+		INSERT INTO user_phone_numbers (user_id, phone_number, acquired_at)
+		SELECT id, phone_number, COALESCE(sessions_revoked_at, created_at) FROM users WHERE id = $1 AND phone_number IS NOT NULL
+		`
+	// CHANGE_PHONE_NUMBER makes the pending phone number the login number and revokes the sessions of the old one
+	CHANGE_PHONE_NUMBER = `
+		UPDATE users
+		SET country_code = pending_country_code, phone_number = pending_phone_number,
+		pending_country_code = NULL, pending_phone_number = NULL, sessions_revoked_at = CURRENT_TIMESTAMP,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + USER_COLUMNS
+	// SET_USER_STATUS stamps deleted_at when deleting and clears it when restoring
+	SET_USER_STATUS = `
+		UPDATE users
+		SET status = $2::VARCHAR, deleted_at = CASE WHEN $2::VARCHAR = 'DELETED' THEN CURRENT_TIMESTAMP END,
+		version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND version = $3
+		RETURNING ` + USER_COLUMNS
+	PURGE_DELETED_USERS = `
+		SELECT id FROM users WHERE status = 'DELETED' AND deleted_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		FOR UPDATE
+		`
+	DELETE_EXPIRED_UNVERIFIED = `
+		SELECT id FROM users WHERE is_verified = false AND created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		FOR UPDATE
+		`
+	// PURGED_PHONE_NUMBERS are the current and the replaced phone numbers of the users $1 with the time they held
+	// them, a number held by another user before or after is left alone
+	PURGED_PHONE_NUMBERS = `
+		WITH numbers AS (
+			SELECT id AS user_id, phone_number,
+			COALESCE(sessions_revoked_at, created_at, '-infinity'::TIMESTAMP) AS since, 'infinity'::TIMESTAMP AS until
+			FROM users WHERE id = ANY($1::INT[]) AND phone_number IS NOT NULL
+			UNION ALL
+			SELECT user_id, phone_number, COALESCE(acquired_at, '-infinity'::TIMESTAMP), replaced_at
+			FROM user_phone_numbers WHERE user_id = ANY($1::INT[])
+		)
+		`
+	// ANONYMIZE_PURGED_EVENTS keeps the events of purged users under "deleted-<user id>" instead of their phone numbers
+	ANONYMIZE_PURGED_EVENTS = PURGED_PHONE_NUMBERS + `
+		UPDATE user_events SET phone_number = 'deleted-' || numbers.user_id FROM numbers
+		WHERE user_events.phone_number = numbers.phone_number AND user_events.created_at BETWEEN numbers.since AND numbers.until
+		`
+	DELETE_PURGED_DELIVERIES = PURGED_PHONE_NUMBERS + `
+		DELETE FROM otp_deliveries USING numbers
+		WHERE otp_deliveries.phone_number = numbers.phone_number AND otp_deliveries.created_at BETWEEN numbers.since AND numbers.until
+		`
+	DELETE_PURGED_OTP_CODES = PURGED_PHONE_NUMBERS + `
+		DELETE FROM otp_codes USING numbers
+		WHERE otp_codes.subject = numbers.phone_number AND otp_codes.created_at BETWEEN numbers.since AND numbers.until
+		`
+	DELETE_PURGED_OUTBOX_MESSAGES = PURGED_PHONE_NUMBERS + `
+		DELETE FROM outbox_messages USING numbers
+		WHERE outbox_messages.phone_number = numbers.phone_number AND outbox_messages.created_at BETWEEN numbers.since AND numbers.until
+		`
+	// LIST_PHONE_NUMBERS lists the replaced phone numbers of the user in the order they were replaced, then the current one
+	LIST_PHONE_NUMBERS = `
+		SELECT phone_number, acquired_at, replaced_at FROM user_phone_numbers WHERE user_id = $1
+		UNION ALL
+		SELECT phone_number, COALESCE(sessions_revoked_at, created_at), NULL FROM users WHERE id = $1 AND phone_number IS NOT NULL
+		ORDER BY replaced_at NULLS LAST
+		`
+	// DELETE_PURGED_USERS cascades to their exports and replaced phone numbers
+	DELETE_PURGED_USERS = "DELETE FROM users WHERE id = ANY($1::INT[])"
 )
 
 type IUserRepository interface {
-	SaveUser(user *models.User) (*models.User, error)
-	GetUser(userId int32) (*models.User, error)
-	GetUserByPhoneNumberAndCountry(countryCode int32, phoneNumber string) (*models.User, error)
-	MarkVerified(id int32) error
+	SaveUser(ctx context.Context, user *models.User) (*models.User, error)
+	GetUser(ctx context.Context, userId int32) (*models.User, error)
+	GetUserByPhoneNumberAndCountry(ctx context.Context, countryCode int32, phoneNumber string) (*models.User, error)
+	MarkVerified(ctx context.Context, id int32) error
+	IsUserNameTaken(ctx context.Context, userName string) (bool, error)
+	// IsPhoneNumberTaken reports if a user holds the phone number under the country code, like the unique constraint
+	IsPhoneNumberTaken(ctx context.Context, countryCode int32, phoneNumber string) (bool, error)
+	// ListPhoneNumbers returns the phone numbers the user held with the time it held them, the replaced ones oldest
+	// first and the current one last
+	ListPhoneNumbers(ctx context.Context, userId int32) ([]models.HeldPhoneNumber, error)
+	// RegisterUser stores the user, its signup event and outbox message in one transaction
+	RegisterUser(ctx context.Context, registration Registration) (*models.User, error)
+	// UpdateUser stores the profile fields of the user if it is still at expectedVersion and returns it with the
+	// incremented version, models.ErrStaleVersion is returned when it changed in between
+	UpdateUser(ctx context.Context, user *models.User, expectedVersion int64) (*models.User, error)
+	// ChangePhoneNumber replaces the phone number of the user with its pending one, stamps SessionsRevokedAt and
+	// records the events of both
+	// numbers in one transaction, it fails with models.ErrStaleVersion like UpdateUser
+	ChangePhoneNumber(ctx context.Context, change PhoneNumberChange) (*models.User, error)
+	// SetUserStatus changes the status of the user if it is still at expectedVersion, deleting stamps DeletedAt and
+	// restoring clears it. models.ErrStaleVersion is returned like UpdateUser
+	SetUserStatus(ctx context.Context, userId int32, status string, expectedVersion int64) (*models.User, error)
+	// PurgeDeletedUsers removes users deleted more than gracePeriod ago. Everything stored under the phone numbers
+	// they held is purged with them, their events are anonymized
+	PurgeDeletedUsers(ctx context.Context, gracePeriod time.Duration) (int64, error)
+	// DeleteExpiredUnverified purges users that did not verify their phone number within ttl of signing up like
+	// PurgeDeletedUsers
+	DeleteExpiredUnverified(ctx context.Context, ttl time.Duration) (int64, error)
+}
+
+// Registration is everything written when a user signs up, either all of it is stored or nothing
+type Registration struct {
+	User   *models.User
+	Event  string
+	Outbox models.OutboxMessage
+	// ReclaimUnverifiedAfter purges unverified users older than this holding the same user name, email or
+	// phone number instead of failing on them, zero keeps them
+	ReclaimUnverifiedAfter time.Duration
+}
+
+// PhoneNumberChange moves the pending phone number of a user into place
+type PhoneNumberChange struct {
+	UserId          int32
+	ExpectedVersion int64
+	// OldNumberEvents and NewNumberEvents are recorded for the replaced and the new phone number
+	OldNumberEvents []string
+	NewNumberEvents []string
 }
 
 func NewUserRepository(db *sql.DB) IUserRepository {
@@ -33,42 +162,248 @@ type psqlUserRepository struct {
 	db *sql.DB
 }
 
-func (p *psqlUserRepository) SaveUser(user *models.User) (*models.User, error) {
-	var id int32
-	err := p.db.QueryRow(INSERT_QUERY, user.Name, user.UserName, user.Email, user.Verified, user.CountryCode, user.PhoneNumber).Scan(&id)
+// SaveUser returns the stored row, so defaults like created_at are populated
+func (p *psqlUserRepository) SaveUser(ctx context.Context, user *models.User) (*models.User, error) {
+	row := p.db.QueryRowContext(ctx, INSERT_QUERY, user.Name, user.UserName, user.Email, user.CanonicalEmail, user.Verified, user.CountryCode, user.PhoneNumber)
+	saved, err := scanUser(row)
 	if err != nil {
-		return nil, err
+		return nil, translateUserWriteError(err)
 	}
-	user.Id = id
-	return user, nil
+	return saved, nil
 }
 
-func (p *psqlUserRepository) GetUser(userId int32) (*models.User, error) {
-	var user models.User
-	err := p.db.QueryRow(GET_QUERY, userId).Scan(&user.Id, &user.Name, &user.UserName, &user.Email, &user.Verified, &user.CountryCode, &user.PhoneNumber, &user.CreatedAt)
+func (p *psqlUserRepository) GetUser(ctx context.Context, userId int32) (*models.User, error) {
+	user, err := scanUser(p.db.QueryRowContext(ctx, GET_QUERY, userId))
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user with id %d not found", userId)
+		}
 		return nil, err
 	}
-	return &user, nil
+	return user, nil
 }
 
-func (p *psqlUserRepository) GetUserByPhoneNumberAndCountry(countryCode int32, phoneNumber string) (*models.User, error) {
-	var user models.User
-	err := p.db.QueryRow(GET_USER_BY_PH, countryCode, phoneNumber).Scan(&user.Id, &user.Name, &user.Email, &user.Verified, &user.CountryCode, &user.PhoneNumber)
+func (p *psqlUserRepository) GetUserByPhoneNumberAndCountry(ctx context.Context, countryCode int32, phoneNumber string) (*models.User, error) {
+	user, err := scanUser(p.db.QueryRowContext(ctx, GET_USER_BY_PH, countryCode, phoneNumber))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user with country code %d and phone number %s not found", countryCode, phoneNumber)
 		}
 		return nil, err
 	}
-
-	return &user, nil
+	return user, nil
 }
 
-func (p *psqlUserRepository) MarkVerified(id int32) error {
-	_, err := p.db.Exec(UPDATE_VERIFIED, id)
+func (p *psqlUserRepository) MarkVerified(ctx context.Context, id int32) error {
+	_, err := p.db.ExecContext(ctx, UPDATE_VERIFIED, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// IsUserNameTaken compares user names case-insensitively, matching the users_user_name_lower_key index
+func (p *psqlUserRepository) IsUserNameTaken(ctx context.Context, userName string) (bool, error) {
+	var taken bool
+	err := p.db.QueryRowContext(ctx, USER_NAME_TAKEN, userName).Scan(&taken)
+	if err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+func (p *psqlUserRepository) IsPhoneNumberTaken(ctx context.Context, countryCode int32, phoneNumber string) (bool, error) {
+	var taken bool
+	err := p.db.QueryRowContext(ctx, PHONE_NUMBER_TAKEN, countryCode, phoneNumber).Scan(&taken)
+	if err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+func (p *psqlUserRepository) ListPhoneNumbers(ctx context.Context, userId int32) ([]models.HeldPhoneNumber, error) {
+	rows, err := p.db.QueryContext(ctx, LIST_PHONE_NUMBERS, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var numbers []models.HeldPhoneNumber
+	for rows.Next() {
+		var number models.HeldPhoneNumber
+		// acquired_at is NULL for numbers replaced before it was recorded
+		var acquiredAt, replacedAt sql.NullTime
+		if err := rows.Scan(&number.PhoneNumber, &acquiredAt, &replacedAt); err != nil {
+			return nil, err
+		}
+		number.AcquiredAt, number.ReplacedAt = acquiredAt.Time, replacedAt.Time
+		numbers = append(numbers, number)
+	}
+	return numbers, rows.Err()
+}
+
+func (p *psqlUserRepository) RegisterUser(ctx context.Context, registration Registration) (*models.User, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// rolling back after a commit is a no-op
+	defer tx.Rollback()
+	user := registration.User
+	if registration.ReclaimUnverifiedAfter > 0 {
+		_, err = purgeUsers(ctx, tx, RECLAIM_UNVERIFIED, user.UserName, user.Email, user.CanonicalEmail, user.PhoneNumber, registration.ReclaimUnverifiedAfter.Seconds(), user.CountryCode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	saved, err := scanUser(tx.QueryRowContext(ctx, INSERT_QUERY, user.Name, user.UserName, user.Email, user.CanonicalEmail, user.Verified, user.CountryCode, user.PhoneNumber))
+	if err != nil {
+		return nil, translateUserWriteError(err)
+	}
+	if _, err = tx.ExecContext(ctx, INSERT_EVENT_QUERY, saved.PhoneNumber, registration.Event); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, INSERT_OUTBOX_MESSAGE, registration.Outbox.Topic, registration.Outbox.Payload,
+		registration.Outbox.CorrelationId, registration.Outbox.TraceParent, registration.Outbox.TraceState, registration.Outbox.PhoneNumber); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (p *psqlUserRepository) UpdateUser(ctx context.Context, user *models.User, expectedVersion int64) (*models.User, error) {
+	row := p.db.QueryRowContext(ctx, UPDATE_USER, user.Id, user.Name, user.UserName, user.Email, user.CanonicalEmail,
+		user.PendingEmail, user.PendingCanonicalEmail, user.PendingCountryCode, user.PendingPhoneNumber, expectedVersion,
+		nullTime(user.EmailConfirmedAt))
+	updated, err := scanUser(row)
+	if err == sql.ErrNoRows {
+		// either the user does not exist or its version moved on
+		if _, err = p.GetUser(ctx, user.Id); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrStaleVersion
+	}
+	if err != nil {
+		return nil, translateUserWriteError(err)
+	}
+	return updated, nil
+}
+
+func (p *psqlUserRepository) ChangePhoneNumber(ctx context.Context, change PhoneNumberChange) (*models.User, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// rolling back after a commit is a no-op
+	defer tx.Rollback()
+	current, err := scanUser(tx.QueryRowContext(ctx, GET_USER_FOR_UPDATE, change.UserId))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user with id %d not found", change.UserId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != change.ExpectedVersion {
+		return nil, models.ErrStaleVersion
+	}
+	if current.PendingPhoneNumber == "" {
+		return nil, fmt.Errorf("user with id %d has no pending phone number", change.UserId)
+	}
+	if _, err = tx.ExecContext(ctx, RECORD_REPLACED_PHONE_NUMBER, change.UserId); err != nil {
+		return nil, err
+	}
+	changed, err := scanUser(tx.QueryRowContext(ctx, CHANGE_PHONE_NUMBER, change.UserId))
+	if err != nil {
+		return nil, translateUserWriteError(err)
+	}
+	for _, event := range change.OldNumberEvents {
+		if _, err = tx.ExecContext(ctx, INSERT_EVENT_QUERY, current.PhoneNumber, event); err != nil {
+			return nil, err
+		}
+	}
+	for _, event := range change.NewNumberEvents {
+		if _, err = tx.ExecContext(ctx, INSERT_EVENT_QUERY, changed.PhoneNumber, event); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (p *psqlUserRepository) SetUserStatus(ctx context.Context, userId int32, status string, expectedVersion int64) (*models.User, error) {
+	updated, err := scanUser(p.db.QueryRowContext(ctx, SET_USER_STATUS, userId, status, expectedVersion))
+	if err == sql.ErrNoRows {
+		// either the user does not exist or its version moved on
+		if _, err = p.GetUser(ctx, userId); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrStaleVersion
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (p *psqlUserRepository) PurgeDeletedUsers(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	return p.purgeUsersInTx(ctx, PURGE_DELETED_USERS, gracePeriod.Seconds())
+}
+
+func (p *psqlUserRepository) DeleteExpiredUnverified(ctx context.Context, ttl time.Duration) (int64, error) {
+	return p.purgeUsersInTx(ctx, DELETE_EXPIRED_UNVERIFIED, ttl.Seconds())
+}
+
+func (p *psqlUserRepository) purgeUsersInTx(ctx context.Context, query string, args ...any) (int64, error) {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	// rolling back after a commit is a no-op
+	defer tx.Rollback()
+	purged, err := purgeUsers(ctx, tx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// purgeUsers deletes the users whose ids the query selects together with everything stored under the phone numbers
+// they held, their events are kept under "deleted-<user id>"
+func purgeUsers(ctx context.Context, tx *sql.Tx, query string, args ...any) (int64, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int32
+	for rows.Next() {
+		var id int32
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	for _, statement := range []string{ANONYMIZE_PURGED_EVENTS, DELETE_PURGED_DELIVERIES, DELETE_PURGED_OTP_CODES, DELETE_PURGED_OUTBOX_MESSAGES} {
+		if _, err = tx.ExecContext(ctx, statement, pq.Array(ids)); err != nil {
+			return 0, err
+		}
+	}
+	result, err := tx.ExecContext(ctx, DELETE_PURGED_USERS, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
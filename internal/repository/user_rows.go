@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+)
+
+// USER_COLUMNS is the only column list used to read users, scanUser depends on its order.
+// Selecting explicit columns keeps reads stable when migrations add or reorder columns.
+const USER_COLUMNS = "id, name, user_name, email, canonical_email, is_verified, country_code, phone_number, created_at, " +
+	"version, updated_at, pending_email, pending_canonical_email, pending_country_code, pending_phone_number, " +
+	"status, deleted_at, sessions_revoked_at, email_confirmed_at"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	// phone_number, created_at, deleted_at, sessions_revoked_at, email_confirmed_at and the pending email and phone
+	// number are nullable in the schema
+	var phoneNumber, pendingEmail, pendingCanonicalEmail, pendingPhoneNumber sql.NullString
+	var pendingCountryCode sql.NullInt32
+	var createdAt, deletedAt, sessionsRevokedAt, emailConfirmedAt sql.NullTime
+	err := row.Scan(&user.Id, &user.Name, &user.UserName, &user.Email, &user.CanonicalEmail, &user.Verified,
+		&user.CountryCode, &phoneNumber, &createdAt, &user.Version, &user.UpdatedAt, &pendingEmail, &pendingCanonicalEmail,
+		&pendingCountryCode, &pendingPhoneNumber, &user.Status, &deletedAt, &sessionsRevokedAt,
+		&emailConfirmedAt)
+	if err != nil {
+		return nil, err
+	}
+	user.PhoneNumber = phoneNumber.String
+	user.CreatedAt = createdAt.Time
+	user.PendingEmail = pendingEmail.String
+	user.PendingCanonicalEmail = pendingCanonicalEmail.String
+	user.PendingCountryCode = pendingCountryCode.Int32
+	user.PendingPhoneNumber = pendingPhoneNumber.String
+	user.DeletedAt = deletedAt.Time
+	user.SessionsRevokedAt = sessionsRevokedAt.Time
+	user.EmailConfirmedAt = emailConfirmedAt.Time
+	return &user, nil
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"auth-service/internal/models"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (f fakeRow) Scan(dest ...any) error {
+	if f.err != nil {
+		return f.err
+	}
+	if len(dest) != len(f.values) {
+		return errors.New("column count mismatch")
+	}
+	for i, value := range f.values {
+		switch target := dest[i].(type) {
+		case *int32:
+			*target = value.(int32)
+		case *int64:
+			*target = value.(int64)
+		case *time.Time:
+			*target = value.(time.Time)
+		case *string:
+			*target = value.(string)
+		case *bool:
+			*target = value.(bool)
+		case *sql.NullString:
+			if value != nil {
+				*target = sql.NullString{String: value.(string), Valid: true}
+			}
+		case *sql.NullInt32:
+			if value != nil {
+				*target = sql.NullInt32{Int32: value.(int32), Valid: true}
+			}
+		case *sql.NullTime:
+			if value != nil {
+				*target = sql.NullTime{Time: value.(time.Time), Valid: true}
+			}
+		default:
+			return errors.New("unexpected scan target")
+		}
+	}
+	return nil
+}
+
+func TestScanUserMapsEveryColumn(t *testing.T) {
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+	deletedAt := updatedAt.Add(time.Hour)
+	revokedAt := createdAt.Add(30 * time.Minute)
+	confirmedAt := createdAt.Add(15 * time.Minute)
+	row := fakeRow{values: []any{int32(7), "John Doe", "johndoe", "John@example.com", "john@example.com", true, int32(91), "1234567890", createdAt,
+		int64(3), updatedAt, "new@example.com", "new@example.com", int32(1), "5551234567",
+		models.USER_STATUS_DELETED, deletedAt, revokedAt, confirmedAt}}
+	user, err := scanUser(row)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.User{
+		Id:                    7,
+		Name:                  "John Doe",
+		UserName:              "johndoe",
+		Email:                 "John@example.com",
+		CanonicalEmail:        "john@example.com",
+		Verified:              true,
+		CountryCode:           91,
+		PhoneNumber:           "1234567890",
+		CreatedAt:             createdAt,
+		Version:               3,
+		UpdatedAt:             updatedAt,
+		PendingEmail:          "new@example.com",
+		PendingCanonicalEmail: "new@example.com",
+		PendingCountryCode:    1,
+		PendingPhoneNumber:    "5551234567",
+		Status:                models.USER_STATUS_DELETED,
+		DeletedAt:             deletedAt,
+		SessionsRevokedAt:     revokedAt,
+		EmailConfirmedAt:      confirmedAt,
+	}, user)
+}
+
+func TestScanUserHandlesNullableColumns(t *testing.T) {
+	row := fakeRow{values: []any{int32(7), "John Doe", "johndoe", "john@example.com", "john@example.com", false, int32(91), nil, nil,
+		int64(1), time.Now(), nil, nil, nil, nil, models.USER_STATUS_ACTIVE, nil, nil, nil}}
+	user, err := scanUser(row)
+	assert.NoError(t, err)
+	assert.Empty(t, user.PhoneNumber)
+	assert.Empty(t, user.PendingEmail)
+	assert.Empty(t, user.PendingPhoneNumber)
+	assert.Zero(t, user.PendingCountryCode)
+	assert.True(t, user.CreatedAt.IsZero())
+	assert.True(t, user.DeletedAt.IsZero())
+	assert.True(t, user.SessionsRevokedAt.IsZero())
+	assert.True(t, user.EmailConfirmedAt.IsZero())
+}
+
+func TestScanUserReturnsScanErrors(t *testing.T) {
+	_, err := scanUser(fakeRow{err: sql.ErrNoRows})
+	assert.Equal(t, sql.ErrNoRows, err)
+}
+
+func TestUserColumnsMatchScanTargets(t *testing.T) {
+	columns := strings.Split(USER_COLUMNS, ",")
+	var targets int
+	_, _ = scanUser(scanCounter(func(dest []any) { targets = len(dest) }))
+	assert.Equal(t, len(columns), targets)
+}
+
+type scanCounter func(dest []any)
+
+func (s scanCounter) Scan(dest ...any) error {
+	s(dest)
+	return errors.New("counted")
+}
@@ -5,41 +5,55 @@ import (
 	"auth-service/internal/service"
 	"connectrpc.com/connect"
 	"context"
+	"errors"
+	"math"
+	"strings"
+)
+
+// idempotency scopes, retried signups and login otp requests must not create users or send otps twice
+const (
+	SIGNUP_SCOPE       = "signupWithPhoneNumber"
+	LOGIN_SCOPE        = "loginWithPhoneNumber"
+	RESEND_OTP_SCOPE   = "resendOtp"
+	PHONE_CHANGE_SCOPE = "startPhoneChange"
 )
 
 type AuthServer struct {
-	service service.IAuthService
+	service     service.IAuthService
+	idempotency *Idempotency
 }
 
-func NewAuthServer(authService service.IAuthService) *AuthServer {
+func NewAuthServer(authService service.IAuthService, idempotency *Idempotency) *AuthServer {
 	return &AuthServer{
-		service: authService,
+		service:     authService,
+		idempotency: idempotency,
 	}
 }
 func (a *AuthServer) SignupWithPhoneNumber(ctx context.Context, req *connect.Request[v1.SignupWithPhoneNumberRequest]) (*connect.Response[v1.SignupWithPhoneNumberResponse], error) {
-	response := &v1.SignupWithPhoneNumberResponse{}
-	user, err := a.service.HandleSignUp(req.Msg)
-	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
+	response, err := runIdempotent(ctx, a.idempotency, SIGNUP_SCOPE, req.Header(), req.Msg.RequestId, req.Msg, &v1.SignupWithPhoneNumberResponse{}, func() *v1.SignupWithPhoneNumberResponse {
+		response := &v1.SignupWithPhoneNumberResponse{}
+		user, deliveryId, err := a.service.HandleSignUp(ctx, req.Msg)
+		if err != nil {
+			response.Error = toError(err)
+			response.IsSuccess = false
+		} else {
+			response.IsSuccess = true
+			response.UserId = user.Id
+			response.DeliveryId = deliveryId
 		}
-		response.IsSuccess = false
-	} else {
-		response.IsSuccess = true
-		response.UserId = user.Id
+		return response
+	})
+	if err != nil {
+		response = &v1.SignupWithPhoneNumberResponse{Error: toError(err), IsSuccess: false}
 	}
 	return connect.NewResponse(response), nil
 }
 
 func (a *AuthServer) VerifyPhoneNumber(ctx context.Context, request *connect.Request[v1.VerifyPhoneNumberRequest]) (*connect.Response[v1.VerifyPhoneNumberResponse], error) {
 	response := &v1.VerifyPhoneNumberResponse{}
-	err := a.service.VerifyOtp(request.Msg)
+	err := a.service.VerifyOtp(ctx, request.Msg)
 	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
-		}
+		response.Error = toError(err)
 		response.IsSuccess = false
 	} else {
 		response.IsSuccess = true
@@ -48,28 +62,29 @@ func (a *AuthServer) VerifyPhoneNumber(ctx context.Context, request *connect.Req
 }
 
 func (a *AuthServer) LoginWithPhoneNumber(ctx context.Context, request *connect.Request[v1.LoginWithPhoneNumberRequest]) (*connect.Response[v1.LoginWithPhoneNumberResponse], error) {
-	response := &v1.LoginWithPhoneNumberResponse{}
-	err := a.service.LoginWithPhoneNumber(request.Msg)
-	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
+	response, err := runIdempotent(ctx, a.idempotency, LOGIN_SCOPE, request.Header(), request.Msg.RequestId, request.Msg, &v1.LoginWithPhoneNumberResponse{}, func() *v1.LoginWithPhoneNumberResponse {
+		response := &v1.LoginWithPhoneNumberResponse{}
+		deliveryId, err := a.service.LoginWithPhoneNumber(ctx, request.Msg)
+		if err != nil {
+			response.Error = toError(err)
+			response.IsSuccess = false
+		} else {
+			response.IsSuccess = true
+			response.DeliveryId = deliveryId
 		}
-		response.IsSuccess = false
-	} else {
-		response.IsSuccess = true
+		return response
+	})
+	if err != nil {
+		response = &v1.LoginWithPhoneNumberResponse{Error: toError(err), IsSuccess: false}
 	}
 	return connect.NewResponse(response), nil
 }
 
 func (a *AuthServer) ValidatePhoneNumberLogin(ctx context.Context, request *connect.Request[v1.ValidatePhoneNumberLoginRequest]) (*connect.Response[v1.ValidatePhoneNumberLoginResponse], error) {
 	response := &v1.ValidatePhoneNumberLoginResponse{}
-	err := a.service.ValidatePhoneNumberLogin(request.Msg)
+	err := a.service.ValidatePhoneNumberLogin(ctx, request.Msg)
 	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
-		}
+		response.Error = toError(err)
 		response.IsSuccess = false
 	} else {
 		response.IsSuccess = true
@@ -79,12 +94,9 @@ func (a *AuthServer) ValidatePhoneNumberLogin(ctx context.Context, request *conn
 
 func (a *AuthServer) GetProfile(ctx context.Context, req *connect.Request[v1.GetProfileRequest]) (*connect.Response[v1.GetProfileResponse], error) {
 	response := &v1.GetProfileResponse{}
-	user, err := a.service.GetUserProfile(req.Msg)
+	user, err := a.service.GetUserProfile(ctx, req.Msg)
 	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
-		}
+		response.Error = toError(err)
 		response.IsSuccess = false
 	} else {
 		response.IsSuccess = true
@@ -95,12 +107,73 @@ func (a *AuthServer) GetProfile(ctx context.Context, req *connect.Request[v1.Get
 
 func (a *AuthServer) GetProfileByPhoneNumber(ctx context.Context, req *connect.Request[v1.GetProfileByPhoneNumberRequest]) (*connect.Response[v1.GetProfileByPhoneNumberResponse], error) {
 	response := &v1.GetProfileByPhoneNumberResponse{}
-	user, err := a.service.GetUserProfileByPhone(req.Msg)
+	user, err := a.service.GetUserProfileByPhone(ctx, req.Msg)
 	if err != nil {
-		response.Error = &v1.Error{
-			Message:   err.Error(),
-			ErrorCode: 1,
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.User = user
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) CheckUsernameAvailability(ctx context.Context, req *connect.Request[v1.CheckUsernameAvailabilityRequest]) (*connect.Response[v1.CheckUsernameAvailabilityResponse], error) {
+	response := &v1.CheckUsernameAvailabilityResponse{}
+	available, suggestions, err := a.service.CheckUsernameAvailability(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.IsAvailable = available
+		response.Suggestions = suggestions
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) ResendOtp(ctx context.Context, req *connect.Request[v1.ResendOtpRequest]) (*connect.Response[v1.ResendOtpResponse], error) {
+	response, err := runIdempotent(ctx, a.idempotency, RESEND_OTP_SCOPE, req.Header(), req.Msg.RequestId, req.Msg, &v1.ResendOtpResponse{}, func() *v1.ResendOtpResponse {
+		response := &v1.ResendOtpResponse{}
+		channel, deliveryId, err := a.service.ResendOtp(ctx, req.Msg)
+		var cooldown *service.ResendCooldownError
+		if errors.As(err, &cooldown) {
+			response.RetryAfterSeconds = int32(math.Ceil(cooldown.RetryAfter.Seconds()))
+		}
+		if err != nil {
+			response.Error = toError(err)
+			response.IsSuccess = false
+		} else {
+			response.IsSuccess = true
+			response.Channel = strings.TrimPrefix(channel.String(), "DELIVERY_CHANNEL_")
+			response.DeliveryId = deliveryId
 		}
+		return response
+	})
+	if err != nil {
+		response = &v1.ResendOtpResponse{Error: toError(err), IsSuccess: false}
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) UpdateProfile(ctx context.Context, req *connect.Request[v1.UpdateProfileRequest]) (*connect.Response[v1.UpdateProfileResponse], error) {
+	response := &v1.UpdateProfileResponse{}
+	user, err := a.service.UpdateProfile(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.User = user
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) ConfirmEmailChange(ctx context.Context, req *connect.Request[v1.ConfirmEmailChangeRequest]) (*connect.Response[v1.ConfirmEmailChangeResponse], error) {
+	response := &v1.ConfirmEmailChangeResponse{}
+	user, err := a.service.ConfirmEmailChange(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
 		response.IsSuccess = false
 	} else {
 		response.IsSuccess = true
@@ -108,3 +181,107 @@ func (a *AuthServer) GetProfileByPhoneNumber(ctx context.Context, req *connect.R
 	}
 	return connect.NewResponse(response), nil
 }
+
+func (a *AuthServer) StartPhoneChange(ctx context.Context, req *connect.Request[v1.StartPhoneChangeRequest]) (*connect.Response[v1.StartPhoneChangeResponse], error) {
+	response, err := runIdempotent(ctx, a.idempotency, PHONE_CHANGE_SCOPE, req.Header(), req.Msg.RequestId, req.Msg, &v1.StartPhoneChangeResponse{}, func() *v1.StartPhoneChangeResponse {
+		response := &v1.StartPhoneChangeResponse{}
+		user, deliveryId, err := a.service.StartPhoneChange(ctx, req.Msg)
+		if err != nil {
+			response.Error = toError(err)
+			response.IsSuccess = false
+		} else {
+			response.IsSuccess = true
+			response.User = user
+			response.DeliveryId = deliveryId
+		}
+		return response
+	})
+	if err != nil {
+		response = &v1.StartPhoneChangeResponse{Error: toError(err), IsSuccess: false}
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) ConfirmPhoneChange(ctx context.Context, req *connect.Request[v1.ConfirmPhoneChangeRequest]) (*connect.Response[v1.ConfirmPhoneChangeResponse], error) {
+	response := &v1.ConfirmPhoneChangeResponse{}
+	user, err := a.service.ConfirmPhoneChange(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.User = user
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) DeleteAccount(ctx context.Context, req *connect.Request[v1.DeleteAccountRequest]) (*connect.Response[v1.DeleteAccountResponse], error) {
+	response := &v1.DeleteAccountResponse{}
+	restorableUntil, err := a.service.DeleteAccount(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.RestorableUntil = restorableUntil.Unix()
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) RestoreAccount(ctx context.Context, req *connect.Request[v1.RestoreAccountRequest]) (*connect.Response[v1.RestoreAccountResponse], error) {
+	response := &v1.RestoreAccountResponse{}
+	user, err := a.service.RestoreAccount(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.User = user
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) ExportMyData(ctx context.Context, req *connect.Request[v1.ExportMyDataRequest]) (*connect.Response[v1.ExportMyDataResponse], error) {
+	response := &v1.ExportMyDataResponse{}
+	ticket, err := a.service.ExportMyData(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.ExportId = ticket.ExportId
+		response.DownloadToken = ticket.Token
+		response.ExpiresAt = ticket.ExpiresAt.Unix()
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) DownloadMyData(ctx context.Context, req *connect.Request[v1.DownloadMyDataRequest]) (*connect.Response[v1.DownloadMyDataResponse], error) {
+	response := &v1.DownloadMyDataResponse{}
+	export, err := a.service.DownloadMyData(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.Status = export.Status
+		response.Archive = export.Archive
+	}
+	return connect.NewResponse(response), nil
+}
+
+func (a *AuthServer) GetOtpDeliveryStatus(ctx context.Context, req *connect.Request[v1.GetOtpDeliveryStatusRequest]) (*connect.Response[v1.GetOtpDeliveryStatusResponse], error) {
+	response := &v1.GetOtpDeliveryStatusResponse{}
+	delivery, err := a.service.GetOtpDeliveryStatus(ctx, req.Msg)
+	if err != nil {
+		response.Error = toError(err)
+		response.IsSuccess = false
+	} else {
+		response.IsSuccess = true
+		response.Status = delivery.Status
+		response.Channel = delivery.Channel
+		response.Reason = delivery.Reason
+		response.UpdatedAt = delivery.UpdatedAt.Unix()
+	}
+	return connect.NewResponse(response), nil
+}
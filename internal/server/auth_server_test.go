@@ -2,19 +2,24 @@ package server
 
 import (
 	auth "auth-service/internal/gen/auth/v1"
-	_ "auth-service/internal/service"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
 	"auth-service/mocks"
 	"connectrpc.com/connect"
 	_ "connectrpc.com/connect"
 	"context"
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"testing"
+	"time"
 )
 
 func TestAuthServer_HandleSignUp_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	User := &auth.User{
 		Id:          123,
 		Name:        "John Doe",
@@ -25,33 +30,34 @@ func TestAuthServer_HandleSignUp_Success(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	request := &auth.SignupWithPhoneNumberRequest{User: User}
-	mockService.On("HandleSignUp", request).Return(User, nil)
+	mockService.On("HandleSignUp", mock.Anything, request).Return(User, "delivery-1", nil)
 	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, "delivery-1", response.Msg.DeliveryId)
 }
 
 func TestAuthServer_HandleSignUp_Failure(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.SignupWithPhoneNumberRequest{
 		User: &auth.User{},
 	}
-	mockService.On("HandleSignUp", request).Return(nil, errors.New("service call failed"))
+	mockService.On("HandleSignUp", mock.Anything, request).Return(nil, "", errors.New("service call failed"))
 	response, _ := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
 
 func TestAuthServer_VerifyPhoneNumber_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.VerifyPhoneNumberRequest{
 		RequestId:   "123",
 		Otp:         123456,
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 	}
-	mockService.On("VerifyOtp", request).Return(nil)
+	mockService.On("VerifyOtp", mock.Anything, request).Return(nil)
 	response, err := authServer.VerifyPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
@@ -59,52 +65,53 @@ func TestAuthServer_VerifyPhoneNumber_Success(t *testing.T) {
 
 func TestAuthServer_VerifyPhoneNumber_Error(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.VerifyPhoneNumberRequest{
 		RequestId:   "123",
 		Otp:         123456,
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 	}
-	mockService.On("VerifyOtp", request).Return(errors.New("service failed"))
+	mockService.On("VerifyOtp", mock.Anything, request).Return(errors.New("service failed"))
 	response, _ := authServer.VerifyPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
 
 func TestAuthServer_LoginWithPhoneNumber_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.LoginWithPhoneNumberRequest{
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 	}
-	mockService.On("LoginWithPhoneNumber", request).Return(nil)
+	mockService.On("LoginWithPhoneNumber", mock.Anything, request).Return("delivery-1", nil)
 	response, err := authServer.LoginWithPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, "delivery-1", response.Msg.DeliveryId)
 }
 
 func TestAuthServer_LoginWithPhoneNumber_Error(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.LoginWithPhoneNumberRequest{
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 	}
-	mockService.On("LoginWithPhoneNumber", request).Return(errors.New("service failed"))
+	mockService.On("LoginWithPhoneNumber", mock.Anything, request).Return("", errors.New("service failed"))
 	response, _ := authServer.LoginWithPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
 
 func TestAuthServer_ValidatePhoneNumberLogin_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.ValidatePhoneNumberLoginRequest{
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 		Otp:         123456,
 	}
-	mockService.On("ValidatePhoneNumberLogin", request).Return(nil)
+	mockService.On("ValidatePhoneNumberLogin", mock.Anything, request).Return(nil)
 	response, err := authServer.ValidatePhoneNumberLogin(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
@@ -112,20 +119,20 @@ func TestAuthServer_ValidatePhoneNumberLogin_Success(t *testing.T) {
 
 func TestAuthServer_ValidatePhoneNumberLogin_Error(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.ValidatePhoneNumberLoginRequest{
 		CountryCode: 1,
 		PhoneNumber: "+1234567890",
 		Otp:         123456,
 	}
-	mockService.On("ValidatePhoneNumberLogin", request).Return(errors.New("service failed"))
+	mockService.On("ValidatePhoneNumberLogin", mock.Anything, request).Return(errors.New("service failed"))
 	response, _ := authServer.ValidatePhoneNumberLogin(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
 
 func TestAuthServer_GetProfile_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	user := &auth.User{
 		Id:          123,
 		Name:        "John Doe",
@@ -136,7 +143,7 @@ func TestAuthServer_GetProfile_Success(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	request := &auth.GetProfileRequest{}
-	mockService.On("GetUserProfile", request).Return(user, nil)
+	mockService.On("GetUserProfile", mock.Anything, request).Return(user, nil)
 	response, err := authServer.GetProfile(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
@@ -145,16 +152,16 @@ func TestAuthServer_GetProfile_Success(t *testing.T) {
 
 func TestAuthServer_GetProfile_Error(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.GetProfileRequest{}
-	mockService.On("GetUserProfile", request).Return(nil, errors.New("service failed"))
+	mockService.On("GetUserProfile", mock.Anything, request).Return(nil, errors.New("service failed"))
 	response, _ := authServer.GetProfile(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
 
 func TestAuthServer_GetProfileByPhoneNumber_Success(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	user := &auth.User{
 		Id:          123,
 		Name:        "John Doe",
@@ -168,7 +175,7 @@ func TestAuthServer_GetProfileByPhoneNumber_Success(t *testing.T) {
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
 	}
-	mockService.On("GetUserProfileByPhone", request).Return(user, nil)
+	mockService.On("GetUserProfileByPhone", mock.Anything, request).Return(user, nil)
 	response, err := authServer.GetProfileByPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.NoError(t, err)
 	assert.True(t, response.Msg.IsSuccess)
@@ -177,12 +184,192 @@ func TestAuthServer_GetProfileByPhoneNumber_Success(t *testing.T) {
 
 func TestAuthServer_GetProfileByPhoneNumber_Error(t *testing.T) {
 	mockService := &mocks.IAuthService{}
-	authServer := NewAuthServer(mockService)
+	authServer := NewAuthServer(mockService, nil)
 	request := &auth.GetProfileByPhoneNumberRequest{
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
 	}
-	mockService.On("GetUserProfileByPhone", request).Return(nil, errors.New("service failed"))
+	mockService.On("GetUserProfileByPhone", mock.Anything, request).Return(nil, errors.New("service failed"))
 	response, _ := authServer.GetProfileByPhoneNumber(context.Background(), connect.NewRequest(request))
 	assert.False(t, response.Msg.IsSuccess)
 }
+
+func TestAuthServer_CheckUsernameAvailability_Success(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "johndoe"}
+	mockService.On("CheckUsernameAvailability", mock.Anything, request).Return(false, []string{"johndoe42"}, nil)
+	response, err := authServer.CheckUsernameAvailability(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.False(t, response.Msg.IsAvailable)
+	assert.Equal(t, []string{"johndoe42"}, response.Msg.Suggestions)
+}
+
+func TestAuthServer_CheckUsernameAvailability_Error(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "admin"}
+	mockService.On("CheckUsernameAvailability", mock.Anything, request).Return(false, nil, errors.New("user name admin is reserved"))
+	response, _ := authServer.CheckUsernameAvailability(context.Background(), connect.NewRequest(request))
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, "user name admin is reserved", response.Msg.Error.Message)
+}
+
+func TestAuthServer_PropagatesRequestContext(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := &auth.GetProfileRequest{UserId: 1}
+	mockService.On("GetUserProfile", ctx, request).Return(nil, context.Canceled)
+	response, err := authServer.GetProfile(ctx, connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthServer_ResendOtp_Success(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.ResendOtpRequest{RequestId: "123", CountryCode: 91, PhoneNumber: "1234567890"}
+	mockService.On("ResendOtp", mock.Anything, request).Return(otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE, "delivery-1", nil)
+	response, err := authServer.ResendOtp(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, "VOICE", response.Msg.Channel)
+	assert.Equal(t, "delivery-1", response.Msg.DeliveryId)
+}
+
+func TestAuthServer_ResendOtp_Cooldown(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.ResendOtpRequest{RequestId: "123", CountryCode: 91, PhoneNumber: "1234567890"}
+	mockService.On("ResendOtp", mock.Anything, request).Return(otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", &service.ResendCooldownError{RetryAfter: 1500 * time.Millisecond})
+	response, err := authServer.ResendOtp(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, ERROR_CODE_TOO_MANY_REQUESTS, response.Msg.Error.ErrorCode)
+	assert.Equal(t, int32(2), response.Msg.RetryAfterSeconds)
+}
+
+func TestAuthServer_UpdateProfile_Success(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.UpdateProfileRequest{UserId: 1, User: &auth.User{Name: "Jane Doe"}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}}, Version: 1}
+	mockService.On("UpdateProfile", mock.Anything, request).Return(&auth.User{Id: 1, Name: "Jane Doe", Version: 2}, nil)
+	response, err := authServer.UpdateProfile(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, int64(2), response.Msg.User.Version)
+}
+
+func TestAuthServer_UpdateProfile_VersionConflict(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.UpdateProfileRequest{UserId: 1, Version: 1}
+	mockService.On("UpdateProfile", mock.Anything, request).Return(nil, models.ErrStaleVersion)
+	response, err := authServer.UpdateProfile(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, ERROR_CODE_VERSION_CONFLICT, response.Msg.Error.ErrorCode)
+}
+
+func TestAuthServer_ConfirmEmailChange(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.ConfirmEmailChangeRequest{UserId: 1, Otp: 123456}
+	mockService.On("ConfirmEmailChange", mock.Anything, request).Return(&auth.User{Id: 1, Email: "jane@example.com"}, nil)
+	response, err := authServer.ConfirmEmailChange(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, "jane@example.com", response.Msg.User.Email)
+}
+
+func TestAuthServer_StartPhoneChange_LoginRequired(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567"}
+	mockService.On("StartPhoneChange", mock.Anything, request).Return(nil, "", service.ErrFreshLoginRequired)
+	response, err := authServer.StartPhoneChange(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, ERROR_CODE_LOGIN_REQUIRED, response.Msg.Error.ErrorCode)
+}
+
+func TestAuthServer_ConfirmPhoneChange(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.ConfirmPhoneChangeRequest{UserId: 1, Otp: 123456}
+	mockService.On("ConfirmPhoneChange", mock.Anything, request).Return(&auth.User{Id: 1, CountryCode: 1, PhoneNumber: "5551234567"}, nil)
+	response, err := authServer.ConfirmPhoneChange(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, "5551234567", response.Msg.User.PhoneNumber)
+}
+
+func TestAuthServer_DeleteAccount(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.DeleteAccountRequest{UserId: 1, Otp: 123456}
+	restorableUntil := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("DeleteAccount", mock.Anything, request).Return(restorableUntil, nil)
+	response, err := authServer.DeleteAccount(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, restorableUntil.Unix(), response.Msg.RestorableUntil)
+}
+
+func TestAuthServer_RestoreAccount(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.RestoreAccountRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 123456}
+	mockService.On("RestoreAccount", mock.Anything, request).Return(&auth.User{Id: 1}, nil)
+	response, err := authServer.RestoreAccount(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, int32(1), response.Msg.User.Id)
+}
+
+func TestAuthServer_ExportMyData(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.ExportMyDataRequest{UserId: 1, Otp: 123456}
+	expiresAt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mockService.On("ExportMyData", mock.Anything, request).Return(&models.DataExportTicket{ExportId: 5, Token: "token", ExpiresAt: expiresAt}, nil)
+	response, err := authServer.ExportMyData(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, int64(5), response.Msg.ExportId)
+	assert.Equal(t, "token", response.Msg.DownloadToken)
+	assert.Equal(t, expiresAt.Unix(), response.Msg.ExpiresAt)
+}
+
+func TestAuthServer_DownloadMyData(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.DownloadMyDataRequest{ExportId: 5, DownloadToken: "token"}
+	mockService.On("DownloadMyData", mock.Anything, request).Return(&models.DataExport{Id: 5, Status: models.DATA_EXPORT_READY, Archive: []byte(`{}`)}, nil)
+	response, err := authServer.DownloadMyData(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, models.DATA_EXPORT_READY, response.Msg.Status)
+	assert.Equal(t, []byte(`{}`), response.Msg.Archive)
+}
+
+func TestAuthServer_GetOtpDeliveryStatus(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer := NewAuthServer(mockService, nil)
+	request := &auth.GetOtpDeliveryStatusRequest{CountryCode: 91, PhoneNumber: "1234567890", DeliveryId: "request-1"}
+	updatedAt := time.Unix(1700000000, 0)
+	mockService.On("GetOtpDeliveryStatus", mock.Anything, request).Return(&models.OtpDelivery{
+		RequestId: "request-1", Channel: "SMS", Status: models.OTP_DELIVERY_FAILED, Reason: "unreachable", UpdatedAt: updatedAt,
+	}, nil)
+	response, err := authServer.GetOtpDeliveryStatus(context.Background(), connect.NewRequest(request))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	assert.Equal(t, models.OTP_DELIVERY_FAILED, response.Msg.Status)
+	assert.Equal(t, "SMS", response.Msg.Channel)
+	assert.Equal(t, "unreachable", response.Msg.Reason)
+	assert.Equal(t, updatedAt.Unix(), response.Msg.UpdatedAt)
+}
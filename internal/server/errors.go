@@ -0,0 +1,65 @@
+package server
+
+import (
+	"auth-service/internal/gateway"
+	v1 "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"errors"
+	"log/slog"
+)
+
+// Error codes returned in v1.Error.ErrorCode
+const (
+	ERROR_CODE_UNKNOWN        int32 = 1
+	ERROR_CODE_ALREADY_EXISTS int32 = 2
+	// ERROR_CODE_LOGIN_INSTEAD tells clients to switch to the login flow
+	ERROR_CODE_LOGIN_INSTEAD int32 = 3
+	// ERROR_CODE_REQUEST_IN_PROGRESS asks clients to retry once the first request with the idempotency key completed
+	ERROR_CODE_REQUEST_IN_PROGRESS    int32 = 4
+	ERROR_CODE_IDEMPOTENCY_KEY_REUSED int32 = 5
+	// ERROR_CODE_TOO_MANY_REQUESTS is returned for rate limited requests, retrying later succeeds
+	ERROR_CODE_TOO_MANY_REQUESTS int32 = 6
+	// ERROR_CODE_VERSION_CONFLICT asks clients to reload the profile and apply their change again
+	ERROR_CODE_VERSION_CONFLICT int32 = 7
+	// ERROR_CODE_LOGIN_REQUIRED asks clients to login again before retrying a sensitive change
+	ERROR_CODE_LOGIN_REQUIRED int32 = 8
+	// ERROR_CODE_ACCOUNT_DELETED tells clients to offer restoring the account instead of logging in
+	ERROR_CODE_ACCOUNT_DELETED int32 = 9
+	// ERROR_CODE_DELIVERY_UNAVAILABLE is returned when the otp could not be handed to the broker, retrying later succeeds
+	ERROR_CODE_DELIVERY_UNAVAILABLE int32 = 10
+)
+
+func toError(err error) *v1.Error {
+	code := ERROR_CODE_UNKNOWN
+	message := err.Error()
+	var alreadyExists *models.AlreadyExistsError
+	var cooldown *service.ResendCooldownError
+	switch {
+	case errors.Is(err, service.ErrPhoneNumberRegistered):
+		code = ERROR_CODE_LOGIN_INSTEAD
+	case errors.As(err, &alreadyExists):
+		code = ERROR_CODE_ALREADY_EXISTS
+	case errors.Is(err, ErrRequestInProgress):
+		code = ERROR_CODE_REQUEST_IN_PROGRESS
+	case errors.Is(err, ErrIdempotencyKeyReused):
+		code = ERROR_CODE_IDEMPOTENCY_KEY_REUSED
+	case errors.As(err, &cooldown), errors.Is(err, service.ErrResendLimitReached):
+		code = ERROR_CODE_TOO_MANY_REQUESTS
+	case errors.Is(err, models.ErrStaleVersion):
+		code = ERROR_CODE_VERSION_CONFLICT
+	case errors.Is(err, service.ErrFreshLoginRequired), errors.Is(err, service.ErrProfileProofRequired):
+		code = ERROR_CODE_LOGIN_REQUIRED
+	case errors.Is(err, service.ErrAccountDeleted):
+		code = ERROR_CODE_ACCOUNT_DELETED
+	case errors.Is(err, gateway.ErrDeliveryUnavailable):
+		// the broker failure is logged, clients only learn that the otp could not be sent
+		slog.Warn("otp delivery unavailable", "error", err)
+		code = ERROR_CODE_DELIVERY_UNAVAILABLE
+		message = gateway.ErrDeliveryUnavailable.Error()
+	}
+	return &v1.Error{
+		Message:   message,
+		ErrorCode: code,
+	}
+}
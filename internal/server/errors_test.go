@@ -0,0 +1,31 @@
+package server
+
+import (
+	"auth-service/internal/gateway"
+	"auth-service/internal/models"
+	"auth-service/internal/service"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestToError(t *testing.T) {
+	assert.Equal(t, ERROR_CODE_UNKNOWN, toError(errors.New("failed")).ErrorCode)
+	assert.Equal(t, ERROR_CODE_LOGIN_INSTEAD, toError(service.ErrPhoneNumberRegistered).ErrorCode)
+	assert.Equal(t, ERROR_CODE_REQUEST_IN_PROGRESS, toError(ErrRequestInProgress).ErrorCode)
+	assert.Equal(t, ERROR_CODE_IDEMPOTENCY_KEY_REUSED, toError(ErrIdempotencyKeyReused).ErrorCode)
+	assert.Equal(t, ERROR_CODE_TOO_MANY_REQUESTS, toError(&service.ResendCooldownError{RetryAfter: time.Second}).ErrorCode)
+	assert.Equal(t, ERROR_CODE_TOO_MANY_REQUESTS, toError(service.ErrResendLimitReached).ErrorCode)
+	assert.Equal(t, ERROR_CODE_VERSION_CONFLICT, toError(models.ErrStaleVersion).ErrorCode)
+	assert.Equal(t, ERROR_CODE_LOGIN_REQUIRED, toError(service.ErrFreshLoginRequired).ErrorCode)
+	assert.Equal(t, ERROR_CODE_ACCOUNT_DELETED, toError(service.ErrAccountDeleted).ErrorCode)
+	unavailable := toError(fmt.Errorf("login: %w", &gateway.DeliveryUnavailableError{Attempts: 3, Err: errors.New("dial tcp 10.0.0.5:5672: connection refused")}))
+	assert.Equal(t, ERROR_CODE_DELIVERY_UNAVAILABLE, unavailable.ErrorCode)
+	assert.Equal(t, gateway.ErrDeliveryUnavailable.Error(), unavailable.Message)
+
+	alreadyExists := toError(fmt.Errorf("signup: %w", &models.AlreadyExistsError{Field: models.FIELD_EMAIL}))
+	assert.Equal(t, ERROR_CODE_ALREADY_EXISTS, alreadyExists.ErrorCode)
+	assert.Equal(t, "signup: a user with this email already exists", alreadyExists.Message)
+}
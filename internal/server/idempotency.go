@@ -0,0 +1,111 @@
+package server
+
+import (
+	"auth-service/internal/repository"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// IDEMPOTENCY_KEY_HEADER takes precedence over the requestId field of the request
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	ErrRequestInProgress    = errors.New("a request with this idempotency key is still being processed, please retry later")
+	ErrIdempotencyKeyReused = errors.New("the idempotency key was already used for a different request")
+)
+
+// Idempotency replays the stored response to retries of a request instead of processing it again
+type Idempotency struct {
+	keys   repository.IIdempotencyRepository
+	window time.Duration
+	lease  time.Duration
+}
+
+// NewIdempotency deduplicates requests with the same key for window, a nil *Idempotency processes every request.
+// A request in progress holds its key for lease, so a request that crashed does not block its retries for the window
+func NewIdempotency(keys repository.IIdempotencyRepository, window time.Duration, lease time.Duration) *Idempotency {
+	return &Idempotency{keys: keys, window: window, lease: lease}
+}
+
+type idempotentResponse interface {
+	proto.Message
+	GetIsSuccess() bool
+}
+
+// idempotencyKey returns the key sent in the Idempotency-Key header, or the request id
+func idempotencyKey(header http.Header, requestId string) (string, error) {
+	key := header.Get(IDEMPOTENCY_KEY_HEADER)
+	if key == "" {
+		key = requestId
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("idempotency key must be at most %d characters long", maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// runIdempotent calls handle once per scope and key. Successful responses are stored and unmarshalled into replay
+// for retries, failed ones release the key so a retry is processed again.
+func runIdempotent[T idempotentResponse](ctx context.Context, i *Idempotency, scope string, header http.Header, requestId string, request proto.Message, replay T, handle func() T) (T, error) {
+	key, err := idempotencyKey(header, requestId)
+	if err != nil {
+		return replay, err
+	}
+	if i == nil || key == "" {
+		return handle(), nil
+	}
+	hash, err := requestHash(request)
+	if err != nil {
+		return replay, err
+	}
+	record, err := i.keys.Reserve(ctx, scope, key, hash, i.lease)
+	if err != nil {
+		return replay, err
+	}
+	if record != nil {
+		if !bytes.Equal(record.RequestHash, hash) {
+			return replay, ErrIdempotencyKeyReused
+		}
+		if record.Response == nil {
+			return replay, ErrRequestInProgress
+		}
+		return replay, proto.Unmarshal(record.Response, replay)
+	}
+	response := handle()
+	if !response.GetIsSuccess() {
+		if err = i.keys.Release(ctx, scope, key); err != nil {
+			slog.Warn("unable to release idempotency key", "key", key, "scope", scope, "error", err)
+		}
+		return response, nil
+	}
+	payload, err := proto.Marshal(response)
+	if err == nil {
+		err = i.keys.Complete(ctx, scope, key, payload, i.window)
+	}
+	if err != nil {
+		// the request was processed, releasing the key lets a retry process it again rather than wait for the lease
+		slog.Warn("unable to store response for idempotency key", "key", key, "scope", scope, "error", err)
+		if err = i.keys.Release(ctx, scope, key); err != nil {
+			slog.Warn("unable to release idempotency key", "key", key, "scope", scope, "error", err)
+		}
+	}
+	return response, nil
+}
+
+func requestHash(request proto.Message) ([]byte, error) {
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(payload)
+	return hash[:], nil
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/repository"
+	"auth-service/mocks"
+	"connectrpc.com/connect"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newIdempotentAuthServer(mockService *mocks.IAuthService) (*AuthServer, repository.IIdempotencyRepository) {
+	keys := repository.NewMemoryIdempotencyRepository(repository.NewMemoryStore())
+	return NewAuthServer(mockService, NewIdempotency(keys, time.Hour, time.Minute)), keys
+}
+
+func signupRequest(requestId string) *auth.SignupWithPhoneNumberRequest {
+	return &auth.SignupWithPhoneNumberRequest{
+		RequestId: requestId,
+		User:      &auth.User{Name: "John Doe", UserName: "johndoe", Email: "john@example.com", CountryCode: 91, PhoneNumber: "1234567890"},
+	}
+}
+
+func TestIdempotency_RetriedSignupReturnsTheOriginalResponse(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(&auth.User{Id: 42}, "delivery-1", nil).Once()
+	first, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.NoError(t, err)
+	retry, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.NoError(t, err)
+	assert.True(t, retry.Msg.IsSuccess)
+	assert.Equal(t, first.Msg.UserId, retry.Msg.UserId)
+	mockService.AssertNumberOfCalls(t, "HandleSignUp", 1)
+}
+
+func TestIdempotency_HeaderTakesPrecedenceOverRequestId(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	request := &auth.LoginWithPhoneNumberRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockService.On("LoginWithPhoneNumber", mock.Anything, mock.Anything).Return("delivery-1", nil)
+	for _, requestId := range []string{"first", "second"} {
+		request.RequestId = requestId
+		connectRequest := connect.NewRequest(request)
+		connectRequest.Header().Set(IDEMPOTENCY_KEY_HEADER, "header-key")
+		response, err := authServer.LoginWithPhoneNumber(context.Background(), connectRequest)
+		assert.NoError(t, err)
+		if requestId == "first" {
+			assert.True(t, response.Msg.IsSuccess)
+		} else {
+			// the same key with a different request id is a different request
+			assert.Equal(t, ERROR_CODE_IDEMPOTENCY_KEY_REUSED, response.Msg.Error.ErrorCode)
+		}
+	}
+	mockService.AssertNumberOfCalls(t, "LoginWithPhoneNumber", 1)
+}
+
+func TestIdempotency_KeyReusedForADifferentRequest(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(&auth.User{Id: 42}, "delivery-1", nil).Once()
+	_, _ = authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	other := signupRequest("request-1")
+	other.User.PhoneNumber = "9999999999"
+	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(other))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, ERROR_CODE_IDEMPOTENCY_KEY_REUSED, response.Msg.Error.ErrorCode)
+}
+
+func TestIdempotency_FailedRequestsAreProcessedAgain(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(nil, "", errors.New("db down")).Once()
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(&auth.User{Id: 42}, "delivery-1", nil).Once()
+	first, _ := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.False(t, first.Msg.IsSuccess)
+	retry, _ := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.True(t, retry.Msg.IsSuccess)
+	assert.Equal(t, int32(42), retry.Msg.UserId)
+	mockService.AssertNumberOfCalls(t, "HandleSignUp", 2)
+}
+
+func TestIdempotency_ConcurrentRetryIsRejectedAsInProgress(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, keys := newIdempotentAuthServer(mockService)
+	hash, err := requestHash(signupRequest("request-1"))
+	assert.NoError(t, err)
+	_, err = keys.Reserve(context.Background(), SIGNUP_SCOPE, "request-1", hash, time.Hour)
+	assert.NoError(t, err)
+	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.NoError(t, err)
+	assert.Equal(t, ERROR_CODE_REQUEST_IN_PROGRESS, response.Msg.Error.ErrorCode)
+	mockService.AssertNotCalled(t, "HandleSignUp", mock.Anything, mock.Anything)
+}
+
+func TestIdempotency_RequestsWithoutKeyAreNotDeduplicated(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(&auth.User{Id: 42}, "delivery-1", nil)
+	_, _ = authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("")))
+	_, _ = authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("")))
+	mockService.AssertNumberOfCalls(t, "HandleSignUp", 2)
+}
+
+func TestIdempotency_RejectsLongKeys(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	authServer, _ := newIdempotentAuthServer(mockService)
+	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest(strings.Repeat("k", maxIdempotencyKeyLength+1))))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	mockService.AssertNotCalled(t, "HandleSignUp", mock.Anything, mock.Anything)
+}
+
+func TestIdempotency_StoreFailureRejectsTheRequest(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	keys := &mocks.IIdempotencyRepository{}
+	authServer := NewAuthServer(mockService, NewIdempotency(keys, time.Hour, time.Minute))
+	keys.On("Reserve", mock.Anything, SIGNUP_SCOPE, "request-1", mock.Anything, time.Minute).Return(nil, errors.New("db down"))
+	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.NoError(t, err)
+	assert.False(t, response.Msg.IsSuccess)
+	assert.Equal(t, "db down", response.Msg.Error.Message)
+	mockService.AssertNotCalled(t, "HandleSignUp", mock.Anything, mock.Anything)
+}
+
+func TestIdempotency_KeyIsReleasedWhenTheResponseCannotBeStored(t *testing.T) {
+	mockService := &mocks.IAuthService{}
+	keys := &mocks.IIdempotencyRepository{}
+	authServer := NewAuthServer(mockService, NewIdempotency(keys, time.Hour, time.Minute))
+	keys.On("Reserve", mock.Anything, SIGNUP_SCOPE, "request-1", mock.Anything, time.Minute).Return(nil, nil)
+	keys.On("Complete", mock.Anything, SIGNUP_SCOPE, "request-1", mock.Anything, time.Hour).Return(errors.New("db down"))
+	keys.On("Release", mock.Anything, SIGNUP_SCOPE, "request-1").Return(nil)
+	mockService.On("HandleSignUp", mock.Anything, mock.Anything).Return(&auth.User{Id: 42}, "delivery-1", nil)
+	response, err := authServer.SignupWithPhoneNumber(context.Background(), connect.NewRequest(signupRequest("request-1")))
+	assert.NoError(t, err)
+	assert.True(t, response.Msg.IsSuccess)
+	keys.AssertCalled(t, "Release", mock.Anything, SIGNUP_SCOPE, "request-1")
+}
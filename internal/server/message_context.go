@@ -0,0 +1,65 @@
+package server
+
+import (
+	"auth-service/internal/gateway"
+	"connectrpc.com/connect"
+	"context"
+	"regexp"
+)
+
+// Headers of rpcs carried to the otp requests they publish
+const (
+	// CORRELATION_ID_HEADER takes precedence over the requestId field of the request
+	CORRELATION_ID_HEADER = "X-Correlation-Id"
+	TRACEPARENT_HEADER    = "traceparent"
+	TRACESTATE_HEADER     = "tracestate"
+)
+
+const (
+	maxCorrelationIdLength = 255
+	maxTraceStateLength    = 512
+)
+
+// traceParentPattern matches a W3C traceparent, the all zero trace and parent ids are invalid
+var traceParentPattern = regexp.MustCompile(`^[0-9a-f]{2}-[0-9a-f]{32}-[0-9a-f]{16}-[0-9a-f]{2}$`)
+
+type requestWithId interface {
+	GetRequestId() string
+}
+
+// NewMessageContextInterceptor puts the correlation id and trace context of every rpc in its context, the messages
+// it publishes carry them to their consumers
+func NewMessageContextInterceptor() connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+			return next(gateway.WithMessageContext(ctx, messageContext(request)), request)
+		}
+	}
+}
+
+// messageContext drops headers that are malformed or too long instead of failing the rpc
+func messageContext(request connect.AnyRequest) gateway.MessageContext {
+	correlationId := request.Header().Get(CORRELATION_ID_HEADER)
+	if withId, ok := request.Any().(requestWithId); ok && correlationId == "" {
+		correlationId = withId.GetRequestId()
+	}
+	if len(correlationId) > maxCorrelationIdLength {
+		correlationId = ""
+	}
+	traceParent := request.Header().Get(TRACEPARENT_HEADER)
+	if !validTraceParent(traceParent) {
+		return gateway.MessageContext{CorrelationId: correlationId}
+	}
+	traceState := request.Header().Get(TRACESTATE_HEADER)
+	if len(traceState) > maxTraceStateLength {
+		traceState = ""
+	}
+	return gateway.MessageContext{CorrelationId: correlationId, TraceParent: traceParent, TraceState: traceState}
+}
+
+func validTraceParent(traceParent string) bool {
+	if !traceParentPattern.MatchString(traceParent) || traceParent[:2] == "ff" {
+		return false
+	}
+	return traceParent[3:35] != "00000000000000000000000000000000" && traceParent[36:52] != "0000000000000000"
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"auth-service/internal/gateway"
+	auth "auth-service/internal/gen/auth/v1"
+	"connectrpc.com/connect"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func interceptedMessageContext(t *testing.T, request connect.AnyRequest) gateway.MessageContext {
+	var messageContext gateway.MessageContext
+	handler := NewMessageContextInterceptor()(func(ctx context.Context, request connect.AnyRequest) (connect.AnyResponse, error) {
+		messageContext = gateway.MessageContextFrom(ctx)
+		return nil, nil
+	})
+	_, err := handler(context.Background(), request)
+	assert.NoError(t, err)
+	return messageContext
+}
+
+func TestMessageContextInterceptor_CarriesHeaders(t *testing.T) {
+	request := connect.NewRequest(&auth.ResendOtpRequest{RequestId: "123"})
+	request.Header().Set(CORRELATION_ID_HEADER, "rpc-1")
+	request.Header().Set(TRACEPARENT_HEADER, testTraceParent)
+	request.Header().Set(TRACESTATE_HEADER, "vendor=1")
+	assert.Equal(t, gateway.MessageContext{CorrelationId: "rpc-1", TraceParent: testTraceParent, TraceState: "vendor=1"}, interceptedMessageContext(t, request))
+}
+
+func TestMessageContextInterceptor_CorrelatesByRequestId(t *testing.T) {
+	request := connect.NewRequest(&auth.ResendOtpRequest{RequestId: "123"})
+	assert.Equal(t, gateway.MessageContext{CorrelationId: "123"}, interceptedMessageContext(t, request))
+}
+
+func TestMessageContextInterceptor_DropsMalformedHeaders(t *testing.T) {
+	for name, traceParent := range map[string]string{
+		"malformed":       "00-4bf92f3577b34da6-00f067aa0ba902b7-01",
+		"invalid version": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"zero trace id":   "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero parent id":  "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	} {
+		request := connect.NewRequest(&auth.GetProfileRequest{})
+		request.Header().Set(CORRELATION_ID_HEADER, strings.Repeat("a", 256))
+		request.Header().Set(TRACEPARENT_HEADER, traceParent)
+		request.Header().Set(TRACESTATE_HEADER, "vendor=1")
+		assert.Equal(t, gateway.MessageContext{}, interceptedMessageContext(t, request), name)
+	}
+}
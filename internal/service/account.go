@@ -0,0 +1,129 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	ErrAccountDeleted    = errors.New("the account is scheduled for deletion, restore it to login")
+	ErrAccountNotDeleted = errors.New("the account is not scheduled for deletion")
+	ErrRestorePeriodOver = errors.New("the account can no longer be restored")
+)
+
+// DeleteAccount schedules the account for deletion after the otp of its phone number is confirmed. The account is
+// hidden right away and purged once the deletion grace period is over, RestoreAccount undoes it until then.
+func (a authService) DeleteAccount(ctx context.Context, request *auth.DeleteAccountRequest) (time.Time, error) {
+	err := a.ValidateDeleteAccountRequest(request)
+	if err != nil {
+		return time.Time{}, err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	err = a.confirmPhoneOtp(ctx, user, request.Otp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	deleted, err := a.SetUserStatus(ctx, user.Id, models.USER_STATUS_DELETED, user.Version)
+	if err != nil {
+		return time.Time{}, err
+	}
+	a.InsertEvent(ctx, string(ACCOUNT_DELETED), deleted.PhoneNumber)
+	return deleted.DeletedAt.Add(a.config.Load().DeletionGracePeriod), nil
+}
+
+// RestoreAccount reactivates an account scheduled for deletion. Deleted accounts can still request login otps,
+// so the otp of the phone number proves the ownership like for a login.
+func (a authService) RestoreAccount(ctx context.Context, request *auth.RestoreAccountRequest) (*auth.User, error) {
+	err := a.ValidateRestoreAccountRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, request.CountryCode, request.PhoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsDeleted() {
+		return nil, ErrAccountNotDeleted
+	}
+	if time.Since(user.DeletedAt) > a.config.Load().DeletionGracePeriod {
+		return nil, ErrRestorePeriodOver
+	}
+	err = a.confirmPhoneOtp(ctx, user, request.Otp)
+	if err != nil {
+		return nil, err
+	}
+	restored, err := a.SetUserStatus(ctx, user.Id, models.USER_STATUS_ACTIVE, user.Version)
+	if err != nil {
+		return nil, err
+	}
+	a.InsertEvent(ctx, string(ACCOUNT_RESTORED), restored.PhoneNumber)
+	return models.ToProto(restored), nil
+}
+
+// confirmPhoneOtp checks an otp sent to the phone number of the user, logging INCORRECT_OTP when it does not match
+func (a authService) confirmPhoneOtp(ctx context.Context, user *models.User, otp int32) error {
+	matches, err := a.checkOtp(ctx, user.PhoneNumber, otp)
+	if err != nil {
+		return errors.New("unable to verify the OTP, Please try again after some time")
+	}
+	if !matches {
+		a.InsertEvent(ctx, string(INCORRECT_OTP), user.PhoneNumber)
+		return errors.New("invalid OTP")
+	}
+	return nil
+}
+
+// checkOtp reports if the otp is the otp sent to the subject, issued by the auth service in server mode and derived
+// from the subject otherwise, with the current or a previous secret key
+func (a authService) checkOtp(ctx context.Context, subject string, otp int32) (bool, error) {
+	if a.codes != nil {
+		return a.codes.Verify(ctx, subject, otp)
+	}
+	generatedOtp, err := a.Generate(subject)
+	if err != nil {
+		return false, err
+	}
+	if generatedOtp == otp {
+		return true, nil
+	}
+	rotated, ok := a.IGenerator.(IRotatedGenerator)
+	if !ok {
+		return false, nil
+	}
+	previousOtps, err := rotated.GeneratePrevious(subject)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(previousOtps, otp), nil
+}
+
+// getActiveUser hides users scheduled for deletion as if they did not exist
+func (a authService) getActiveUser(ctx context.Context, userId int32) (*models.User, error) {
+	user, err := a.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, fmt.Errorf("user with id %d not found", userId)
+	}
+	return user, nil
+}
+
+func (a authService) getActiveUserByPhone(ctx context.Context, countryCode int32, phoneNumber string) (*models.User, error) {
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, countryCode, phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, fmt.Errorf("user with country code %d and phone number %s not found", countryCode, phoneNumber)
+	}
+	return user, nil
+}
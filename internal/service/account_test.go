@@ -0,0 +1,104 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/models"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func deletedProfile(deletedAt time.Time) *models.User {
+	user := storedProfile()
+	user.Status = models.USER_STATUS_DELETED
+	user.DeletedAt = deletedAt
+	return user
+}
+
+func TestDeleteAccount_SchedulesTheDeletion(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.DeleteAccountRequest{UserId: 1, Otp: 654321}
+	deletedAt := time.Now()
+	mockValidator.On("ValidateDeleteAccountRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	mockUserRepo.On("SetUserStatus", mock.Anything, int32(1), models.USER_STATUS_DELETED, int64(3)).Return(deletedProfile(deletedAt), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(ACCOUNT_DELETED), "1234567890").Return()
+	restorableUntil, err := authService.DeleteAccount(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, deletedAt.Add(30*24*time.Hour), restorableUntil)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDeleteAccount_InvalidOtp(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.DeleteAccountRequest{UserId: 1, Otp: 111111}
+	mockValidator.On("ValidateDeleteAccountRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, err := authService.DeleteAccount(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockUserRepo.AssertNotCalled(t, "SetUserStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeletedAccountsAreHidden(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(deletedProfile(time.Now()), nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(deletedProfile(time.Now()), nil)
+	byPhone := &auth.GetProfileByPhoneNumberRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateGetProfileByMobileNumberRequest", byPhone).Return(nil)
+
+	_, err := authService.GetUserProfile(context.Background(), &auth.GetProfileRequest{UserId: 1})
+	assert.EqualError(t, err, "user with id 1 not found")
+	_, err = authService.GetUserProfileByPhone(context.Background(), byPhone)
+	assert.EqualError(t, err, "user with country code 91 and phone number 1234567890 not found")
+}
+
+func TestValidatePhoneNumberLogin_DeletedAccount(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ValidatePhoneNumberLoginRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 654321}
+	mockValidator.On("ValidatePhoneNumberLogin", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(deletedProfile(time.Now()), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
+	assert.ErrorIs(t, err, ErrAccountDeleted)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, string(LOGIN_SUCCESSFUL), mock.Anything)
+}
+
+func TestRestoreAccount_WithinTheGracePeriod(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.RestoreAccountRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 654321}
+	mockValidator.On("ValidateRestoreAccountRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(deletedProfile(time.Now().Add(-24*time.Hour)), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	restored := storedProfile()
+	restored.Status, restored.Version = models.USER_STATUS_ACTIVE, 4
+	mockUserRepo.On("SetUserStatus", mock.Anything, int32(1), models.USER_STATUS_ACTIVE, int64(3)).Return(restored, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(ACCOUNT_RESTORED), "1234567890").Return()
+	user, err := authService.RestoreAccount(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), user.Version)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestRestoreAccount_AfterTheGracePeriod(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, _, authService := setupAuthServiceMocks(t)
+	request := &auth.RestoreAccountRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 654321}
+	mockValidator.On("ValidateRestoreAccountRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(deletedProfile(time.Now().Add(-31*24*time.Hour)), nil)
+	_, err := authService.RestoreAccount(context.Background(), request)
+	assert.ErrorIs(t, err, ErrRestorePeriodOver)
+	mockGenerator.AssertNotCalled(t, "Generate", mock.Anything)
+}
+
+func TestRestoreAccount_ActiveAccount(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.RestoreAccountRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 654321}
+	mockValidator.On("ValidateRestoreAccountRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(storedProfile(), nil)
+	_, err := authService.RestoreAccount(context.Background(), request)
+	assert.ErrorIs(t, err, ErrAccountNotDeleted)
+}
@@ -7,8 +7,10 @@ import (
 	"auth-service/internal/models"
 	"auth-service/internal/repository"
 	"auth-service/internal/validators"
+	"context"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type UserEvents string
@@ -21,15 +23,63 @@ const (
 	LOGIN_REQUEST            UserEvents = "LOGIN_REQUEST"
 	LOGIN_SUCCESSFUL         UserEvents = "LOGIN"
 	LOGOUT                   UserEvents = "LOGOUT"
+	OTP_RESENT               UserEvents = "OTP_RESENT"
+	PROFILE_UPDATED          UserEvents = "PROFILE_UPDATED"
+	EMAIL_CHANGE_REQUESTED   UserEvents = "EMAIL_CHANGE_REQUESTED"
+	EMAIL_CHANGED            UserEvents = "EMAIL_CHANGED"
+	PHONE_CHANGE_REQUESTED   UserEvents = "PHONE_CHANGE_REQUESTED"
+	PHONE_CHANGED            UserEvents = "PHONE_CHANGED"
+	ACCOUNT_DELETED          UserEvents = "ACCOUNT_DELETED"
+	ACCOUNT_RESTORED         UserEvents = "ACCOUNT_RESTORED"
+	DATA_EXPORT_REQUESTED    UserEvents = "DATA_EXPORT_REQUESTED"
 )
 
+// ErrPhoneNumberRegistered steers users signing up with a known phone number to the login flow
+var ErrPhoneNumberRegistered = errors.New("an account with this phone number already exists, please login instead")
+
 type IAuthService interface {
-	HandleSignUp(*auth.SignupWithPhoneNumberRequest) (*auth.User, error)
-	GetUserProfile(*auth.GetProfileRequest) (*auth.User, error)
-	GetUserProfileByPhone(*auth.GetProfileByPhoneNumberRequest) (*auth.User, error)
-	VerifyOtp(request *auth.VerifyPhoneNumberRequest) error
-	LoginWithPhoneNumber(request *auth.LoginWithPhoneNumberRequest) error
-	ValidatePhoneNumberLogin(request *auth.ValidatePhoneNumberLoginRequest) error
+	// HandleSignUp registers the user and returns the delivery id of the otp sent to them
+	HandleSignUp(ctx context.Context, request *auth.SignupWithPhoneNumberRequest) (*auth.User, string, error)
+	GetUserProfile(ctx context.Context, request *auth.GetProfileRequest) (*auth.User, error)
+	GetUserProfileByPhone(ctx context.Context, request *auth.GetProfileByPhoneNumberRequest) (*auth.User, error)
+	VerifyOtp(ctx context.Context, request *auth.VerifyPhoneNumberRequest) error
+	// LoginWithPhoneNumber sends a login otp to the phone number and returns its delivery id
+	LoginWithPhoneNumber(ctx context.Context, request *auth.LoginWithPhoneNumberRequest) (string, error)
+	ValidatePhoneNumberLogin(ctx context.Context, request *auth.ValidatePhoneNumberLoginRequest) error
+	CheckUsernameAvailability(ctx context.Context, request *auth.CheckUsernameAvailabilityRequest) (bool, []string, error)
+	// ResendOtp returns the channel the otp was resent with and its delivery id
+	ResendOtp(ctx context.Context, request *auth.ResendOtpRequest) (otp.DeliveryChannel, string, error)
+	UpdateProfile(ctx context.Context, request *auth.UpdateProfileRequest) (*auth.User, error)
+	ConfirmEmailChange(ctx context.Context, request *auth.ConfirmEmailChangeRequest) (*auth.User, error)
+	// StartPhoneChange returns the user with the pending phone number and the delivery id of the otp sent to it
+	StartPhoneChange(ctx context.Context, request *auth.StartPhoneChangeRequest) (*auth.User, string, error)
+	ConfirmPhoneChange(ctx context.Context, request *auth.ConfirmPhoneChangeRequest) (*auth.User, error)
+	// DeleteAccount returns the time until which the account can be restored
+	DeleteAccount(ctx context.Context, request *auth.DeleteAccountRequest) (time.Time, error)
+	RestoreAccount(ctx context.Context, request *auth.RestoreAccountRequest) (*auth.User, error)
+	ExportMyData(ctx context.Context, request *auth.ExportMyDataRequest) (*models.DataExportTicket, error)
+	DownloadMyData(ctx context.Context, request *auth.DownloadMyDataRequest) (*models.DataExport, error)
+	GetOtpDeliveryStatus(ctx context.Context, request *auth.GetOtpDeliveryStatusRequest) (*models.OtpDelivery, error)
+}
+
+type AuthServiceConfig struct {
+	// ResendCooldown is the minimum time between two otps sent to the same phone number
+	ResendCooldown time.Duration
+	// ResendWindow is how far back otp requests and resends are considered, usually the otp validity
+	ResendWindow time.Duration
+	// MaxResends limits the resends after a signup or login otp request within the window
+	MaxResends int
+	// EmailEscalationAge is how long an email has to be confirmed before resends escalate to it
+	EmailEscalationAge time.Duration
+	// UnverifiedUserTTL is how long an unverified signup holds its user name, email and phone number,
+	// signing up again after it takes over the abandoned record
+	UnverifiedUserTTL time.Duration
+	// FreshLoginWindow is how recent a login has to be for sensitive changes like the phone number
+	FreshLoginWindow time.Duration
+	// DeletionGracePeriod is how long a deleted account can be restored before it is purged
+	DeletionGracePeriod time.Duration
+	// DataExportTTL is how long a data export can be downloaded after it was requested
+	DataExportTTL time.Duration
 }
 
 type authService struct {
@@ -38,124 +88,218 @@ type authService struct {
 	publisher gateway.IMessagePublisher
 	IGenerator
 	repository.IEventRepository
+	exports    repository.IDataExportRepository
+	deliveries repository.IOtpDeliveryRepository
+	// codes verifies the otps issued in server mode, otps are derived with the generator when it is nil
+	codes IOtpCodes
+	// config is swapped when the config is reloaded
+	config *Tunable[AuthServiceConfig]
 }
 
-func (a authService) HandleSignUp(request *auth.SignupWithPhoneNumberRequest) (*auth.User, error) {
+func (a authService) HandleSignUp(ctx context.Context, request *auth.SignupWithPhoneNumberRequest) (*auth.User, string, error) {
 	err := a.ValidateSignupWithPhoneNumberRequest(request)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	user := models.ToUser(request)
-	savedUser, err := a.SaveUser(user)
+	user.Email, user.CanonicalEmail = a.NormalizeEmail(user.Email)
+	message, deliveryId, err := newOtpOutboxMessage(ctx, user)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	err = a.publishMessageForOtp(savedUser)
+	// the otp request is delivered by the outbox relay, so a broker outage can not leave a user without an otp
+	savedUser, err := a.RegisterUser(ctx, repository.Registration{
+		User:                   user,
+		Event:                  string(SIGN_IN_REQUEST_OTP),
+		Outbox:                 message,
+		ReclaimUnverifiedAfter: a.config.Load().UnverifiedUserTTL,
+	})
 	if err != nil {
-		return nil, err
+		var alreadyExists *models.AlreadyExistsError
+		if errors.As(err, &alreadyExists) && alreadyExists.Field == models.FIELD_PHONE_NUMBER {
+			return nil, "", ErrPhoneNumberRegistered
+		}
+		return nil, "", err
 	}
-	a.InsertEvent(string(SIGN_IN_REQUEST_OTP), savedUser.PhoneNumber)
-	return models.ToProto(savedUser), nil
+	return models.ToProto(savedUser), deliveryId, nil
 }
 
-func (a authService) GetUserProfile(request *auth.GetProfileRequest) (*auth.User, error) {
-	user, err := a.GetUser(request.UserId)
+func (a authService) GetUserProfile(ctx context.Context, request *auth.GetProfileRequest) (*auth.User, error) {
+	user, err := a.getActiveUser(ctx, request.UserId)
 	if err != nil {
 		return nil, err
 	}
 	return models.ToProto(user), nil
 }
 
-func (a authService) GetUserProfileByPhone(request *auth.GetProfileByPhoneNumberRequest) (*auth.User, error) {
+func (a authService) GetUserProfileByPhone(ctx context.Context, request *auth.GetProfileByPhoneNumberRequest) (*auth.User, error) {
 	err := a.ValidateGetProfileByMobileNumberRequest(request)
 	if err != nil {
 		return nil, err
 	}
-	user, err := a.GetUserByPhoneNumberAndCountry(request.CountryCode, request.PhoneNumber)
+	user, err := a.getActiveUserByPhone(ctx, request.CountryCode, request.PhoneNumber)
 	if err != nil {
 		return nil, err
 	}
 	return models.ToProto(user), nil
 }
 
-func (a authService) VerifyOtp(request *auth.VerifyPhoneNumberRequest) error {
+func (a authService) VerifyOtp(ctx context.Context, request *auth.VerifyPhoneNumberRequest) error {
 	err := a.ValidateVerifyPhoneNumberRequest(request)
 	if err != nil {
 		return err
 	}
-	user, err := a.GetUserByPhoneNumberAndCountry(request.CountryCode, request.PhoneNumber)
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, request.CountryCode, request.PhoneNumber)
 	if err != nil {
 		return err
 	}
 	if user == nil {
 		return errors.New(fmt.Sprintf("No user registered with %s", request.PhoneNumber))
 	}
-	generatedOtp, err := a.Generate(request.PhoneNumber)
+	matches, err := a.checkOtp(ctx, request.PhoneNumber, request.Otp)
 	if err != nil {
 		return errors.New("unable to verify the OTP, Please try again after some time")
 	}
-	if generatedOtp != request.Otp {
-		a.InsertEvent(string(INCORRECT_OTP), user.PhoneNumber)
+	if !matches {
+		a.InsertEvent(ctx, string(INCORRECT_OTP), user.PhoneNumber)
 		return errors.New("invalid OTP")
 	}
-	err = a.MarkVerified(user.Id)
+	err = a.MarkVerified(ctx, user.Id)
 	if err != nil {
 		return err
 	}
-	a.InsertEvent(string(PHONE_VERIFIED), user.PhoneNumber)
+	a.InsertEvent(ctx, string(PHONE_VERIFIED), user.PhoneNumber)
 	return nil
 }
 
-func (a authService) LoginWithPhoneNumber(request *auth.LoginWithPhoneNumberRequest) error {
+func (a authService) LoginWithPhoneNumber(ctx context.Context, request *auth.LoginWithPhoneNumberRequest) (string, error) {
 	err := a.ValidateLoginWithPhoneNumberRequest(request)
 	if err != nil {
-		return err
+		return "", err
 	}
-	user, err := a.GetUserByPhoneNumberAndCountry(request.CountryCode, request.PhoneNumber)
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, request.CountryCode, request.PhoneNumber)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if !user.Verified {
-		a.InsertEvent(string(UNVERIFIED_LOGIN_ATTEMPT), user.PhoneNumber)
-		return fmt.Errorf("verify phone number to login")
+		a.InsertEvent(ctx, string(UNVERIFIED_LOGIN_ATTEMPT), user.PhoneNumber)
+		return "", fmt.Errorf("verify phone number to login")
 	}
-	err = a.publishMessageForOtp(user)
+	deliveryId, err := a.publishOtp(ctx, newOtpRequest(user))
 	if err != nil {
-		return err
+		return "", err
 	}
-	a.InsertEvent(string(LOGIN_REQUEST), user.PhoneNumber)
-	return nil
+	a.InsertEvent(ctx, string(LOGIN_REQUEST), user.PhoneNumber)
+	return deliveryId, nil
 }
 
-func (a authService) ValidatePhoneNumberLogin(request *auth.ValidatePhoneNumberLoginRequest) error {
+func (a authService) ValidatePhoneNumberLogin(ctx context.Context, request *auth.ValidatePhoneNumberLoginRequest) error {
 	err := a.IRequestValidator.ValidatePhoneNumberLogin(request)
 	if err != nil {
 		return err
 	}
-	user, err := a.GetUserByPhoneNumberAndCountry(request.CountryCode, request.PhoneNumber)
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, request.CountryCode, request.PhoneNumber)
 	if err != nil {
 		return err
 	}
-	generatedOtp, err := a.Generate(request.PhoneNumber)
+	matches, err := a.checkOtp(ctx, request.PhoneNumber, request.Otp)
 	if err != nil {
 		return errors.New("unable to verify the OTP, Please try again after some time")
 	}
-	if generatedOtp != request.Otp {
-		a.InsertEvent(string(INCORRECT_OTP), user.PhoneNumber)
+	if !matches {
+		a.InsertEvent(ctx, string(INCORRECT_OTP), user.PhoneNumber)
 		return errors.New("invalid OTP")
 	}
-	a.InsertEvent(string(LOGIN_SUCCESSFUL), user.PhoneNumber)
+	if user.IsDeleted() {
+		// checked after the otp, so the deletion is only revealed to the owner of the phone number
+		return ErrAccountDeleted
+	}
+	a.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), user.PhoneNumber)
 	return nil
 }
 
-func (a authService) publishMessageForOtp(user *models.User) error {
-	request := &otp.GenerateOTPRequest{
+func (a authService) CheckUsernameAvailability(ctx context.Context, request *auth.CheckUsernameAvailabilityRequest) (bool, []string, error) {
+	err := a.ValidateCheckUsernameAvailabilityRequest(request)
+	if err != nil {
+		return false, nil, err
+	}
+	taken, err := a.IsUserNameTaken(ctx, request.UserName)
+	if err != nil {
+		return false, nil, err
+	}
+	if !taken {
+		return true, nil, nil
+	}
+	suggestions := make([]string, 0, maxUserNameSuggestions)
+	for _, candidate := range userNameCandidates(request.UserName, maxUserNameSuggestionTry) {
+		candidateTaken, err := a.IsUserNameTaken(ctx, candidate)
+		if err != nil {
+			return false, nil, err
+		}
+		if !candidateTaken {
+			suggestions = append(suggestions, candidate)
+		}
+		if len(suggestions) == maxUserNameSuggestions {
+			break
+		}
+	}
+	return false, suggestions, nil
+}
+
+func (a authService) ResendOtp(ctx context.Context, request *auth.ResendOtpRequest) (otp.DeliveryChannel, string, error) {
+	err := a.ValidateResendOtpRequest(request)
+	if err != nil {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", err
+	}
+	user, err := a.GetUserByPhoneNumberAndCountry(ctx, request.CountryCode, request.PhoneNumber)
+	if err != nil {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", err
+	}
+	config := a.config.Load()
+	events, err := a.ListRecentEvents(ctx, user.PhoneNumber, config.ResendWindow)
+	if err != nil {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", err
+	}
+	channel, err := config.nextResendChannel(user, events, time.Now())
+	if err != nil {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", err
+	}
+	otpRequest := newOtpRequest(user)
+	otpRequest.Channel = channel
+	if channel == otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL {
+		otpRequest.Email = user.Email
+	}
+	deliveryId, err := a.publishOtp(ctx, otpRequest)
+	if err != nil {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, "", err
+	}
+	a.InsertEvent(ctx, string(OTP_RESENT), user.PhoneNumber)
+	return channel, deliveryId, nil
+}
+
+// publishOtp publishes the otp request under a new request id and returns it as the delivery id, which callers of
+// GetOtpDeliveryStatus have to hold
+func (a authService) publishOtp(ctx context.Context, request *otp.GenerateOTPRequest) (string, error) {
+	deliveryId, err := gateway.NewMessageId()
+	if err != nil {
+		return "", err
+	}
+	request.RequestId = deliveryId
+	err = a.publisher.Publish(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return deliveryId, nil
+}
+
+func newOtpRequest(user *models.User) *otp.GenerateOTPRequest {
+	return &otp.GenerateOTPRequest{
 		CountryCode: user.CountryCode,
 		PhoneNumber: user.PhoneNumber,
+		Channel:     otp.DeliveryChannel_DELIVERY_CHANNEL_SMS,
 	}
-	return a.publisher.Publish(request)
 }
 
-func NewAuthService(userRepository repository.IUserRepository, validator validators.IRequestValidator, publisher gateway.IMessagePublisher, generator IGenerator, eventRepository repository.IEventRepository) IAuthService {
-	return &authService{IUserRepository: userRepository, IRequestValidator: validator, publisher: publisher, IGenerator: generator, IEventRepository: eventRepository}
+func NewAuthService(userRepository repository.IUserRepository, validator validators.IRequestValidator, publisher gateway.IMessagePublisher, generator IGenerator, eventRepository repository.IEventRepository, exportRepository repository.IDataExportRepository, deliveryRepository repository.IOtpDeliveryRepository, codes IOtpCodes, config *Tunable[AuthServiceConfig]) IAuthService {
+	return &authService{IUserRepository: userRepository, IRequestValidator: validator, publisher: publisher, IGenerator: generator, IEventRepository: eventRepository, exports: exportRepository, deliveries: deliveryRepository, codes: codes, config: config}
 }
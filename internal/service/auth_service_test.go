@@ -1,14 +1,20 @@
 package service
 
 import (
+	"auth-service/internal/gateway"
 	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
 	"auth-service/internal/models"
+	"auth-service/internal/repository"
 	"auth-service/mocks"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/proto"
 	"testing"
+	"time"
 )
 
 func TestHandleSignUpSuccess(t *testing.T) {
@@ -22,20 +28,30 @@ func TestHandleSignUpSuccess(t *testing.T) {
 	}
 	request := &auth.SignupWithPhoneNumberRequest{User: user}
 	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("SaveUser", mock.Anything).Return(models.ToUser(request), nil)
-	mockPublisher.On("Publish", mock.Anything).Return(nil)
-	mockEventRepo.On("InsertEvent", string(SIGN_IN_REQUEST_OTP), user.PhoneNumber).Return(nil)
-	user, err := authService.HandleSignUp(request)
+	mockValidator.On("NormalizeEmail", "john@example.com").Return("john@example.com", "john@example.com")
+	mockUserRepo.On("RegisterUser", mock.Anything, mock.MatchedBy(func(registration repository.Registration) bool {
+		otpRequest := &otp.GenerateOTPRequest{}
+		if err := proto.Unmarshal(registration.Outbox.Payload, otpRequest); err != nil {
+			return false
+		}
+		return registration.Event == string(SIGN_IN_REQUEST_OTP) &&
+			registration.ReclaimUnverifiedAfter == 24*time.Hour &&
+			registration.Outbox.Topic == OTP_REQUEST_TOPIC &&
+			otpRequest.PhoneNumber == "1234567890" && otpRequest.CountryCode == 91
+	})).Return(models.ToUser(request), nil)
+	user, deliveryId, err := authService.HandleSignUp(context.Background(), request)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, request.User.PhoneNumber, user.PhoneNumber)
+	registration := mockUserRepo.Calls[0].Arguments.Get(1).(repository.Registration)
+	outboxRequest := &otp.GenerateOTPRequest{}
+	assert.NoError(t, proto.Unmarshal(registration.Outbox.Payload, outboxRequest))
+	assert.Equal(t, outboxRequest.RequestId, deliveryId)
 	mockValidator.AssertCalled(t, "ValidateSignupWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "SaveUser", mock.Anything)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(SIGN_IN_REQUEST_OTP), user.PhoneNumber)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
-	mockPublisher.AssertExpectations(t)
-	mockEventRepo.AssertExpectations(t)
 }
 
 func TestHandleSignUp_ValidationFailure(t *testing.T) {
@@ -44,7 +60,7 @@ func TestHandleSignUp_ValidationFailure(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	user := &auth.User{
 		Name:        "John Doe",
 		UserName:    "johndoe",
@@ -55,13 +71,13 @@ func TestHandleSignUp_ValidationFailure(t *testing.T) {
 	request := &auth.SignupWithPhoneNumberRequest{User: user}
 	expectedErr := errors.New("validation error")
 	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(expectedErr)
-	user, err := authService.HandleSignUp(request)
+	user, _, err := authService.HandleSignUp(context.Background(), request)
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	mockValidator.AssertCalled(t, "ValidateSignupWithPhoneNumberRequest", request)
-	mockUserRepo.AssertNotCalled(t, "SaveUser", mock.Anything)
-	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
-	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "RegisterUser", mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
@@ -75,7 +91,7 @@ func TestHandleSignUp_UserSavingFailure(t *testing.T) {
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
 
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 
 	user := &auth.User{
 		Name:        "John Doe",
@@ -86,58 +102,28 @@ func TestHandleSignUp_UserSavingFailure(t *testing.T) {
 	}
 	request := &auth.SignupWithPhoneNumberRequest{User: user}
 	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "john@example.com").Return("john@example.com", "john@example.com")
 	expectedErr := errors.New("user saving error")
-	mockUserRepo.On("SaveUser", mock.Anything).Return(nil, expectedErr)
-	user, err := authService.HandleSignUp(request)
+	mockUserRepo.On("RegisterUser", mock.Anything, mock.Anything).Return(nil, expectedErr)
+	user, _, err := authService.HandleSignUp(context.Background(), request)
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	mockValidator.AssertCalled(t, "ValidateSignupWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "SaveUser", mock.Anything)
-	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
-	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything)
+	mockUserRepo.AssertCalled(t, "RegisterUser", mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
 	mockEventRepo.AssertExpectations(t)
 }
-func TestHandleSignUp_PublishMessageFailure(t *testing.T) {
-	mockValidator := &mocks.IRequestValidator{}
-	mockUserRepo := &mocks.IUserRepository{}
-	mockPublisher := &mocks.IMessagePublisher{}
-	mockGenerator := &mocks.IGenerator{}
-	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
-	user := &auth.User{
-		Name:        "John Doe",
-		UserName:    "johndoe",
-		Email:       "john@example.com",
-		PhoneNumber: "1234567890",
-		CountryCode: 91,
-	}
-	request := &auth.SignupWithPhoneNumberRequest{User: user}
-	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("SaveUser", mock.Anything).Return(models.ToUser(request), nil)
-	expectedErr := errors.New("publish message error")
-	mockPublisher.On("Publish", mock.Anything).Return(expectedErr)
-	user, err := authService.HandleSignUp(request)
-	assert.Error(t, err)
-	assert.Nil(t, user)
-	mockValidator.AssertCalled(t, "ValidateSignupWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "SaveUser", mock.Anything)
-	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything)
-	mockValidator.AssertExpectations(t)
-	mockUserRepo.AssertExpectations(t)
-	mockPublisher.AssertExpectations(t)
-	mockEventRepo.AssertExpectations(t)
-}
-
 func TestGetUserProfile_Success(t *testing.T) {
 	mockUserRepo := &mocks.IUserRepository{}
 	mockValidator := &mocks.IRequestValidator{}
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	mockUser := &models.User{
 		Id:          1,
 		Name:        "John Doe",
@@ -151,8 +137,8 @@ func TestGetUserProfile_Success(t *testing.T) {
 		RequestId: "123",
 		UserId:    1,
 	}
-	mockUserRepo.On("GetUser", request.UserId).Return(mockUser, nil)
-	user, err := authService.GetUserProfile(request)
+	mockUserRepo.On("GetUser", mock.Anything, request.UserId).Return(mockUser, nil)
+	user, err := authService.GetUserProfile(context.Background(), request)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	expectedUser := &auth.User{
@@ -165,7 +151,7 @@ func TestGetUserProfile_Success(t *testing.T) {
 		PhoneNumber: mockUser.PhoneNumber,
 	}
 	assert.Equal(t, expectedUser, user)
-	mockUserRepo.AssertCalled(t, "GetUser", request.UserId)
+	mockUserRepo.AssertCalled(t, "GetUser", mock.Anything, request.UserId)
 	mockValidator.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -178,18 +164,18 @@ func TestGetUserProfile_Failure(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.GetProfileRequest{
 		RequestId: "123",
 		UserId:    1,
 	}
 	expectedError := errors.New("failed to get user")
-	mockUserRepo.On("GetUser", request.UserId).Return(nil, expectedError)
-	user, err := authService.GetUserProfile(request)
+	mockUserRepo.On("GetUser", mock.Anything, request.UserId).Return(nil, expectedError)
+	user, err := authService.GetUserProfile(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedError.Error())
 	assert.Nil(t, user)
-	mockUserRepo.AssertCalled(t, "GetUser", request.UserId)
+	mockUserRepo.AssertCalled(t, "GetUser", mock.Anything, request.UserId)
 	mockValidator.AssertExpectations(t)
 	mockPublisher.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -202,7 +188,7 @@ func TestGetUserProfileByPhone_Success(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.GetProfileByPhoneNumberRequest{
 		RequestId:   "123",
 		CountryCode: 91,
@@ -213,14 +199,14 @@ func TestGetUserProfileByPhone_Success(t *testing.T) {
 		Name:        "John Doe",
 		UserName:    "johndoe",
 		Email:       "john@example.com",
-		CreatedAt:   "2022-05-01",
+		CreatedAt:   time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
 		Verified:    true,
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidateGetProfileByMobileNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
-	user, err := authService.GetUserProfileByPhone(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	user, err := authService.GetUserProfileByPhone(context.Background(), request)
 	assert.NoError(t, err)
 	assert.NotNil(t, user)
 	assert.Equal(t, mockUser.Id, user.Id)
@@ -231,7 +217,7 @@ func TestGetUserProfileByPhone_Success(t *testing.T) {
 	assert.Equal(t, mockUser.CountryCode, user.CountryCode)
 	assert.Equal(t, mockUser.PhoneNumber, user.PhoneNumber)
 	mockValidator.AssertCalled(t, "ValidateGetProfileByMobileNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockPublisher.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
 	mockEventRepo.AssertExpectations(t)
@@ -243,7 +229,7 @@ func TestGetUserProfileByPhone_Failure(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.GetProfileByPhoneNumberRequest{
 		RequestId:   "123",
 		CountryCode: 91,
@@ -251,13 +237,13 @@ func TestGetUserProfileByPhone_Failure(t *testing.T) {
 	}
 	expectedError := errors.New("failed to get user by phone number and country code")
 	mockValidator.On("ValidateGetProfileByMobileNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, expectedError)
-	user, err := authService.GetUserProfileByPhone(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, expectedError)
+	user, err := authService.GetUserProfileByPhone(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedError.Error())
 	assert.Nil(t, user)
 	mockValidator.AssertCalled(t, "ValidateGetProfileByMobileNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockPublisher.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
 	mockEventRepo.AssertExpectations(t)
@@ -269,7 +255,7 @@ func TestGetUserProfileByPhone_ValidationFailure(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.GetProfileByPhoneNumberRequest{
 		RequestId:   "123",
 		CountryCode: 91,
@@ -277,7 +263,7 @@ func TestGetUserProfileByPhone_ValidationFailure(t *testing.T) {
 	}
 	expectedError := errors.New("request validation failed")
 	mockValidator.On("ValidateGetProfileByMobileNumberRequest", request).Return(expectedError)
-	user, err := authService.GetUserProfileByPhone(request)
+	user, err := authService.GetUserProfileByPhone(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedError.Error())
 	assert.Nil(t, user)
@@ -294,7 +280,7 @@ func TestVerifyOtp_Success(t *testing.T) {
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{
 		RequestId:   "123",
 		Otp:         1234,
@@ -311,17 +297,17 @@ func TestVerifyOtp_Success(t *testing.T) {
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
 	}
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(request.Otp, nil)
-	mockUserRepo.On("MarkVerified", mockUser.Id).Return(nil)
-	mockEventRepo.On("InsertEvent", string(PHONE_VERIFIED), mockUser.PhoneNumber).Return(nil)
-	err := authService.VerifyOtp(request)
+	mockUserRepo.On("MarkVerified", mock.Anything, mockUser.Id).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PHONE_VERIFIED), mockUser.PhoneNumber).Return(nil)
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.NoError(t, err)
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockUserRepo.AssertCalled(t, "MarkVerified", mockUser.Id)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(PHONE_VERIFIED), mockUser.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "MarkVerified", mock.Anything, mockUser.Id)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(PHONE_VERIFIED), mockUser.PhoneNumber)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -330,11 +316,11 @@ func TestVerifyOtp_Success(t *testing.T) {
 
 func TestVerifyOtp_ValidationFailure(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
-	authService := NewAuthService(nil, mockValidator, nil, nil, nil)
+	authService := NewAuthService(nil, mockValidator, nil, nil, nil, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{RequestId: "123", Otp: 1234, CountryCode: 91, PhoneNumber: "1234567890"}
 	expectedErr := errors.New("validation error")
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(expectedErr)
-	err := authService.VerifyOtp(request)
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.EqualError(t, err, expectedErr.Error())
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
 	mockValidator.AssertExpectations(t)
@@ -343,15 +329,15 @@ func TestVerifyOtp_ValidationFailure(t *testing.T) {
 func TestVerifyOtp_GetUserFailure(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
 	mockUserRepo := &mocks.IUserRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{RequestId: "123", Otp: 1234, CountryCode: 91, PhoneNumber: "1234567890"}
 	expectedErr := errors.New("user not found")
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
-	err := authService.VerifyOtp(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.EqualError(t, err, expectedErr.Error())
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 }
@@ -359,14 +345,14 @@ func TestVerifyOtp_GetUserFailure(t *testing.T) {
 func TestVerifyOtp_GetUserNil(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
 	mockUserRepo := &mocks.IUserRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{RequestId: "123", Otp: 1234, CountryCode: 91, PhoneNumber: "1234567890"}
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, nil)
-	err := authService.VerifyOtp(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, nil)
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.EqualError(t, err, fmt.Sprintf("No user registered with %s", request.PhoneNumber))
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 }
@@ -376,7 +362,7 @@ func TestVerifyOtp_InvalidOtp(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
 	mockEventRepo := &mocks.IEventRepository{}
 	mockGenerator := &mocks.IGenerator{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
@@ -389,15 +375,15 @@ func TestVerifyOtp_InvalidOtp(t *testing.T) {
 		CountryCode: request.CountryCode,
 	}
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(user, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(user, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(654321), nil) // Correct OTP
-	mockEventRepo.On("InsertEvent", string(INCORRECT_OTP), request.PhoneNumber).Return(errors.New("failed to insert event"))
-	err := authService.VerifyOtp(request)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), request.PhoneNumber).Return(errors.New("failed to insert event"))
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.Error(t, err)
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(INCORRECT_OTP), request.PhoneNumber)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(INCORRECT_OTP), request.PhoneNumber)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -409,7 +395,7 @@ func TestVerifyOtp_GenerateFailure(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
 	mockEventRepo := &mocks.IEventRepository{}
 	mockGenerator := &mocks.IGenerator{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
@@ -422,12 +408,12 @@ func TestVerifyOtp_GenerateFailure(t *testing.T) {
 		CountryCode: request.CountryCode,
 	}
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(user, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(user, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(0), errors.New("failed to generate OTP"))
-	err := authService.VerifyOtp(request)
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.Error(t, err)
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
@@ -439,7 +425,7 @@ func TestVerifyOtp_UpdateFailure(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
 	mockEventRepo := &mocks.IEventRepository{}
 	mockGenerator := &mocks.IGenerator{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.VerifyPhoneNumberRequest{
 		CountryCode: 91,
 		PhoneNumber: "1234567890",
@@ -452,15 +438,15 @@ func TestVerifyOtp_UpdateFailure(t *testing.T) {
 		CountryCode: request.CountryCode,
 	}
 	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(user, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(user, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(123456), nil)
-	mockUserRepo.On("MarkVerified", user.Id).Return(errors.New("failed to update user verification"))
-	err := authService.VerifyOtp(request)
+	mockUserRepo.On("MarkVerified", mock.Anything, user.Id).Return(errors.New("failed to update user verification"))
+	err := authService.VerifyOtp(context.Background(), request)
 	assert.Error(t, err)
 	mockValidator.AssertCalled(t, "ValidateVerifyPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockUserRepo.AssertCalled(t, "MarkVerified", user.Id)
+	mockUserRepo.AssertCalled(t, "MarkVerified", mock.Anything, user.Id)
 	mockValidator.AssertExpectations(t)
 	mockUserRepo.AssertExpectations(t)
 	mockGenerator.AssertExpectations(t)
@@ -482,18 +468,37 @@ func TestLoginWithPhoneNumber_Success(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
-	mockPublisher.On("Publish", mock.Anything).Return(nil)
-	mockEventRepo.On("InsertEvent", string(LOGIN_REQUEST), request.PhoneNumber).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(LOGIN_REQUEST), request.PhoneNumber).Return(nil)
 
-	err := authService.LoginWithPhoneNumber(request)
+	deliveryId, err := authService.LoginWithPhoneNumber(context.Background(), request)
 
 	assert.NoError(t, err)
+	published := mockPublisher.Calls[0].Arguments.Get(1).(*otp.GenerateOTPRequest)
+	assert.NotEmpty(t, deliveryId)
+	assert.Equal(t, published.RequestId, deliveryId)
 
 	mockValidator.AssertCalled(t, "ValidateLoginWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
-	mockPublisher.AssertCalled(t, "Publish", mock.Anything)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(LOGIN_REQUEST), request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
+	mockPublisher.AssertCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(LOGIN_REQUEST), request.PhoneNumber)
+}
+
+func TestLoginWithPhoneNumber_DeliveryUnavailable(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.LoginWithPhoneNumberRequest{
+		CountryCode: 91,
+		PhoneNumber: "1234567890",
+	}
+	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(storedProfile(), nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(&gateway.DeliveryUnavailableError{Attempts: 3, Err: errors.New("nacked")})
+
+	_, err := authService.LoginWithPhoneNumber(context.Background(), request)
+
+	assert.ErrorIs(t, err, gateway.ErrDeliveryUnavailable)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, string(LOGIN_REQUEST), mock.Anything)
 }
 
 func TestLoginWithPhoneNumber_ValidationFailure(t *testing.T) {
@@ -505,13 +510,13 @@ func TestLoginWithPhoneNumber_ValidationFailure(t *testing.T) {
 	expectedErr := errors.New("validation error")
 	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(expectedErr)
 
-	err := authService.LoginWithPhoneNumber(request)
+	_, err := authService.LoginWithPhoneNumber(context.Background(), request)
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedErr.Error())
 
 	mockValidator.AssertCalled(t, "ValidateLoginWithPhoneNumberRequest", request)
-	mockUserRepo.AssertNotCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, mock.Anything)
+	mockUserRepo.AssertNotCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestLoginWithPhoneNumber_GetUserFailure(t *testing.T) {
@@ -522,15 +527,15 @@ func TestLoginWithPhoneNumber_GetUserFailure(t *testing.T) {
 	}
 	expectedErr := errors.New("failed to get user")
 	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
 
-	err := authService.LoginWithPhoneNumber(request)
+	_, err := authService.LoginWithPhoneNumber(context.Background(), request)
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedErr.Error())
 
 	mockValidator.AssertCalled(t, "ValidateLoginWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 }
 
 func TestLoginWithPhoneNumber_UnverifiedUser(t *testing.T) {
@@ -549,15 +554,15 @@ func TestLoginWithPhoneNumber_UnverifiedUser(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
-	mockEventRepo.On("InsertEvent", string(UNVERIFIED_LOGIN_ATTEMPT), request.PhoneNumber).Return(nil)
-	err := authService.LoginWithPhoneNumber(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(UNVERIFIED_LOGIN_ATTEMPT), request.PhoneNumber).Return(nil)
+	_, err := authService.LoginWithPhoneNumber(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "verify phone number to login")
 	mockValidator.AssertCalled(t, "ValidateLoginWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
-	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(UNVERIFIED_LOGIN_ATTEMPT), request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(UNVERIFIED_LOGIN_ATTEMPT), request.PhoneNumber)
 }
 
 func TestLoginWithPhoneNumber_PublishMessageFailure(t *testing.T) {
@@ -576,14 +581,14 @@ func TestLoginWithPhoneNumber_PublishMessageFailure(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidateLoginWithPhoneNumberRequest", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
-	mockPublisher.On("Publish", mock.Anything).Return(errors.New("failed to publish message"))
-	err := authService.LoginWithPhoneNumber(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("failed to publish message"))
+	_, err := authService.LoginWithPhoneNumber(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "failed to publish message")
 	mockValidator.AssertCalled(t, "ValidateLoginWithPhoneNumberRequest", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
-	mockPublisher.AssertCalled(t, "Publish", mock.Anything)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
+	mockPublisher.AssertCalled(t, "Publish", mock.Anything, mock.Anything)
 }
 
 func TestValidatePhoneNumberLogin_Success(t *testing.T) {
@@ -603,18 +608,18 @@ func TestValidatePhoneNumberLogin_Success(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidatePhoneNumberLogin", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(123456), nil)
-	mockEventRepo.On("InsertEvent", string(LOGIN_SUCCESSFUL), request.PhoneNumber).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(LOGIN_SUCCESSFUL), request.PhoneNumber).Return(nil)
 
-	err := authService.ValidatePhoneNumberLogin(request)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
 
 	assert.NoError(t, err)
 
 	mockValidator.AssertCalled(t, "ValidatePhoneNumberLogin", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(LOGIN_SUCCESSFUL), request.PhoneNumber)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(LOGIN_SUCCESSFUL), request.PhoneNumber)
 }
 
 func TestValidatePhoneNumberLogin_GenerateFailure(t *testing.T) {
@@ -625,18 +630,18 @@ func TestValidatePhoneNumberLogin_GenerateFailure(t *testing.T) {
 		Otp:         123456,
 	}
 	mockValidator.On("ValidatePhoneNumberLogin", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(0), errors.New("failed to generate OTP"))
 
-	err := authService.ValidatePhoneNumberLogin(request)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, "unable to verify the OTP, Please try again after some time")
 
 	mockValidator.AssertCalled(t, "ValidatePhoneNumberLogin", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
 }
 
 func TestValidatePhoneNumberLogin_InvalidOTP(t *testing.T) {
@@ -656,21 +661,21 @@ func TestValidatePhoneNumberLogin_InvalidOTP(t *testing.T) {
 		PhoneNumber: "1234567890",
 	}
 	mockValidator.On("ValidatePhoneNumberLogin", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(mockUser, nil)
 	mockGenerator.On("Generate", request.PhoneNumber).Return(int32(654321), nil) // Correct OTP
-	mockEventRepo.On("InsertEvent", string(INCORRECT_OTP), request.PhoneNumber).Return(nil)
-	err := authService.ValidatePhoneNumberLogin(request)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), request.PhoneNumber).Return(nil)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "invalid OTP")
 	mockValidator.AssertCalled(t, "ValidatePhoneNumberLogin", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
 	mockGenerator.AssertCalled(t, "Generate", request.PhoneNumber)
-	mockEventRepo.AssertCalled(t, "InsertEvent", string(INCORRECT_OTP), request.PhoneNumber)
+	mockEventRepo.AssertCalled(t, "InsertEvent", mock.Anything, string(INCORRECT_OTP), request.PhoneNumber)
 }
 
 func TestValidatePhoneNumberLogin_ValidationFailure(t *testing.T) {
 	mockValidator := &mocks.IRequestValidator{}
-	authService := NewAuthService(nil, mockValidator, nil, nil, nil)
+	authService := NewAuthService(nil, mockValidator, nil, nil, nil, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.ValidatePhoneNumberLoginRequest{
 		RequestId:   "123",
 		PhoneNumber: "1234567890",
@@ -679,7 +684,7 @@ func TestValidatePhoneNumberLogin_ValidationFailure(t *testing.T) {
 	}
 	expectedErr := errors.New("validation error")
 	mockValidator.On("ValidatePhoneNumberLogin", request).Return(expectedErr)
-	err := authService.ValidatePhoneNumberLogin(request)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedErr.Error())
 	mockValidator.AssertCalled(t, "ValidatePhoneNumberLogin", request)
@@ -689,7 +694,7 @@ func TestValidatePhoneNumberLogin_GetUserFailure(t *testing.T) {
 	// Setup
 	mockValidator := &mocks.IRequestValidator{}
 	mockUserRepo := &mocks.IUserRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil)
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, nil, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	request := &auth.ValidatePhoneNumberLoginRequest{
 		RequestId:   "123",
 		PhoneNumber: "1234567890",
@@ -698,12 +703,23 @@ func TestValidatePhoneNumberLogin_GetUserFailure(t *testing.T) {
 	}
 	expectedErr := errors.New("failed to get user")
 	mockValidator.On("ValidatePhoneNumberLogin", request).Return(nil)
-	mockUserRepo.On("GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
-	err := authService.ValidatePhoneNumberLogin(request)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(nil, expectedErr)
+	err := authService.ValidatePhoneNumberLogin(context.Background(), request)
 	assert.Error(t, err)
 	assert.EqualError(t, err, expectedErr.Error())
 	mockValidator.AssertCalled(t, "ValidatePhoneNumberLogin", request)
-	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", request.CountryCode, request.PhoneNumber)
+	mockUserRepo.AssertCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber)
+}
+
+var testAuthServiceConfig = AuthServiceConfig{
+	ResendCooldown:      30 * time.Second,
+	ResendWindow:        10 * time.Minute,
+	MaxResends:          3,
+	EmailEscalationAge:  7 * 24 * time.Hour,
+	UnverifiedUserTTL:   24 * time.Hour,
+	FreshLoginWindow:    10 * time.Minute,
+	DeletionGracePeriod: 30 * 24 * time.Hour,
+	DataExportTTL:       7 * 24 * time.Hour,
 }
 
 func setupAuthServiceMocks(t *testing.T) (*mocks.IUserRepository, *mocks.IRequestValidator, *mocks.IMessagePublisher, *mocks.IGenerator, *mocks.IEventRepository, IAuthService) {
@@ -712,6 +728,218 @@ func setupAuthServiceMocks(t *testing.T) (*mocks.IUserRepository, *mocks.IReques
 	mockPublisher := &mocks.IMessagePublisher{}
 	mockGenerator := &mocks.IGenerator{}
 	mockEventRepo := &mocks.IEventRepository{}
-	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, nil, nil, nil, NewTunable(testAuthServiceConfig))
 	return mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, authService
 }
+
+func TestCheckUsernameAvailability_Available(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "johndoe"}
+	mockValidator.On("ValidateCheckUsernameAvailabilityRequest", request).Return(nil)
+	mockUserRepo.On("IsUserNameTaken", mock.Anything, "johndoe").Return(false, nil)
+	available, suggestions, err := authService.CheckUsernameAvailability(context.Background(), request)
+	assert.NoError(t, err)
+	assert.True(t, available)
+	assert.Empty(t, suggestions)
+	mockValidator.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestCheckUsernameAvailability_TakenSuggestsAlternatives(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "JohnDoe"}
+	mockValidator.On("ValidateCheckUsernameAvailabilityRequest", request).Return(nil)
+	mockUserRepo.On("IsUserNameTaken", mock.Anything, "JohnDoe").Return(true, nil).Once()
+	mockUserRepo.On("IsUserNameTaken", mock.Anything, mock.Anything).Return(false, nil)
+	available, suggestions, err := authService.CheckUsernameAvailability(context.Background(), request)
+	assert.NoError(t, err)
+	assert.False(t, available)
+	assert.Len(t, suggestions, maxUserNameSuggestions)
+	for _, suggestion := range suggestions {
+		assert.Contains(t, suggestion, "johndoe")
+	}
+	mockValidator.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestCheckUsernameAvailability_ValidationFailure(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "admin"}
+	mockValidator.On("ValidateCheckUsernameAvailabilityRequest", request).Return(errors.New("user name admin is reserved"))
+	available, suggestions, err := authService.CheckUsernameAvailability(context.Background(), request)
+	assert.EqualError(t, err, "user name admin is reserved")
+	assert.False(t, available)
+	assert.Nil(t, suggestions)
+	mockUserRepo.AssertNotCalled(t, "IsUserNameTaken", mock.Anything, mock.Anything)
+}
+
+func TestCheckUsernameAvailability_RepositoryFailure(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.CheckUsernameAvailabilityRequest{RequestId: "123", UserName: "johndoe"}
+	mockValidator.On("ValidateCheckUsernameAvailabilityRequest", request).Return(nil)
+	mockUserRepo.On("IsUserNameTaken", mock.Anything, "johndoe").Return(false, errors.New("db down"))
+	_, _, err := authService.CheckUsernameAvailability(context.Background(), request)
+	assert.EqualError(t, err, "db down")
+}
+
+func TestHandleSignUp_StoresNormalizedEmail(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.SignupWithPhoneNumberRequest{User: &auth.User{
+		Name:        "John Doe",
+		UserName:    "johndoe",
+		Email:       "John.Doe+news@Gmail.com",
+		PhoneNumber: "1234567890",
+		CountryCode: 91,
+	}}
+	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "John.Doe+news@Gmail.com").Return("john.doe+news@gmail.com", "johndoe@gmail.com")
+	mockUserRepo.On("RegisterUser", mock.Anything, mock.MatchedBy(func(registration repository.Registration) bool {
+		return registration.User.Email == "john.doe+news@gmail.com" && registration.User.CanonicalEmail == "johndoe@gmail.com"
+	})).Return(&models.User{Id: 1, Email: "john.doe+news@gmail.com", PhoneNumber: "1234567890"}, nil)
+	user, _, err := authService.HandleSignUp(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "john.doe+news@gmail.com", user.Email)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestHandleSignUp_ExistingPhoneNumberSuggestsLogin(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.SignupWithPhoneNumberRequest{User: &auth.User{
+		Name:        "John Doe",
+		UserName:    "johndoe",
+		Email:       "john@example.com",
+		PhoneNumber: "1234567890",
+		CountryCode: 91,
+	}}
+	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "john@example.com").Return("john@example.com", "john@example.com")
+	mockUserRepo.On("RegisterUser", mock.Anything, mock.Anything).Return(nil, &models.AlreadyExistsError{Field: models.FIELD_PHONE_NUMBER})
+	user, _, err := authService.HandleSignUp(context.Background(), request)
+	assert.Nil(t, user)
+	assert.ErrorIs(t, err, ErrPhoneNumberRegistered)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleSignUp_ExistingEmailIsReported(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.SignupWithPhoneNumberRequest{User: &auth.User{
+		Name:        "John Doe",
+		UserName:    "johndoe",
+		Email:       "john@example.com",
+		PhoneNumber: "1234567890",
+		CountryCode: 91,
+	}}
+	mockValidator.On("ValidateSignupWithPhoneNumberRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "john@example.com").Return("john@example.com", "john@example.com")
+	mockUserRepo.On("RegisterUser", mock.Anything, mock.Anything).Return(nil, &models.AlreadyExistsError{Field: models.FIELD_EMAIL})
+	_, _, err := authService.HandleSignUp(context.Background(), request)
+	assert.EqualError(t, err, "a user with this email already exists")
+}
+
+func TestResendOtp_EscalatesToVoiceOnSecondResend(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ResendOtpRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockUser := &models.User{Id: 1, Email: "john@example.com", CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(mockUser, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", testAuthServiceConfig.ResendWindow).Return([]models.UserEvent{
+		{Id: 1, Event: string(SIGN_IN_REQUEST_OTP), CreatedAt: time.Now().Add(-5 * time.Minute)},
+		{Id: 2, Event: string(OTP_RESENT), CreatedAt: time.Now().Add(-4 * time.Minute)},
+	}, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(otpRequest *otp.GenerateOTPRequest) bool {
+		return otpRequest.PhoneNumber == "1234567890" && otpRequest.Channel == otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE && otpRequest.Email == ""
+	})).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(OTP_RESENT), "1234567890").Return()
+	channel, deliveryId, err := authService.ResendOtp(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE, channel)
+	assert.Equal(t, mockPublisher.Calls[0].Arguments.Get(1).(*otp.GenerateOTPRequest).RequestId, deliveryId)
+	mockPublisher.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestResendOtp_EmailChannelCarriesTheEmail(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ResendOtpRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockUser := &models.User{Id: 1, Email: "john@example.com", CountryCode: 91, PhoneNumber: "1234567890", Verified: true,
+		EmailConfirmedAt: time.Now().Add(-30 * 24 * time.Hour)}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(mockUser, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", mock.Anything).Return([]models.UserEvent{
+		{Id: 1, Event: string(LOGIN_REQUEST), CreatedAt: time.Now().Add(-5 * time.Minute)},
+		{Id: 2, Event: string(OTP_RESENT), CreatedAt: time.Now().Add(-4 * time.Minute)},
+		{Id: 3, Event: string(OTP_RESENT), CreatedAt: time.Now().Add(-3 * time.Minute)},
+	}, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(otpRequest *otp.GenerateOTPRequest) bool {
+		return otpRequest.Channel == otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL && otpRequest.Email == "john@example.com"
+	})).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(OTP_RESENT), "1234567890").Return()
+	channel, _, err := authService.ResendOtp(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL, channel)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestResendOtp_CooldownDoesNotPublish(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ResendOtpRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(&models.User{Id: 1, PhoneNumber: "1234567890"}, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", mock.Anything).Return([]models.UserEvent{
+		{Id: 1, Event: string(SIGN_IN_REQUEST_OTP), CreatedAt: time.Now().Add(-10 * time.Second)},
+	}, nil)
+	_, _, err := authService.ResendOtp(context.Background(), request)
+	var cooldown *ResendCooldownError
+	assert.ErrorAs(t, err, &cooldown)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResendOtp_UsesTheSwappedConfig(t *testing.T) {
+	mockUserRepo := &mocks.IUserRepository{}
+	mockValidator := &mocks.IRequestValidator{}
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockEventRepo := &mocks.IEventRepository{}
+	config := NewTunable(testAuthServiceConfig)
+	authService := NewAuthService(mockUserRepo, mockValidator, mockPublisher, nil, mockEventRepo, nil, nil, nil, config)
+	request := &auth.ResendOtpRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(&models.User{Id: 1, PhoneNumber: "1234567890"}, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", mock.Anything).Return([]models.UserEvent{
+		{Id: 1, Event: string(SIGN_IN_REQUEST_OTP), CreatedAt: time.Now().Add(-10 * time.Second)},
+	}, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(OTP_RESENT), "1234567890").Return()
+	_, _, err := authService.ResendOtp(context.Background(), request)
+	var cooldown *ResendCooldownError
+	assert.ErrorAs(t, err, &cooldown)
+
+	swapped := testAuthServiceConfig
+	swapped.ResendCooldown = 5 * time.Second
+	config.Swap(swapped)
+	_, _, err = authService.ResendOtp(context.Background(), request)
+	assert.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestResendOtp_PublishFailureIsNotRecorded(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ResendOtpRequest{CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, int32(91), "1234567890").Return(&models.User{Id: 1, PhoneNumber: "1234567890"}, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", mock.Anything).Return(nil, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("broker down"))
+	_, _, err := authService.ResendOtp(context.Background(), request)
+	assert.EqualError(t, err, "broker down")
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestResendOtp_ValidationFailure(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.ResendOtpRequest{PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateResendOtpRequest", request).Return(errors.New("country code 0 is not yet supported"))
+	_, _, err := authService.ResendOtp(context.Background(), request)
+	assert.EqualError(t, err, "country code 0 is not yet supported")
+	mockUserRepo.AssertNotCalled(t, "GetUserByPhoneNumberAndCountry", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// ErrDataExportNotFound is returned for unknown and expired exports as well as wrong tokens, so the token can not
+// be guessed for a known export id
+var ErrDataExportNotFound = errors.New("no data export with this id and download token")
+
+// ExportMyData requests an archive of the data stored about the user after confirming a fresh otp of its phone
+// number. The archive is assembled by the DataExporter and downloaded with the token of the returned ticket.
+func (a authService) ExportMyData(ctx context.Context, request *auth.ExportMyDataRequest) (*models.DataExportTicket, error) {
+	err := a.ValidateExportMyDataRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	err = a.confirmPhoneOtp(ctx, user, request.Otp)
+	if err != nil {
+		return nil, err
+	}
+	token, err := newDownloadToken()
+	if err != nil {
+		return nil, err
+	}
+	export, err := a.exports.Create(ctx, user.Id, hashDownloadToken(token), a.config.Load().DataExportTTL)
+	if err != nil {
+		return nil, err
+	}
+	a.InsertEvent(ctx, string(DATA_EXPORT_REQUESTED), user.PhoneNumber)
+	return &models.DataExportTicket{ExportId: export.Id, Token: token, ExpiresAt: export.ExpiresAt}, nil
+}
+
+// DownloadMyData returns the export if the token matches, its archive is set once its status is READY
+func (a authService) DownloadMyData(ctx context.Context, request *auth.DownloadMyDataRequest) (*models.DataExport, error) {
+	err := a.ValidateDownloadMyDataRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	export, err := a.exports.GetExport(ctx, request.ExportId)
+	if err != nil {
+		return nil, ErrDataExportNotFound
+	}
+	if subtle.ConstantTimeCompare(export.TokenHash, hashDownloadToken(request.DownloadToken)) != 1 {
+		return nil, ErrDataExportNotFound
+	}
+	if !export.ExpiresAt.After(time.Now()) {
+		return nil, ErrDataExportNotFound
+	}
+	return export, nil
+}
+
+func newDownloadToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func hashDownloadToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+type DataExporterConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease hides claimed exports from other exporters while their archive is assembled
+	Lease time.Duration
+}
+
+// DataExporter assembles the archives of requested data exports in the background
+type DataExporter struct {
+	exports repository.IDataExportRepository
+	users   repository.IUserRepository
+	events  repository.IEventRepository
+	config  DataExporterConfig
+}
+
+func NewDataExporter(exports repository.IDataExportRepository, users repository.IUserRepository, events repository.IEventRepository, config DataExporterConfig) *DataExporter {
+	return &DataExporter{exports: exports, users: users, events: events, config: config}
+}
+
+// Run assembles pending exports every poll interval until the context is cancelled
+func (e *DataExporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := e.ExportPending(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("data exporter failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExportPending assembles one batch of pending exports and returns how many became ready. Exports failing on
+// storage errors are left to be claimed again once their lease expires.
+func (e *DataExporter) ExportPending(ctx context.Context) (int, error) {
+	exports, err := e.exports.ClaimPending(ctx, e.config.BatchSize, e.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+	ready := 0
+	for _, export := range exports {
+		archive, err := e.assemble(ctx, export.UserId)
+		if err != nil {
+			slog.Error("assembling data export failed", "id", export.Id, "error", err)
+			continue
+		}
+		if err = e.exports.Complete(ctx, export.Id, archive); err != nil {
+			return ready, err
+		}
+		ready++
+	}
+	return ready, nil
+}
+
+func (e *DataExporter) assemble(ctx context.Context, userId int32) ([]byte, error) {
+	user, err := e.users.GetUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	numbers, err := e.users.ListPhoneNumbers(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	// the events of every number the user held while holding it, a number held by someone else before or after keeps
+	// their events out of the archive
+	var events []models.UserEvent
+	for _, number := range numbers {
+		held, err := e.events.ListEvents(ctx, number.PhoneNumber, number.AcquiredAt, number.ReplacedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, held...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+	return json.MarshalIndent(newDataArchive(user, events, time.Now().UTC()), "", "  ")
+}
+
+// dataArchive is the JSON document handed out by DownloadMyData. The service keeps no session store or external
+// identities, so sessions are derived from the login events and identities are the phone number and email.
+type dataArchive struct {
+	ExportedAt time.Time          `json:"exportedAt"`
+	Profile    archivedProfile    `json:"profile"`
+	Identities []archivedIdentity `json:"identities"`
+	Sessions   []archivedSession  `json:"sessions"`
+	Events     []archivedEvent    `json:"events"`
+}
+
+type archivedProfile struct {
+	Id                 int32      `json:"id"`
+	Name               string     `json:"name"`
+	UserName           string     `json:"userName"`
+	Email              string     `json:"email"`
+	PendingEmail       string     `json:"pendingEmail,omitempty"`
+	CountryCode        int32      `json:"countryCode"`
+	PhoneNumber        string     `json:"phoneNumber"`
+	PendingCountryCode int32      `json:"pendingCountryCode,omitempty"`
+	PendingPhoneNumber string     `json:"pendingPhoneNumber,omitempty"`
+	Verified           bool       `json:"verified"`
+	Status             string     `json:"status"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+	DeletedAt          *time.Time `json:"deletedAt,omitempty"`
+}
+
+type archivedIdentity struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type archivedSession struct {
+	StartedAt time.Time  `json:"startedAt"`
+	EndedAt   *time.Time `json:"endedAt,omitempty"`
+}
+
+type archivedEvent struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newDataArchive(user *models.User, events []models.UserEvent, exportedAt time.Time) dataArchive {
+	archive := dataArchive{
+		ExportedAt: exportedAt,
+		Profile: archivedProfile{
+			Id:                 user.Id,
+			Name:               user.Name,
+			UserName:           user.UserName,
+			Email:              user.Email,
+			PendingEmail:       user.PendingEmail,
+			CountryCode:        user.CountryCode,
+			PhoneNumber:        user.PhoneNumber,
+			PendingCountryCode: user.PendingCountryCode,
+			PendingPhoneNumber: user.PendingPhoneNumber,
+			Verified:           user.Verified,
+			Status:             user.Status,
+			CreatedAt:          user.CreatedAt,
+			UpdatedAt:          user.UpdatedAt,
+		},
+		Identities: []archivedIdentity{
+			{Type: "phone", Value: user.PhoneNumber},
+			{Type: "email", Value: user.Email},
+		},
+		Sessions: []archivedSession{},
+		Events:   []archivedEvent{},
+	}
+	if user.IsDeleted() {
+		deletedAt := user.DeletedAt
+		archive.Profile.DeletedAt = &deletedAt
+	}
+	open := 0
+	for _, event := range events {
+		archive.Events = append(archive.Events, archivedEvent{Event: event.Event, CreatedAt: event.CreatedAt})
+		switch UserEvents(event.Event) {
+		case LOGIN_SUCCESSFUL:
+			archive.Sessions = append(archive.Sessions, archivedSession{StartedAt: event.CreatedAt})
+			open++
+		case LOGOUT:
+			// a logout ends every open login of the phone number
+			endedAt := event.CreatedAt
+			for i := len(archive.Sessions) - open; i < len(archive.Sessions); i++ {
+				archive.Sessions[i].EndedAt = &endedAt
+			}
+			open = 0
+		}
+	}
+	return archive
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/mocks"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func setupDataExportMocks() (*mocks.IUserRepository, *mocks.IRequestValidator, *mocks.IGenerator, *mocks.IEventRepository, *mocks.IDataExportRepository, IAuthService) {
+	mockUserRepo := &mocks.IUserRepository{}
+	mockValidator := &mocks.IRequestValidator{}
+	mockGenerator := &mocks.IGenerator{}
+	mockEventRepo := &mocks.IEventRepository{}
+	mockExports := &mocks.IDataExportRepository{}
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, mockGenerator, mockEventRepo, mockExports, nil, nil, NewTunable(testAuthServiceConfig))
+	return mockUserRepo, mockValidator, mockGenerator, mockEventRepo, mockExports, authService
+}
+
+func TestExportMyData_CreatesAPendingExport(t *testing.T) {
+	mockUserRepo, mockValidator, mockGenerator, mockEventRepo, mockExports, authService := setupDataExportMocks()
+	request := &auth.ExportMyDataRequest{UserId: 1, Otp: 654321}
+	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	var tokenHash []byte
+	mockValidator.On("ValidateExportMyDataRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	mockExports.On("Create", mock.Anything, int32(1), mock.Anything, 7*24*time.Hour).
+		Run(func(args mock.Arguments) { tokenHash = args.Get(2).([]byte) }).
+		Return(&models.DataExport{Id: 5, UserId: 1, Status: models.DATA_EXPORT_PENDING, ExpiresAt: expiresAt}, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(DATA_EXPORT_REQUESTED), "1234567890").Return()
+	ticket, err := authService.ExportMyData(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), ticket.ExportId)
+	assert.Equal(t, expiresAt, ticket.ExpiresAt)
+	assert.NotEmpty(t, ticket.Token)
+	// only the hash of the token is stored
+	assert.Equal(t, hashDownloadToken(ticket.Token), tokenHash)
+	assert.NotEqual(t, []byte(ticket.Token), tokenHash)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestExportMyData_InvalidOtp(t *testing.T) {
+	mockUserRepo, mockValidator, mockGenerator, mockEventRepo, mockExports, authService := setupDataExportMocks()
+	request := &auth.ExportMyDataRequest{UserId: 1, Otp: 111111}
+	mockValidator.On("ValidateExportMyDataRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(654321), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, err := authService.ExportMyData(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockExports.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDownloadMyData(t *testing.T) {
+	ready := &models.DataExport{
+		Id:        5,
+		TokenHash: hashDownloadToken("token"),
+		Status:    models.DATA_EXPORT_READY,
+		Archive:   []byte(`{}`),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	expired := *ready
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	cases := []struct {
+		name   string
+		token  string
+		export *models.DataExport
+		err    error
+	}{
+		{"matching token", "token", ready, nil},
+		{"wrong token", "other", ready, ErrDataExportNotFound},
+		{"expired export", "token", &expired, ErrDataExportNotFound},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, mockValidator, _, _, mockExports, authService := setupDataExportMocks()
+			request := &auth.DownloadMyDataRequest{ExportId: 5, DownloadToken: c.token}
+			mockValidator.On("ValidateDownloadMyDataRequest", request).Return(nil)
+			mockExports.On("GetExport", mock.Anything, int64(5)).Return(c.export, nil)
+			export, err := authService.DownloadMyData(context.Background(), request)
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(`{}`), export.Archive)
+		})
+	}
+}
+
+func TestDataExporter_AssemblesPendingExports(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	events := repository.NewMemoryEventRepository(store)
+	exports := repository.NewMemoryDataExportRepository(store)
+	user, err := users.SaveUser(ctx, &models.User{Name: "John Doe", UserName: "johndoe", Email: "john@example.com", CanonicalEmail: "john@example.com", CountryCode: 91, PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "1234567890")
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "1234567890")
+	events.InsertEvent(ctx, string(LOGOUT), "1234567890")
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "1234567890")
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "5555555555")
+	export, err := exports.Create(ctx, user.Id, hashDownloadToken("token"), time.Hour)
+	assert.NoError(t, err)
+
+	exporter := NewDataExporter(exports, users, events, DataExporterConfig{PollInterval: time.Second, BatchSize: 10, Lease: time.Minute})
+	ready, err := exporter.ExportPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ready)
+
+	stored, err := exports.GetExport(ctx, export.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DATA_EXPORT_READY, stored.Status)
+	var archive dataArchive
+	assert.NoError(t, json.Unmarshal(stored.Archive, &archive))
+	assert.Equal(t, "johndoe", archive.Profile.UserName)
+	assert.Equal(t, "1234567890", archive.Profile.PhoneNumber)
+	assert.Len(t, archive.Events, 4)
+	assert.Equal(t, []archivedIdentity{{Type: "phone", Value: "1234567890"}, {Type: "email", Value: "john@example.com"}}, archive.Identities)
+	if assert.Len(t, archive.Sessions, 3) {
+		// the logout ends both earlier logins, the last one is still open
+		assert.NotNil(t, archive.Sessions[0].EndedAt)
+		assert.NotNil(t, archive.Sessions[1].EndedAt)
+		assert.Nil(t, archive.Sessions[2].EndedAt)
+	}
+
+	again, err := exporter.ExportPending(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, again)
+}
+
+func TestDataExporter_IncludesTheEventsOfReplacedPhoneNumbers(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	users := repository.NewMemoryUserRepository(store)
+	events := repository.NewMemoryEventRepository(store)
+	exports := repository.NewMemoryDataExportRepository(store)
+	// the earlier owner of the number the user changes to
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "5551234567")
+	time.Sleep(time.Millisecond)
+	user, err := users.SaveUser(ctx, &models.User{Name: "John Doe", UserName: "johndoe", Email: "john@example.com", CanonicalEmail: "john@example.com", CountryCode: 91, PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "1234567890")
+	pending := *user
+	pending.PendingCountryCode, pending.PendingPhoneNumber = 1, "5551234567"
+	updated, err := users.UpdateUser(ctx, &pending, user.Version)
+	assert.NoError(t, err)
+	_, err = users.ChangePhoneNumber(ctx, repository.PhoneNumberChange{
+		UserId:          user.Id,
+		ExpectedVersion: updated.Version,
+		OldNumberEvents: []string{string(PHONE_CHANGED), string(LOGOUT)},
+		NewNumberEvents: []string{string(PHONE_CHANGED)},
+	})
+	assert.NoError(t, err)
+	events.InsertEvent(ctx, string(LOGIN_SUCCESSFUL), "5551234567")
+	export, err := exports.Create(ctx, user.Id, hashDownloadToken("token"), time.Hour)
+	assert.NoError(t, err)
+
+	exporter := NewDataExporter(exports, users, events, DataExporterConfig{PollInterval: time.Second, BatchSize: 10, Lease: time.Minute})
+	ready, err := exporter.ExportPending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, ready)
+
+	stored, err := exports.GetExport(ctx, export.Id)
+	assert.NoError(t, err)
+	var archive dataArchive
+	assert.NoError(t, json.Unmarshal(stored.Archive, &archive))
+	assert.Equal(t, "5551234567", archive.Profile.PhoneNumber)
+	var exported []string
+	for _, event := range archive.Events {
+		exported = append(exported, event.Event)
+	}
+	assert.Equal(t, []string{
+		string(LOGIN_SUCCESSFUL), string(PHONE_CHANGED), string(LOGOUT), string(PHONE_CHANGED), string(LOGIN_SUCCESSFUL),
+	}, exported)
+	if assert.Len(t, archive.Sessions, 2) {
+		assert.NotNil(t, archive.Sessions[0].EndedAt)
+		assert.Nil(t, archive.Sessions[1].EndedAt)
+	}
+}
+
+func TestDataExporter_LeavesExportsPendingOnStorageErrors(t *testing.T) {
+	mockExports := &mocks.IDataExportRepository{}
+	mockUserRepo := &mocks.IUserRepository{}
+	exporter := NewDataExporter(mockExports, mockUserRepo, &mocks.IEventRepository{}, DataExporterConfig{PollInterval: time.Second, BatchSize: 10, Lease: time.Minute})
+	mockExports.On("ClaimPending", mock.Anything, 10, time.Minute).Return([]models.DataExport{{Id: 5, UserId: 1}}, nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(nil, assert.AnError)
+	ready, err := exporter.ExportPending(context.Background())
+	assert.NoError(t, err)
+	assert.Zero(t, ready)
+	mockExports.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything)
+	mockExports.AssertNotCalled(t, "Fail", mock.Anything, mock.Anything, mock.Anything)
+}
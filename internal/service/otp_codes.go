@@ -0,0 +1,174 @@
+package service
+
+import (
+	"auth-service/internal/gateway"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"math/big"
+	"os"
+	"time"
+)
+
+// Otp modes selectable in config
+const (
+	// OTP_MODE_DERIVED has the otp service derive otps from the subject with the secret key shared with it
+	OTP_MODE_DERIVED = "derived"
+	// OTP_MODE_SERVER has the auth service generate otps and send them encrypted, the otp service needs no secret
+	OTP_MODE_SERVER = "server"
+)
+
+// minDeliveryKeyBits is the smallest rsa key otps are encrypted for
+const minDeliveryKeyBits = 2048
+
+// IOtpCodes issues and verifies the otps of server mode, only hashes of issued otps are stored
+type IOtpCodes interface {
+	// Issue generates an otp for the subject of the request, stores its hash and sets it encrypted on the request
+	Issue(ctx context.Context, request *otp.GenerateOTPRequest) error
+	// Verify reports if the otp is the last otp issued to the subject, a matching otp is accepted once
+	Verify(ctx context.Context, subject string, otp int32) (bool, error)
+}
+
+type OtpCodesConfig struct {
+	// TTL is how long an issued otp is accepted
+	TTL time.Duration
+	// MaxAttempts is how often an issued otp can be tried before a new one has to be requested
+	MaxAttempts int
+}
+
+// NewOtpCodes hashes otps with the otp keys and encrypts them for deliveryKey, the public key of the otp service
+func NewOtpCodes(codes repository.IOtpCodeRepository, keys *OtpKeys, deliveryKey *rsa.PublicKey, config *Tunable[OtpCodesConfig]) (IOtpCodes, error) {
+	der, err := x509.MarshalPKIXPublicKey(deliveryKey)
+	if err != nil {
+		return nil, err
+	}
+	keyId := sha256.Sum256(der)
+	return &otpCodes{
+		codes:       codes,
+		keys:        keys,
+		deliveryKey: deliveryKey,
+		keyId:       hex.EncodeToString(keyId[:]),
+		config:      config,
+	}, nil
+}
+
+type otpCodes struct {
+	codes       repository.IOtpCodeRepository
+	keys        *OtpKeys
+	deliveryKey *rsa.PublicKey
+	keyId       string
+	config      *Tunable[OtpCodesConfig]
+}
+
+func (o *otpCodes) Issue(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	code, err := randomOtp()
+	if err != nil {
+		return err
+	}
+	ttl := o.config.Load().TTL
+	payload, err := proto.Marshal(&otp.OtpCode{
+		Otp:       code,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+		RequestId: request.RequestId,
+	})
+	if err != nil {
+		return err
+	}
+	encrypted, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, o.deliveryKey, payload, nil)
+	if err != nil {
+		return err
+	}
+	subject := otpSubject(request)
+	// the hash is stored before the otp is sent, so it is never delivered before it can be verified
+	if err = o.codes.SaveCode(ctx, subject, hashOtp(o.keys.current(), subject, code), ttl); err != nil {
+		return err
+	}
+	request.EncryptedOtp = encrypted
+	request.KeyId = o.keyId
+	return nil
+}
+
+// Verify accepts otps stored before the key was rotated by trying the hash of every key
+func (o *otpCodes) Verify(ctx context.Context, subject string, otp int32) (bool, error) {
+	var hashes [][]byte
+	for _, key := range o.keys.all() {
+		hashes = append(hashes, hashOtp(key, subject, otp))
+	}
+	return o.codes.ConsumeCode(ctx, subject, hashes, o.config.Load().MaxAttempts)
+}
+
+// hashOtp keys the hash of the otp with a secret of the auth service, a leaked table of 6 digit otps is useless without it
+func hashOtp(key string, subject string, otp int32) []byte {
+	hash := hmac.New(sha256.New, []byte(key))
+	hash.Write([]byte(fmt.Sprintf("%s:%d", subject, otp)))
+	return hash.Sum(nil)
+}
+
+// randomOtp returns a uniformly random 6 digit otp
+func randomOtp() (int32, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(900000))
+	if err != nil {
+		return 0, err
+	}
+	return int32(n.Int64()) + 100000, nil
+}
+
+// otpSubject is the value the otp of a request is verified against, the phone number unless a subject is set
+func otpSubject(request *otp.GenerateOTPRequest) string {
+	if request.Subject != "" {
+		return request.Subject
+	}
+	return request.PhoneNumber
+}
+
+// NewIssuingPublisher issues an otp for every request before publishing it
+func NewIssuingPublisher(publisher gateway.IMessagePublisher, codes IOtpCodes) gateway.IMessagePublisher {
+	return &issuingPublisher{publisher: publisher, codes: codes}
+}
+
+type issuingPublisher struct {
+	publisher gateway.IMessagePublisher
+	codes     IOtpCodes
+}
+
+func (p *issuingPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	// a retried request gets a new otp, replacing the one of the attempt that failed
+	if err := p.codes.Issue(ctx, request); err != nil {
+		return err
+	}
+	return p.publisher.Publish(ctx, request)
+}
+
+// LoadDeliveryKey reads the PEM encoded rsa public key of the otp service
+func LoadDeliveryKey(file string) (*rsa.PublicKey, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM encoded key in %s", file)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the public key in %s: %w", file, err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("the otp delivery key has to be an rsa public key")
+	}
+	if rsaKey.N.BitLen() < minDeliveryKeyBits {
+		return nil, fmt.Errorf("the otp delivery key has %d bits, at least %d are required", rsaKey.N.BitLen(), minDeliveryKeyBits)
+	}
+	return rsaKey, nil
+}
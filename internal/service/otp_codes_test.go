@@ -0,0 +1,180 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"auth-service/mocks"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/proto"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestOtpCodes(t *testing.T) (IOtpCodes, *rsa.PrivateKey) {
+	codes, key, _ := newRotatedTestOtpCodes(t)
+	return codes, key
+}
+
+func newRotatedTestOtpCodes(t *testing.T) (IOtpCodes, *rsa.PrivateKey, *OtpKeys) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	keys := NewOtpKeys("secret", nil)
+	codes, err := NewOtpCodes(repository.NewMemoryOtpCodeRepository(repository.NewMemoryStore()), keys, &key.PublicKey, NewTunable(OtpCodesConfig{
+		TTL:         time.Minute,
+		MaxAttempts: 3,
+	}))
+	assert.NoError(t, err)
+	return codes, key, keys
+}
+
+// decryptOtp decrypts the otp of a request like the otp service does
+func decryptOtp(t *testing.T, key *rsa.PrivateKey, request *otp.GenerateOTPRequest) *otp.OtpCode {
+	payload, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, request.EncryptedOtp, nil)
+	assert.NoError(t, err)
+	code := &otp.OtpCode{}
+	assert.NoError(t, proto.Unmarshal(payload, code))
+	return code
+}
+
+func TestOtpCodes_IssuesEncryptedOtpsAcceptedOnce(t *testing.T) {
+	codes, key := newTestOtpCodes(t)
+	request := &otp.GenerateOTPRequest{RequestId: "request-1", CountryCode: 91, PhoneNumber: "1234567890"}
+
+	err := codes.Issue(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Len(t, request.KeyId, 64)
+	code := decryptOtp(t, key, request)
+	assert.Equal(t, "request-1", code.RequestId)
+	assert.True(t, code.Otp >= 100000 && code.Otp <= 999999)
+	assert.InDelta(t, time.Now().Add(time.Minute).Unix(), code.ExpiresAt, 5)
+
+	matches, err := codes.Verify(context.Background(), "1234567890", code.Otp)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+	matches, err = codes.Verify(context.Background(), "1234567890", code.Otp)
+	assert.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestOtpCodes_BindsOtpsToTheSubject(t *testing.T) {
+	codes, key := newTestOtpCodes(t)
+	request := &otp.GenerateOTPRequest{PhoneNumber: "1234567890", Subject: "john@example.com"}
+	assert.NoError(t, codes.Issue(context.Background(), request))
+	code := decryptOtp(t, key, request)
+
+	matches, err := codes.Verify(context.Background(), "1234567890", code.Otp)
+	assert.NoError(t, err)
+	assert.False(t, matches)
+	matches, err = codes.Verify(context.Background(), "john@example.com", code.Otp)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+}
+
+func TestOtpCodes_RejectsOtpsAfterTooManyAttempts(t *testing.T) {
+	codes, key := newTestOtpCodes(t)
+	request := &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}
+	assert.NoError(t, codes.Issue(context.Background(), request))
+	code := decryptOtp(t, key, request)
+
+	for attempt := 0; attempt < 3; attempt++ {
+		matches, err := codes.Verify(context.Background(), "1234567890", code.Otp%999999+1)
+		assert.NoError(t, err)
+		assert.False(t, matches)
+	}
+	matches, err := codes.Verify(context.Background(), "1234567890", code.Otp)
+	assert.NoError(t, err)
+	assert.False(t, matches)
+}
+
+func TestOtpCodes_AcceptsOtpsStoredBeforeTheKeyWasRotated(t *testing.T) {
+	codes, key, keys := newRotatedTestOtpCodes(t)
+	request := &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}
+	assert.NoError(t, codes.Issue(context.Background(), request))
+	code := decryptOtp(t, key, request)
+
+	keys.Rotate("new-secret", []string{"secret"})
+	matches, err := codes.Verify(context.Background(), "1234567890", code.Otp)
+	assert.NoError(t, err)
+	assert.True(t, matches)
+}
+
+func TestIssuingPublisher_PublishesRequestsWithAnOtp(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockCodes := &mocks.IOtpCodes{}
+	request := &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}
+	mockCodes.On("Issue", mock.Anything, request).Run(func(args mock.Arguments) {
+		args.Get(1).(*otp.GenerateOTPRequest).EncryptedOtp = []byte("encrypted")
+	}).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(published *otp.GenerateOTPRequest) bool {
+		return string(published.EncryptedOtp) == "encrypted"
+	})).Return(nil)
+
+	err := NewIssuingPublisher(mockPublisher, mockCodes).Publish(context.Background(), request)
+	assert.NoError(t, err)
+	mockCodes.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestIssuingPublisher_DoesNotPublishWithoutAnOtp(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockCodes := &mocks.IOtpCodes{}
+	request := &otp.GenerateOTPRequest{PhoneNumber: "1234567890"}
+	mockCodes.On("Issue", mock.Anything, request).Return(errors.New("database is down"))
+
+	err := NewIssuingPublisher(mockPublisher, mockCodes).Publish(context.Background(), request)
+	assert.EqualError(t, err, "database is down")
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestVerifyOtp_ServerMode(t *testing.T) {
+	mockUserRepo := &mocks.IUserRepository{}
+	mockValidator := &mocks.IRequestValidator{}
+	mockEventRepo := &mocks.IEventRepository{}
+	mockCodes := &mocks.IOtpCodes{}
+	authService := NewAuthService(mockUserRepo, mockValidator, nil, nil, mockEventRepo, nil, nil, mockCodes, NewTunable(testAuthServiceConfig))
+	request := &auth.VerifyPhoneNumberRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 123456}
+	user := &models.User{Id: 1, PhoneNumber: request.PhoneNumber, CountryCode: request.CountryCode}
+	mockValidator.On("ValidateVerifyPhoneNumberRequest", request).Return(nil)
+	mockUserRepo.On("GetUserByPhoneNumberAndCountry", mock.Anything, request.CountryCode, request.PhoneNumber).Return(user, nil)
+	mockCodes.On("Verify", mock.Anything, request.PhoneNumber, request.Otp).Return(true, nil)
+	mockUserRepo.On("MarkVerified", mock.Anything, user.Id).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PHONE_VERIFIED), request.PhoneNumber).Return(nil)
+
+	err := authService.VerifyOtp(context.Background(), request)
+	assert.NoError(t, err)
+	mockCodes.AssertExpectations(t)
+	mockUserRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestLoadDeliveryKey(t *testing.T) {
+	write := func(t *testing.T, key any) string {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		assert.NoError(t, err)
+		file := filepath.Join(t.TempDir(), "delivery.pem")
+		assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600))
+		return file
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	loaded, err := LoadDeliveryKey(write(t, &key.PublicKey))
+	assert.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(loaded))
+
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+	_, err = LoadDeliveryKey(write(t, &weak.PublicKey))
+	assert.EqualError(t, err, "the otp delivery key has 1024 bits, at least 2048 are required")
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"auth-service/internal/gateway"
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	OTP_DELIVERY_QUEUED    UserEvents = "OTP_DELIVERY_QUEUED"
+	OTP_DELIVERY_SENT      UserEvents = "OTP_DELIVERY_SENT"
+	OTP_DELIVERY_DELIVERED UserEvents = "OTP_DELIVERY_DELIVERED"
+	OTP_DELIVERY_FAILED    UserEvents = "OTP_DELIVERY_FAILED"
+)
+
+// OTP_PUBLISH_FAILED_REASON is the reason of deliveries whose otp request could not be published, the error is logged
+const OTP_PUBLISH_FAILED_REASON = "the otp request could not be published"
+
+// receiptStatuses maps the statuses of delivery receipts to delivery statuses and the events recorded for them
+var receiptStatuses = map[otp.DeliveryStatus]struct {
+	status string
+	event  UserEvents
+}{
+	otp.DeliveryStatus_DELIVERY_STATUS_QUEUED:    {models.OTP_DELIVERY_QUEUED, OTP_DELIVERY_QUEUED},
+	otp.DeliveryStatus_DELIVERY_STATUS_SENT:      {models.OTP_DELIVERY_SENT, OTP_DELIVERY_SENT},
+	otp.DeliveryStatus_DELIVERY_STATUS_DELIVERED: {models.OTP_DELIVERY_DELIVERED, OTP_DELIVERY_DELIVERED},
+	otp.DeliveryStatus_DELIVERY_STATUS_FAILED:    {models.OTP_DELIVERY_FAILED, OTP_DELIVERY_FAILED},
+}
+
+// GetOtpDeliveryStatus returns the delivery of an otp sent to the phone number. Callers have to hold its delivery id,
+// returned by the request that sent the otp, so probing a phone number does not reveal whether otps are sent to it
+func (a authService) GetOtpDeliveryStatus(ctx context.Context, request *auth.GetOtpDeliveryStatusRequest) (*models.OtpDelivery, error) {
+	err := a.ValidateGetOtpDeliveryStatusRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	return a.deliveries.GetDelivery(ctx, request.DeliveryId, request.CountryCode, request.PhoneNumber)
+}
+
+// NewTrackingPublisher records every otp request as a pending delivery before publishing it, which the delivery
+// receipts of the otp service update, and marks it failed when publishing fails. Requests without a request id get
+// one before they are published
+func NewTrackingPublisher(publisher gateway.IMessagePublisher, deliveries repository.IOtpDeliveryRepository) gateway.IMessagePublisher {
+	return &trackingPublisher{publisher: publisher, deliveries: deliveries}
+}
+
+type trackingPublisher struct {
+	publisher  gateway.IMessagePublisher
+	deliveries repository.IOtpDeliveryRepository
+}
+
+func (p *trackingPublisher) Publish(ctx context.Context, request *otp.GenerateOTPRequest) error {
+	if request.RequestId == "" {
+		requestId, err := gateway.NewMessageId()
+		if err != nil {
+			return err
+		}
+		request.RequestId = requestId
+	}
+	// the delivery is recorded first so a receipt arriving right after the publish finds it, failing to record it
+	// only leaves its status unknown
+	err := p.deliveries.CreateDelivery(ctx, models.OtpDelivery{
+		RequestId:   request.RequestId,
+		CountryCode: request.CountryCode,
+		PhoneNumber: request.PhoneNumber,
+		Channel:     deliveryChannelName(request.Channel),
+	})
+	if err != nil {
+		slog.Error("recording the delivery of otp request failed", "request_id", request.RequestId, "error", err)
+	}
+	publishErr := p.publisher.Publish(ctx, request)
+	if publishErr == nil {
+		return nil
+	}
+	_, _, err = p.deliveries.UpdateDeliveryStatus(ctx, request.RequestId, models.OTP_DELIVERY_FAILED, OTP_PUBLISH_FAILED_REASON, time.Now())
+	if err != nil {
+		slog.Error("marking the delivery of otp request failed", "request_id", request.RequestId, "error", err)
+	}
+	return publishErr
+}
+
+// deliveryChannelName is SMS, VOICE or EMAIL, requests without a channel are sent as sms
+func deliveryChannelName(channel otp.DeliveryChannel) string {
+	if channel == otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED {
+		channel = otp.DeliveryChannel_DELIVERY_CHANNEL_SMS
+	}
+	return strings.TrimPrefix(channel.String(), "DELIVERY_CHANNEL_")
+}
+
+// DeliveryReceipts stores the delivery receipts published by the otp service
+type DeliveryReceipts struct {
+	deliveries repository.IOtpDeliveryRepository
+	events     repository.IEventRepository
+}
+
+func NewDeliveryReceipts(deliveries repository.IOtpDeliveryRepository, events repository.IEventRepository) *DeliveryReceipts {
+	return &DeliveryReceipts{deliveries: deliveries, events: events}
+}
+
+// Handle moves the delivery of the receipt forward and records an event for the phone number. Receipts that are
+// late, repeated or for unknown requests are dropped, an error means the receipt should be retried
+func (r *DeliveryReceipts) Handle(ctx context.Context, receipt *otp.DeliveryReceipt) error {
+	status, ok := receiptStatuses[receipt.Status]
+	if !ok {
+		slog.Warn("dropping delivery receipt with unknown status", "request_id", receipt.RequestId, "status", receipt.Status)
+		return nil
+	}
+	at := time.Unix(receipt.Timestamp, 0)
+	if receipt.Timestamp == 0 {
+		at = time.Now()
+	}
+	delivery, changed, err := r.deliveries.UpdateDeliveryStatus(ctx, receipt.RequestId, status.status, receipt.Reason, at)
+	if err != nil {
+		return err
+	}
+	if delivery == nil {
+		slog.Warn("dropping delivery receipt of unknown otp request", "request_id", receipt.RequestId)
+		return nil
+	}
+	if changed {
+		r.events.InsertEvent(ctx, string(status.event), delivery.PhoneNumber)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestTrackingPublisher_RecordsPublishedRequestsAsPending(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	request := &otp.GenerateOTPRequest{CountryCode: 91, PhoneNumber: "1234567890", Channel: otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE}
+	mockPublisher.On("Publish", mock.Anything, request).Return(nil)
+	mockDeliveries.On("CreateDelivery", mock.Anything, mock.MatchedBy(func(delivery models.OtpDelivery) bool {
+		return delivery.RequestId == request.RequestId && delivery.PhoneNumber == "1234567890" && delivery.Channel == "VOICE"
+	})).Return(nil)
+
+	err := NewTrackingPublisher(mockPublisher, mockDeliveries).Publish(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Len(t, request.RequestId, 32)
+	mockDeliveries.AssertExpectations(t)
+}
+
+func TestTrackingPublisher_KeepsTheRequestIdOfRetriedRequests(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	request := &otp.GenerateOTPRequest{RequestId: "request-1", PhoneNumber: "1234567890"}
+	mockPublisher.On("Publish", mock.Anything, request).Return(nil)
+	mockDeliveries.On("CreateDelivery", mock.Anything, mock.MatchedBy(func(delivery models.OtpDelivery) bool {
+		return delivery.RequestId == "request-1" && delivery.Channel == "SMS"
+	})).Return(errors.New("database down"))
+
+	err := NewTrackingPublisher(mockPublisher, mockDeliveries).Publish(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "request-1", request.RequestId)
+}
+
+func TestTrackingPublisher_RecordsTheDeliveryBeforePublishing(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	recorded := false
+	mockDeliveries.On("CreateDelivery", mock.Anything, mock.Anything).Run(func(mock.Arguments) { recorded = true }).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, mock.Anything).Run(func(mock.Arguments) {
+		assert.True(t, recorded, "the delivery is recorded before the request is published")
+	}).Return(nil)
+
+	err := NewTrackingPublisher(mockPublisher, mockDeliveries).Publish(context.Background(), &otp.GenerateOTPRequest{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	mockPublisher.AssertExpectations(t)
+}
+
+func TestTrackingPublisher_MarksFailedPublishesAsFailed(t *testing.T) {
+	mockPublisher := &mocks.IMessagePublisher{}
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	request := &otp.GenerateOTPRequest{RequestId: "request-1", PhoneNumber: "1234567890"}
+	mockDeliveries.On("CreateDelivery", mock.Anything, mock.Anything).Return(nil)
+	mockPublisher.On("Publish", mock.Anything, request).Return(errors.New("broker down"))
+	mockDeliveries.On("UpdateDeliveryStatus", mock.Anything, "request-1", models.OTP_DELIVERY_FAILED, OTP_PUBLISH_FAILED_REASON, mock.Anything).
+		Return(&models.OtpDelivery{RequestId: "request-1", Status: models.OTP_DELIVERY_FAILED}, true, nil)
+
+	err := NewTrackingPublisher(mockPublisher, mockDeliveries).Publish(context.Background(), request)
+	assert.EqualError(t, err, "broker down")
+	mockDeliveries.AssertExpectations(t)
+}
+
+func TestDeliveryReceipts_RecordsAnEventWhenTheStatusChanges(t *testing.T) {
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	mockEventRepo := &mocks.IEventRepository{}
+	receipt := &otp.DeliveryReceipt{RequestId: "request-1", Status: otp.DeliveryStatus_DELIVERY_STATUS_FAILED, Reason: "unreachable", Timestamp: 1700000000}
+	mockDeliveries.On("UpdateDeliveryStatus", mock.Anything, "request-1", models.OTP_DELIVERY_FAILED, "unreachable", time.Unix(1700000000, 0)).
+		Return(&models.OtpDelivery{RequestId: "request-1", PhoneNumber: "1234567890", Status: models.OTP_DELIVERY_FAILED}, true, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(OTP_DELIVERY_FAILED), "1234567890").Return()
+
+	err := NewDeliveryReceipts(mockDeliveries, mockEventRepo).Handle(context.Background(), receipt)
+	assert.NoError(t, err)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestDeliveryReceipts_IgnoresLateAndUnknownReceipts(t *testing.T) {
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	mockEventRepo := &mocks.IEventRepository{}
+	mockDeliveries.On("UpdateDeliveryStatus", mock.Anything, "late", models.OTP_DELIVERY_QUEUED, "", mock.Anything).
+		Return(&models.OtpDelivery{RequestId: "late", Status: models.OTP_DELIVERY_DELIVERED}, false, nil)
+	mockDeliveries.On("UpdateDeliveryStatus", mock.Anything, "unknown", models.OTP_DELIVERY_SENT, "", mock.Anything).
+		Return(nil, false, nil)
+	receipts := NewDeliveryReceipts(mockDeliveries, mockEventRepo)
+
+	assert.NoError(t, receipts.Handle(context.Background(), &otp.DeliveryReceipt{RequestId: "late", Status: otp.DeliveryStatus_DELIVERY_STATUS_QUEUED}))
+	assert.NoError(t, receipts.Handle(context.Background(), &otp.DeliveryReceipt{RequestId: "unknown", Status: otp.DeliveryStatus_DELIVERY_STATUS_SENT}))
+	assert.NoError(t, receipts.Handle(context.Background(), &otp.DeliveryReceipt{RequestId: "unspecified"}))
+	mockEventRepo.AssertNotCalled(t, "InsertEvent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestDeliveryReceipts_FailsWhenTheStatusCanNotBeStored(t *testing.T) {
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	mockDeliveries.On("UpdateDeliveryStatus", mock.Anything, "request-1", models.OTP_DELIVERY_SENT, "", mock.Anything).
+		Return(nil, false, errors.New("database down"))
+
+	err := NewDeliveryReceipts(mockDeliveries, nil).Handle(context.Background(), &otp.DeliveryReceipt{RequestId: "request-1", Status: otp.DeliveryStatus_DELIVERY_STATUS_SENT})
+	assert.EqualError(t, err, "database down")
+}
+
+func TestGetOtpDeliveryStatus_ReturnsTheDeliveryOfTheId(t *testing.T) {
+	mockValidator := &mocks.IRequestValidator{}
+	mockDeliveries := &mocks.IOtpDeliveryRepository{}
+	authService := NewAuthService(nil, mockValidator, nil, nil, nil, nil, mockDeliveries, nil, NewTunable(testAuthServiceConfig))
+	request := &auth.GetOtpDeliveryStatusRequest{CountryCode: 91, PhoneNumber: "1234567890", DeliveryId: "request-1"}
+	mockValidator.On("ValidateGetOtpDeliveryStatusRequest", request).Return(nil)
+	mockDeliveries.On("GetDelivery", mock.Anything, "request-1", int32(91), "1234567890").
+		Return(&models.OtpDelivery{RequestId: "request-1", CountryCode: 91, PhoneNumber: "1234567890", Status: models.OTP_DELIVERY_SENT}, nil)
+
+	delivery, err := authService.GetOtpDeliveryStatus(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OTP_DELIVERY_SENT, delivery.Status)
+}
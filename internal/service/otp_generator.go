@@ -13,24 +13,41 @@ type IGenerator interface {
 	Generate(phoneNumber string) (int32, error)
 }
 
-func NewOtpGenerator(key string, interval time.Duration) IGenerator {
+// IRotatedGenerator is a generator whose secret key can be rotated, otps derived with the previous keys stay valid
+type IRotatedGenerator interface {
+	GeneratePrevious(phoneNumber string) ([]int32, error)
+}
+
+func NewOtpGenerator(keys *OtpKeys, interval time.Duration) IGenerator {
 	return &otpGenerator{
-		secretKey: key,
-		interval:  interval,
+		keys:     keys,
+		interval: interval,
 	}
 }
 
 type otpGenerator struct {
-	secretKey string
-	interval  time.Duration
+	keys     *OtpKeys
+	interval time.Duration
 }
 
 func (o otpGenerator) Generate(phoneNumber string) (int32, error) {
-	return o.generateOtp(phoneNumber)
+	return o.generateOtp(phoneNumber, o.keys.current())
+}
+
+// GeneratePrevious derives the otps of the phone number with the previous keys
+func (o otpGenerator) GeneratePrevious(phoneNumber string) ([]int32, error) {
+	var otps []int32
+	for _, key := range o.keys.previous() {
+		otp, err := o.otpHelper(phoneNumber, key)
+		if err != nil {
+			return nil, err
+		}
+		otps = append(otps, otp)
+	}
+	return otps, nil
 }
 
-func (o otpGenerator) generateOtp(phoneNumber string) (int32, error) {
-	secretKey := o.secretKey
+func (o otpGenerator) generateOtp(phoneNumber string, secretKey string) (int32, error) {
 	OTP, err := o.otpHelper(phoneNumber, secretKey)
 	if err != nil {
 		fmt.Println("Error generating OTP:", err)
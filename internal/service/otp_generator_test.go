@@ -11,7 +11,7 @@ func TestOtpGenerator_Generate(t *testing.T) {
 	mockKey := "mock-key"
 	mockInterval := time.Second * 30
 
-	otpGen := service.NewOtpGenerator(mockKey, mockInterval)
+	otpGen := service.NewOtpGenerator(service.NewOtpKeys(mockKey, nil), mockInterval)
 
 	t.Run("Generate OTP successfully", func(t *testing.T) {
 		otp, err := otpGen.Generate(phoneNumber)
@@ -23,3 +23,22 @@ func TestOtpGenerator_Generate(t *testing.T) {
 		}
 	})
 }
+
+func TestOtpGenerator_GeneratePrevious(t *testing.T) {
+	phoneNumber := "+911234567890"
+	keys := service.NewOtpKeys("old-key", nil)
+	otpGen := service.NewOtpGenerator(keys, time.Hour)
+	oldOtp, err := otpGen.Generate(phoneNumber)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	keys.Rotate("new-key", []string{"old-key"})
+	previous, err := otpGen.(service.IRotatedGenerator).GeneratePrevious(phoneNumber)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(previous) != 1 || previous[0] != oldOtp {
+		t.Errorf("Expected the otp of the old key %d, got %v", oldOtp, previous)
+	}
+}
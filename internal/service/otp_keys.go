@@ -0,0 +1,42 @@
+package service
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// OtpKeys are the secret keys otps are derived and hashed with. The current key creates otps, previous keys are only
+// accepted for otps created before the current key was rotated in. Rotate swaps them while otps are being checked
+type OtpKeys struct {
+	keyring atomic.Pointer[otpKeyring]
+}
+
+type otpKeyring struct {
+	current  string
+	previous []string
+}
+
+func NewOtpKeys(current string, previous []string) *OtpKeys {
+	keys := &OtpKeys{}
+	keys.Rotate(current, previous)
+	return keys
+}
+
+// Rotate makes current the key new otps are created with
+func (k *OtpKeys) Rotate(current string, previous []string) {
+	k.keyring.Store(&otpKeyring{current: current, previous: slices.Clone(previous)})
+}
+
+func (k *OtpKeys) current() string {
+	return k.keyring.Load().current
+}
+
+func (k *OtpKeys) previous() []string {
+	return k.keyring.Load().previous
+}
+
+// all returns the current key followed by the previous keys
+func (k *OtpKeys) all() []string {
+	keyring := k.keyring.Load()
+	return append([]string{keyring.current}, keyring.previous...)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// resendChannels is the escalation ladder, the n-th resend uses the n-th channel and the last one after that.
+// Email is only used when the user confirmed the email long enough ago, see canEscalateToEmail
+var resendChannels = []otp.DeliveryChannel{
+	otp.DeliveryChannel_DELIVERY_CHANNEL_SMS,
+	otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE,
+	otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL,
+}
+
+var (
+	ErrNoOtpToResend      = errors.New("no otp was requested for this phone number, please request a login otp first")
+	ErrResendLimitReached = errors.New("too many otps were resent, please try again later")
+)
+
+// ResendCooldownError is returned when an otp was sent to the phone number less than the cooldown ago
+type ResendCooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ResendCooldownError) Error() string {
+	return fmt.Sprintf("an otp was sent recently, please retry after %d seconds", int(math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// nextResendChannel decides from the otp events of the resend window whether another otp can be sent and on which channel.
+// Unverified users can always resend their signup otp, verified users only after requesting a login otp.
+func (c AuthServiceConfig) nextResendChannel(user *models.User, events []models.UserEvent, now time.Time) (otp.DeliveryChannel, error) {
+	requested := !user.Verified
+	resends := 0
+	var lastSent time.Time
+	for _, event := range events {
+		switch UserEvents(event.Event) {
+		case LOGIN_REQUEST:
+			requested = true
+			resends = 0
+			lastSent = event.CreatedAt
+		case SIGN_IN_REQUEST_OTP:
+			resends = 0
+			lastSent = event.CreatedAt
+		case OTP_RESENT:
+			resends++
+			lastSent = event.CreatedAt
+		}
+	}
+	if !requested {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, ErrNoOtpToResend
+	}
+	if resends >= c.MaxResends {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, ErrResendLimitReached
+	}
+	if wait := lastSent.Add(c.ResendCooldown).Sub(now); wait > 0 {
+		return otp.DeliveryChannel_DELIVERY_CHANNEL_UNSPECIFIED, &ResendCooldownError{RetryAfter: wait}
+	}
+	channels := resendChannels
+	if !c.canEscalateToEmail(user, now) {
+		// the ladder stops at voice
+		channels = channels[:len(channels)-1]
+	}
+	return channels[min(resends, len(channels)-1)], nil
+}
+
+// canEscalateToEmail reports whether otps of the phone number can be resent to the email of the user. The email has to
+// be confirmed and in use for EmailEscalationAge, an email given at signup or changed recently may not be the owner's
+func (c AuthServiceConfig) canEscalateToEmail(user *models.User, now time.Time) bool {
+	return user.Email != "" && !user.EmailConfirmedAt.IsZero() && now.Sub(user.EmailConfirmedAt) >= c.EmailEscalationAge
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func otpEvents(now time.Time, events ...UserEvents) []models.UserEvent {
+	var userEvents []models.UserEvent
+	for i, event := range events {
+		// one minute apart, the last one a minute before now
+		at := now.Add(time.Duration(i-len(events)) * time.Minute)
+		userEvents = append(userEvents, models.UserEvent{Id: int64(i + 1), Event: string(event), CreatedAt: at})
+	}
+	return userEvents
+}
+
+var (
+	unverifiedUser = &models.User{Email: "john@example.com"}
+	verifiedUser   = &models.User{Email: "john@example.com", Verified: true}
+)
+
+func TestNextResendChannel_EscalatesOnSuccessiveResends(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 5, EmailEscalationAge: 24 * time.Hour}
+	user := &models.User{Email: "john@example.com", EmailConfirmedAt: now.Add(-48 * time.Hour)}
+	cases := []struct {
+		events  []UserEvents
+		channel otp.DeliveryChannel
+	}{
+		{[]UserEvents{SIGN_IN_REQUEST_OTP}, otp.DeliveryChannel_DELIVERY_CHANNEL_SMS},
+		{[]UserEvents{SIGN_IN_REQUEST_OTP, OTP_RESENT}, otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE},
+		{[]UserEvents{SIGN_IN_REQUEST_OTP, OTP_RESENT, OTP_RESENT}, otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL},
+		{[]UserEvents{SIGN_IN_REQUEST_OTP, OTP_RESENT, OTP_RESENT, OTP_RESENT}, otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL},
+		// events unrelated to sending otps are ignored
+		{[]UserEvents{SIGN_IN_REQUEST_OTP, INCORRECT_OTP}, otp.DeliveryChannel_DELIVERY_CHANNEL_SMS},
+	}
+	for _, c := range cases {
+		channel, err := config.nextResendChannel(user, otpEvents(now, c.events...), now)
+		assert.NoError(t, err)
+		assert.Equal(t, c.channel, channel, "events %v", c.events)
+	}
+}
+
+func TestNextResendChannel_StopsAtVoiceWithoutAnEstablishedEmail(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 5, EmailEscalationAge: 24 * time.Hour}
+	events := otpEvents(now, LOGIN_REQUEST, OTP_RESENT, OTP_RESENT)
+	for name, user := range map[string]*models.User{
+		"signup email":           verifiedUser,
+		"recently changed email": {Email: "john@example.com", Verified: true, EmailConfirmedAt: now.Add(-time.Hour)},
+		"no email":               {Verified: true, EmailConfirmedAt: now.Add(-48 * time.Hour)},
+	} {
+		channel, err := config.nextResendChannel(user, events, now)
+		assert.NoError(t, err)
+		assert.Equal(t, otp.DeliveryChannel_DELIVERY_CHANNEL_VOICE, channel, name)
+	}
+}
+
+func TestNextResendChannel_NewLoginRequestRestartsTheLadder(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 2}
+	channel, err := config.nextResendChannel(verifiedUser, otpEvents(now, LOGIN_REQUEST, OTP_RESENT, OTP_RESENT, LOGIN_REQUEST), now)
+	assert.NoError(t, err)
+	assert.Equal(t, otp.DeliveryChannel_DELIVERY_CHANNEL_SMS, channel)
+}
+
+func TestNextResendChannel_VerifiedUsersNeedALoginRequest(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 5}
+	_, err := config.nextResendChannel(verifiedUser, otpEvents(now, SIGN_IN_REQUEST_OTP, PHONE_VERIFIED), now)
+	assert.ErrorIs(t, err, ErrNoOtpToResend)
+	_, err = config.nextResendChannel(verifiedUser, nil, now)
+	assert.ErrorIs(t, err, ErrNoOtpToResend)
+}
+
+func TestNextResendChannel_UnverifiedUsersCanResendAfterTheWindow(t *testing.T) {
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 5}
+	channel, err := config.nextResendChannel(unverifiedUser, nil, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, otp.DeliveryChannel_DELIVERY_CHANNEL_SMS, channel)
+}
+
+func TestNextResendChannel_Cooldown(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 90 * time.Second, MaxResends: 5}
+	_, err := config.nextResendChannel(unverifiedUser, otpEvents(now, SIGN_IN_REQUEST_OTP, OTP_RESENT), now)
+	var cooldown *ResendCooldownError
+	if assert.ErrorAs(t, err, &cooldown) {
+		assert.Equal(t, 30*time.Second, cooldown.RetryAfter)
+		assert.EqualError(t, err, "an otp was sent recently, please retry after 30 seconds")
+	}
+}
+
+func TestNextResendChannel_Limit(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceConfig{ResendCooldown: 30 * time.Second, MaxResends: 2}
+	_, err := config.nextResendChannel(unverifiedUser, otpEvents(now, SIGN_IN_REQUEST_OTP, OTP_RESENT, OTP_RESENT), now)
+	assert.ErrorIs(t, err, ErrResendLimitReached)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"auth-service/internal/gateway"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"log/slog"
+	"time"
+)
+
+// OTP_REQUEST_TOPIC marks outbox messages holding a serialized otp.GenerateOTPRequest
+const OTP_REQUEST_TOPIC = "otp.generate"
+
+// newOtpOutboxMessage stores the message context of ctx with the message, the relay publishes it outside of the rpc.
+// The request id of the otp request is returned as its delivery id
+func newOtpOutboxMessage(ctx context.Context, user *models.User) (models.OutboxMessage, string, error) {
+	// the request id is stored with the message, so every attempt of the relay publishes the same request
+	requestId, err := gateway.NewMessageId()
+	if err != nil {
+		return models.OutboxMessage{}, "", err
+	}
+	request := newOtpRequest(user)
+	request.RequestId = requestId
+	payload, err := proto.Marshal(request)
+	if err != nil {
+		return models.OutboxMessage{}, "", err
+	}
+	messageContext := gateway.MessageContextFrom(ctx)
+	return models.OutboxMessage{
+		Topic:         OTP_REQUEST_TOPIC,
+		Payload:       payload,
+		CorrelationId: messageContext.CorrelationId,
+		TraceParent:   messageContext.TraceParent,
+		TraceState:    messageContext.TraceState,
+		PhoneNumber:   user.PhoneNumber,
+	}, requestId, nil
+}
+
+type OutboxRelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease hides claimed messages from other relays while they are being published
+	Lease time.Duration
+	// MinRetryDelay doubles with every failed attempt up to MaxRetryDelay
+	MinRetryDelay time.Duration
+	MaxRetryDelay time.Duration
+	// MaxAttempts failed publishes dead letter a message, so a message that can never be published is not retried forever
+	MaxAttempts int32
+	// MaxAge dead letters messages instead of publishing them, an otp delivered after it expired is useless
+	MaxAge time.Duration
+}
+
+// OutboxRelay publishes messages written to the outbox through the message publisher
+type OutboxRelay struct {
+	outbox    repository.IOutboxRepository
+	publisher gateway.IMessagePublisher
+	config    OutboxRelayConfig
+}
+
+func NewOutboxRelay(outbox repository.IOutboxRepository, publisher gateway.IMessagePublisher, config OutboxRelayConfig) *OutboxRelay {
+	return &OutboxRelay{outbox: outbox, publisher: publisher, config: config}
+}
+
+// Run relays pending messages every poll interval until the context is cancelled
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RelayPending(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("outbox relay failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayPending publishes one batch of due messages and returns how many were delivered
+func (r *OutboxRelay) RelayPending(ctx context.Context) (int, error) {
+	messages, err := r.outbox.ClaimPending(ctx, r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, message := range messages {
+		if age := time.Since(message.CreatedAt); age > r.config.MaxAge {
+			if err = r.deadLetter(ctx, message, fmt.Sprintf("expired after %v", age.Round(time.Second))); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err = r.publish(ctx, message); err != nil {
+			slog.Warn("publishing outbox message failed", "id", message.Id, "attempt", message.Attempts+1, "error", err)
+			if message.Attempts+1 >= r.config.MaxAttempts {
+				err = r.deadLetter(ctx, message, err.Error())
+			} else {
+				err = r.outbox.MarkFailed(ctx, message.Id, err.Error(), r.retryDelay(message.Attempts))
+			}
+			if err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if err = r.outbox.MarkDelivered(ctx, message.Id); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func (r *OutboxRelay) deadLetter(ctx context.Context, message models.OutboxMessage, reason string) error {
+	slog.Error("dead lettering outbox message", "id", message.Id, "topic", message.Topic, "attempts", message.Attempts, "reason", reason)
+	return r.outbox.DeadLetter(ctx, message.Id, reason)
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, message models.OutboxMessage) error {
+	switch message.Topic {
+	case OTP_REQUEST_TOPIC:
+		request := &otp.GenerateOTPRequest{}
+		if err := proto.Unmarshal(message.Payload, request); err != nil {
+			return err
+		}
+		ctx = gateway.WithMessageContext(ctx, gateway.MessageContext{
+			CorrelationId: message.CorrelationId,
+			TraceParent:   message.TraceParent,
+			TraceState:    message.TraceState,
+		})
+		return r.publisher.Publish(ctx, request)
+	default:
+		return fmt.Errorf("unknown outbox topic %s", message.Topic)
+	}
+}
+
+func (r *OutboxRelay) retryDelay(attempts int32) time.Duration {
+	delay := r.config.MinRetryDelay
+	for i := int32(0); i < attempts && delay < r.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.config.MaxRetryDelay {
+		return r.config.MaxRetryDelay
+	}
+	return delay
+}
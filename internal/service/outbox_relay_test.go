@@ -0,0 +1,142 @@
+package service
+
+import (
+	"auth-service/internal/gateway"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/mocks"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+var testRelayConfig = OutboxRelayConfig{
+	PollInterval:  time.Second,
+	BatchSize:     10,
+	Lease:         30 * time.Second,
+	MinRetryDelay: time.Second,
+	MaxRetryDelay: 10 * time.Second,
+	MaxAttempts:   5,
+	MaxAge:        10 * time.Minute,
+}
+
+func otpOutboxMessage(t *testing.T, id int64, attempts int32) models.OutboxMessage {
+	message, _, err := newOtpOutboxMessage(context.Background(), &models.User{PhoneNumber: "1234567890", CountryCode: 91})
+	assert.NoError(t, err)
+	message.Id = id
+	message.Attempts = attempts
+	message.CreatedAt = time.Now()
+	return message
+}
+
+func TestOutboxRelay_PublishesAndMarksDelivered(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{otpOutboxMessage(t, 1, 0)}, nil)
+	publisher.On("Publish", mock.Anything, mock.MatchedBy(func(request *otp.GenerateOTPRequest) bool {
+		return request.PhoneNumber == "1234567890" && request.CountryCode == 91
+	})).Return(nil)
+	outbox.On("MarkDelivered", mock.Anything, int64(1)).Return(nil)
+	delivered, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	outbox.AssertExpectations(t)
+	publisher.AssertExpectations(t)
+}
+
+func TestOutboxRelay_PublishFailureIsRetriedWithBackoff(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{otpOutboxMessage(t, 1, 2)}, nil)
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("broker down"))
+	outbox.On("MarkFailed", mock.Anything, int64(1), "broker down", 4*time.Second).Return(nil)
+	delivered, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	outbox.AssertNotCalled(t, "MarkDelivered", mock.Anything, mock.Anything)
+	outbox.AssertExpectations(t)
+}
+
+func TestOutboxRelay_UnknownTopicIsMarkedFailed(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{{Id: 7, Topic: "unknown", CreatedAt: time.Now()}}, nil)
+	outbox.On("MarkFailed", mock.Anything, int64(7), "unknown outbox topic unknown", time.Second).Return(nil)
+	_, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	outbox.AssertExpectations(t)
+}
+
+func TestOutboxRelay_ExpiredMessagesAreDeadLettered(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	message := otpOutboxMessage(t, 1, 0)
+	message.CreatedAt = time.Now().Add(-11 * time.Minute)
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{message}, nil)
+	outbox.On("DeadLetter", mock.Anything, int64(1), "expired after 11m0s").Return(nil)
+	delivered, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+	publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	outbox.AssertExpectations(t)
+}
+
+func TestOutboxRelay_LastFailedAttemptIsDeadLettered(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{otpOutboxMessage(t, 1, 4)}, nil)
+	publisher.On("Publish", mock.Anything, mock.Anything).Return(errors.New("broker down"))
+	outbox.On("DeadLetter", mock.Anything, int64(1), "broker down").Return(nil)
+	_, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	outbox.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	outbox.AssertExpectations(t)
+}
+
+func TestOutboxRelay_ClaimFailure(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	relay := NewOutboxRelay(outbox, &mocks.IMessagePublisher{}, testRelayConfig)
+	outbox.On("ClaimPending", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("db down"))
+	_, err := relay.RelayPending(context.Background())
+	assert.EqualError(t, err, "db down")
+}
+
+func TestOutboxRelay_RetryDelayIsCapped(t *testing.T) {
+	relay := NewOutboxRelay(nil, nil, testRelayConfig)
+	assert.Equal(t, time.Second, relay.retryDelay(0))
+	assert.Equal(t, 2*time.Second, relay.retryDelay(1))
+	assert.Equal(t, 8*time.Second, relay.retryDelay(3))
+	assert.Equal(t, 10*time.Second, relay.retryDelay(4))
+	assert.Equal(t, 10*time.Second, relay.retryDelay(60))
+}
+
+func TestOutboxRelay_PublishesWithTheContextOfTheRpc(t *testing.T) {
+	outbox := &mocks.IOutboxRepository{}
+	publisher := &mocks.IMessagePublisher{}
+	relay := NewOutboxRelay(outbox, publisher, testRelayConfig)
+	messageContext := gateway.MessageContext{CorrelationId: "rpc-1", TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	message, _, err := newOtpOutboxMessage(gateway.WithMessageContext(context.Background(), messageContext), &models.User{PhoneNumber: "1234567890"})
+	assert.NoError(t, err)
+	message.Id = 1
+	message.CreatedAt = time.Now()
+	outbox.On("ClaimPending", mock.Anything, 10, 30*time.Second).Return([]models.OutboxMessage{message}, nil)
+	publisher.On("Publish", mock.MatchedBy(func(ctx context.Context) bool {
+		return gateway.MessageContextFrom(ctx) == messageContext
+	}), mock.MatchedBy(func(request *otp.GenerateOTPRequest) bool {
+		return request.RequestId != ""
+	})).Return(nil)
+	outbox.On("MarkDelivered", mock.Anything, int64(1)).Return(nil)
+	delivered, err := relay.RelayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	publisher.AssertExpectations(t)
+}
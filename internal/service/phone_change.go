@@ -0,0 +1,130 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrFreshLoginRequired    = errors.New("please login again with your current phone number to change it")
+	ErrNoPendingPhoneChange  = errors.New("there is no phone number change to confirm")
+	ErrSamePhoneNumber       = errors.New("the new phone number is the current one")
+	ErrPhoneNumberUnverified = errors.New("verify phone number to change it")
+)
+
+// StartPhoneChange stores the new phone number as pending and sends an otp to it. The user has to have logged in
+// with the current number within the fresh login window and confirm a fresh otp of it, the current number stays the
+// login identity until confirmed. Numbers held by another user are rejected before anything is sent to them.
+func (a authService) StartPhoneChange(ctx context.Context, request *auth.StartPhoneChangeRequest) (*auth.User, string, error) {
+	err := a.ValidateStartPhoneChangeRequest(request)
+	if err != nil {
+		return nil, "", err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return nil, "", err
+	}
+	if !user.Verified {
+		return nil, "", ErrPhoneNumberUnverified
+	}
+	if user.CountryCode == request.CountryCode && user.PhoneNumber == request.PhoneNumber {
+		return nil, "", ErrSamePhoneNumber
+	}
+	events, err := a.ListRecentEvents(ctx, user.PhoneNumber, a.config.Load().FreshLoginWindow)
+	if err != nil {
+		return nil, "", err
+	}
+	if !hasFreshLogin(events, user.SessionsRevokedAt) {
+		return nil, "", ErrFreshLoginRequired
+	}
+	err = a.confirmPhoneOtp(ctx, user, request.Otp)
+	if err != nil {
+		return nil, "", err
+	}
+	taken, err := a.IsPhoneNumberTaken(ctx, request.CountryCode, request.PhoneNumber)
+	if err != nil {
+		return nil, "", err
+	}
+	if taken {
+		return nil, "", &models.AlreadyExistsError{Field: models.FIELD_PHONE_NUMBER}
+	}
+	changed := *user
+	changed.PendingCountryCode, changed.PendingPhoneNumber = request.CountryCode, request.PhoneNumber
+	updated, err := a.UpdateUser(ctx, &changed, user.Version)
+	if err != nil {
+		return nil, "", err
+	}
+	deliveryId, err := a.publishOtp(ctx, newPhoneConfirmationRequest(updated))
+	if err != nil {
+		return nil, "", err
+	}
+	a.InsertEvent(ctx, string(PHONE_CHANGE_REQUESTED), updated.PhoneNumber)
+	return models.ToProto(updated), deliveryId, nil
+}
+
+// ConfirmPhoneChange swaps in the pending phone number once the otp sent to it is confirmed. Every session of the
+// user is revoked by stamping SessionsRevokedAt and the login of the old number is ended with a LOGOUT event, the user
+// logs in with the new number afterwards.
+func (a authService) ConfirmPhoneChange(ctx context.Context, request *auth.ConfirmPhoneChangeRequest) (*auth.User, error) {
+	err := a.ValidateConfirmPhoneChangeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.PendingPhoneNumber == "" {
+		return nil, ErrNoPendingPhoneChange
+	}
+	matches, err := a.checkOtp(ctx, user.PendingPhoneNumber, request.Otp)
+	if err != nil {
+		return nil, errors.New("unable to verify the OTP, Please try again after some time")
+	}
+	if !matches {
+		a.InsertEvent(ctx, string(INCORRECT_OTP), user.PhoneNumber)
+		return nil, errors.New("invalid OTP")
+	}
+	changed, err := a.ChangePhoneNumber(ctx, repository.PhoneNumberChange{
+		UserId:          user.Id,
+		ExpectedVersion: user.Version,
+		OldNumberEvents: []string{string(PHONE_CHANGED), string(LOGOUT)},
+		NewNumberEvents: []string{string(PHONE_CHANGED)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return models.ToProto(changed), nil
+}
+
+// hasFreshLogin reports whether the events contain a successful login after the sessions were revoked that was not
+// followed by a logout
+func hasFreshLogin(events []models.UserEvent, revokedAt time.Time) bool {
+	loggedIn := false
+	for _, event := range events {
+		if event.CreatedAt.Before(revokedAt) {
+			continue
+		}
+		switch UserEvents(event.Event) {
+		case LOGIN_SUCCESSFUL:
+			loggedIn = true
+		case LOGOUT:
+			loggedIn = false
+		}
+	}
+	return loggedIn
+}
+
+// newPhoneConfirmationRequest sends an otp derived from the pending phone number to it
+func newPhoneConfirmationRequest(user *models.User) *otp.GenerateOTPRequest {
+	return &otp.GenerateOTPRequest{
+		CountryCode: user.PendingCountryCode,
+		PhoneNumber: user.PendingPhoneNumber,
+		Channel:     otp.DeliveryChannel_DELIVERY_CHANNEL_SMS,
+	}
+}
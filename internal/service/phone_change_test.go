@@ -0,0 +1,167 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/repository"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestStartPhoneChange_SendsOtpToTheNewNumber(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567", Otp: 123456}
+	updated := storedProfile()
+	updated.PendingCountryCode, updated.PendingPhoneNumber, updated.Version = 1, "5551234567", 4
+	mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now(), LOGIN_REQUEST, LOGIN_SUCCESSFUL), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(123456), nil)
+	mockUserRepo.On("IsPhoneNumberTaken", mock.Anything, int32(1), "5551234567").Return(false, nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.PhoneNumber == "1234567890" && user.PendingCountryCode == 1 && user.PendingPhoneNumber == "5551234567"
+	}), int64(3)).Return(updated, nil)
+	var published *otp.GenerateOTPRequest
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(otpRequest *otp.GenerateOTPRequest) bool {
+		published = otpRequest
+		return otpRequest.CountryCode == 1 && otpRequest.PhoneNumber == "5551234567" &&
+			otpRequest.Channel == otp.DeliveryChannel_DELIVERY_CHANNEL_SMS
+	})).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PHONE_CHANGE_REQUESTED), "1234567890").Return()
+	user, deliveryId, err := authService.StartPhoneChange(context.Background(), request)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, deliveryId)
+	assert.Equal(t, published.RequestId, deliveryId)
+	assert.Equal(t, "1234567890", user.PhoneNumber)
+	assert.Equal(t, "5551234567", user.PendingPhoneNumber)
+	mockPublisher.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestStartPhoneChange_RequiresAFreshLogin(t *testing.T) {
+	cases := map[string][]models.UserEvent{
+		"no login":            nil,
+		"only a login otp":    otpEvents(time.Now(), LOGIN_REQUEST),
+		"logged out after it": otpEvents(time.Now(), LOGIN_SUCCESSFUL, LOGOUT),
+	}
+	for name, events := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+			request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567"}
+			mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+			mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+			mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).Return(events, nil)
+			_, _, err := authService.StartPhoneChange(context.Background(), request)
+			assert.ErrorIs(t, err, ErrFreshLoginRequired)
+			mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+			mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestStartPhoneChange_LoginsBeforeTheRevocationAreNotFresh(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567", Otp: 123456}
+	user := storedProfile()
+	user.SessionsRevokedAt = time.Now()
+	mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(user, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now().Add(-time.Minute), LOGIN_SUCCESSFUL), nil)
+	_, _, err := authService.StartPhoneChange(context.Background(), request)
+	assert.ErrorIs(t, err, ErrFreshLoginRequired)
+}
+
+func TestStartPhoneChange_RequiresTheOtpOfTheCurrentNumber(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567", Otp: 111111}
+	mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now(), LOGIN_SUCCESSFUL), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(123456), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, _, err := authService.StartPhoneChange(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestStartPhoneChange_RejectsANumberOfAnotherUser(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 1, PhoneNumber: "5551234567", Otp: 123456}
+	mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now(), LOGIN_SUCCESSFUL), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(123456), nil)
+	mockUserRepo.On("IsPhoneNumberTaken", mock.Anything, int32(1), "5551234567").Return(true, nil)
+	_, _, err := authService.StartPhoneChange(context.Background(), request)
+	var exists *models.AlreadyExistsError
+	if assert.ErrorAs(t, err, &exists) {
+		assert.Equal(t, models.FIELD_PHONE_NUMBER, exists.Field)
+	}
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+}
+
+func TestStartPhoneChange_RejectsTheCurrentNumber(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.StartPhoneChangeRequest{UserId: 1, CountryCode: 91, PhoneNumber: "1234567890"}
+	mockValidator.On("ValidateStartPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	_, _, err := authService.StartPhoneChange(context.Background(), request)
+	assert.ErrorIs(t, err, ErrSamePhoneNumber)
+	mockEventRepo.AssertNotCalled(t, "ListRecentEvents", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfirmPhoneChange_SwapsTheNumbers(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, _, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmPhoneChangeRequest{UserId: 1, Otp: 654321}
+	pending := storedProfile()
+	pending.PendingCountryCode, pending.PendingPhoneNumber = 1, "5551234567"
+	mockValidator.On("ValidateConfirmPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(pending, nil)
+	mockGenerator.On("Generate", "5551234567").Return(int32(654321), nil)
+	mockUserRepo.On("ChangePhoneNumber", mock.Anything, repository.PhoneNumberChange{
+		UserId:          1,
+		ExpectedVersion: 3,
+		OldNumberEvents: []string{string(PHONE_CHANGED), string(LOGOUT)},
+		NewNumberEvents: []string{string(PHONE_CHANGED)},
+	}).Return(&models.User{Id: 1, CountryCode: 1, PhoneNumber: "5551234567", Version: 4, SessionsRevokedAt: time.Unix(1700000000, 0)}, nil)
+	user, err := authService.ConfirmPhoneChange(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), user.CountryCode)
+	assert.Equal(t, "5551234567", user.PhoneNumber)
+	assert.Equal(t, int64(1700000000), user.SessionsRevokedAt)
+	mockUserRepo.AssertExpectations(t)
+}
+
+func TestConfirmPhoneChange_InvalidOtp(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmPhoneChangeRequest{UserId: 1, Otp: 111111}
+	pending := storedProfile()
+	pending.PendingCountryCode, pending.PendingPhoneNumber = 1, "5551234567"
+	mockValidator.On("ValidateConfirmPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(pending, nil)
+	mockGenerator.On("Generate", "5551234567").Return(int32(654321), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, err := authService.ConfirmPhoneChange(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockUserRepo.AssertNotCalled(t, "ChangePhoneNumber", mock.Anything, mock.Anything)
+}
+
+func TestConfirmPhoneChange_NothingPending(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, _, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmPhoneChangeRequest{UserId: 1, Otp: 111111}
+	mockValidator.On("ValidateConfirmPhoneChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	_, err := authService.ConfirmPhoneChange(context.Background(), request)
+	assert.ErrorIs(t, err, ErrNoPendingPhoneChange)
+	mockGenerator.AssertNotCalled(t, "Generate", mock.Anything)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"auth-service/internal/validators"
+	"context"
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNoPendingEmailChange = errors.New("there is no email change to confirm")
+	ErrProfileProofRequired = errors.New("please login again or send an otp of your phone number to change your email or user name")
+)
+
+// UpdateProfile applies the fields of the update mask to the profile at the requested version.
+// Changing the user name or email needs a login within the fresh login window or an otp of the current phone number,
+// the version alone does not prove the ownership of the account.
+// A changed email is kept as pending and a confirmation otp is sent to it, the confirmed email stays in use until then.
+// Repeating the pending email sends the confirmation again, so a request that failed to send it can be retried.
+func (a authService) UpdateProfile(ctx context.Context, request *auth.UpdateProfileRequest) (*auth.User, error) {
+	err := a.ValidateUpdateProfileRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if slices.ContainsFunc(request.UpdateMask.Paths, isIdentityPath) {
+		err = a.confirmAccountOwner(ctx, user, request.Otp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if user.Version != request.Version {
+		return nil, models.ErrStaleVersion
+	}
+	changed := *user
+	confirmEmail := false
+	for _, path := range request.UpdateMask.Paths {
+		switch path {
+		case validators.PROFILE_PATH_NAME:
+			changed.Name = request.User.Name
+		case validators.PROFILE_PATH_USER_NAME:
+			changed.UserName = request.User.UserName
+		case validators.PROFILE_PATH_EMAIL:
+			address, canonical := a.NormalizeEmail(request.User.Email)
+			if address == user.Email {
+				// changing back to the confirmed email drops the pending change
+				changed.PendingEmail, changed.PendingCanonicalEmail = "", ""
+			} else {
+				changed.PendingEmail, changed.PendingCanonicalEmail = address, canonical
+				confirmEmail = true
+			}
+		}
+	}
+	updated, err := a.UpdateUser(ctx, &changed, request.Version)
+	if err != nil {
+		return nil, err
+	}
+	a.InsertEvent(ctx, string(PROFILE_UPDATED), updated.PhoneNumber)
+	if confirmEmail {
+		err = a.publisher.Publish(ctx, newEmailConfirmationRequest(updated))
+		if err != nil {
+			return nil, err
+		}
+		a.InsertEvent(ctx, string(EMAIL_CHANGE_REQUESTED), updated.PhoneNumber)
+	}
+	return models.ToProto(updated), nil
+}
+
+// ConfirmEmailChange replaces the email with the pending one once the otp sent to it is confirmed
+func (a authService) ConfirmEmailChange(ctx context.Context, request *auth.ConfirmEmailChangeRequest) (*auth.User, error) {
+	err := a.ValidateConfirmEmailChangeRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	user, err := a.getActiveUser(ctx, request.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.PendingEmail == "" {
+		return nil, ErrNoPendingEmailChange
+	}
+	matches, err := a.checkOtp(ctx, user.PendingEmail, request.Otp)
+	if err != nil {
+		return nil, errors.New("unable to verify the OTP, Please try again after some time")
+	}
+	if !matches {
+		a.InsertEvent(ctx, string(INCORRECT_OTP), user.PhoneNumber)
+		return nil, errors.New("invalid OTP")
+	}
+	changed := *user
+	changed.Email, changed.CanonicalEmail = user.PendingEmail, user.PendingCanonicalEmail
+	changed.PendingEmail, changed.PendingCanonicalEmail = "", ""
+	changed.EmailConfirmedAt = time.Now()
+	updated, err := a.UpdateUser(ctx, &changed, user.Version)
+	if err != nil {
+		return nil, err
+	}
+	a.InsertEvent(ctx, string(EMAIL_CHANGED), updated.PhoneNumber)
+	return models.ToProto(updated), nil
+}
+
+// isIdentityPath reports whether the profile path identifies the user to others or receives otps
+func isIdentityPath(path string) bool {
+	return path == validators.PROFILE_PATH_USER_NAME || path == validators.PROFILE_PATH_EMAIL
+}
+
+// confirmAccountOwner checks the otp of the current phone number when one is given and a fresh login otherwise
+func (a authService) confirmAccountOwner(ctx context.Context, user *models.User, otp int32) error {
+	if otp != 0 {
+		return a.confirmPhoneOtp(ctx, user, otp)
+	}
+	events, err := a.ListRecentEvents(ctx, user.PhoneNumber, a.config.Load().FreshLoginWindow)
+	if err != nil {
+		return err
+	}
+	if !hasFreshLogin(events, user.SessionsRevokedAt) {
+		return ErrProfileProofRequired
+	}
+	return nil
+}
+
+// newEmailConfirmationRequest sends an otp derived from the pending email to it, so only its owner can confirm it
+func newEmailConfirmationRequest(user *models.User) *otp.GenerateOTPRequest {
+	request := newOtpRequest(user)
+	request.Channel = otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL
+	request.Email = user.PendingEmail
+	request.Subject = user.PendingEmail
+	return request
+}
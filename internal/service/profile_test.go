@@ -0,0 +1,239 @@
+package service
+
+import (
+	auth "auth-service/internal/gen/auth/v1"
+	otp "auth-service/internal/gen/otp/v1"
+	"auth-service/internal/models"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"testing"
+	"time"
+)
+
+func storedProfile() *models.User {
+	return &models.User{
+		Id:             1,
+		Name:           "John Doe",
+		UserName:       "johndoe",
+		Email:          "john@example.com",
+		CanonicalEmail: "john@example.com",
+		Verified:       true,
+		CountryCode:    91,
+		PhoneNumber:    "1234567890",
+		Version:        3,
+	}
+}
+
+func TestUpdateProfile_AppliesOnlyMaskedFields(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{Name: "Jane Doe", UserName: "ignored"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		Version:    3,
+	}
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.Name == "Jane Doe" && user.UserName == "johndoe" && user.PendingEmail == ""
+	}), int64(3)).Return(&models.User{Id: 1, Name: "Jane Doe", UserName: "johndoe", PhoneNumber: "1234567890", Version: 4}, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PROFILE_UPDATED), "1234567890").Return()
+	user, err := authService.UpdateProfile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Doe", user.Name)
+	assert.Equal(t, int64(4), user.Version)
+	mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	mockUserRepo.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestUpdateProfile_NewEmailIsPendingUntilConfirmed(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{Email: "Jane@Example.com"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+		Version:    3,
+	}
+	updated := storedProfile()
+	updated.PendingEmail, updated.PendingCanonicalEmail, updated.Version = "jane@example.com", "jane@example.com", 4
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "Jane@Example.com").Return("jane@example.com", "jane@example.com")
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now(), LOGIN_REQUEST, LOGIN_SUCCESSFUL), nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.Email == "john@example.com" && user.PendingEmail == "jane@example.com"
+	}), int64(3)).Return(updated, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(request *otp.GenerateOTPRequest) bool {
+		return request.Channel == otp.DeliveryChannel_DELIVERY_CHANNEL_EMAIL && request.Email == "jane@example.com" &&
+			request.Subject == "jane@example.com"
+	})).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PROFILE_UPDATED), "1234567890").Return()
+	mockEventRepo.On("InsertEvent", mock.Anything, string(EMAIL_CHANGE_REQUESTED), "1234567890").Return()
+	user, err := authService.UpdateProfile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "john@example.com", user.Email)
+	assert.Equal(t, "jane@example.com", user.PendingEmail)
+	mockPublisher.AssertExpectations(t)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestUpdateProfile_RepeatedPendingEmailSendsTheConfirmationAgain(t *testing.T) {
+	mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{Email: "jane@example.com"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+		Version:    4,
+	}
+	// the pending email was saved by a request that failed to send its confirmation
+	pending := storedProfile()
+	pending.PendingEmail, pending.PendingCanonicalEmail, pending.Version = "jane@example.com", "jane@example.com", 4
+	updated := *pending
+	updated.Version = 5
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockValidator.On("NormalizeEmail", "jane@example.com").Return("jane@example.com", "jane@example.com")
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(pending, nil)
+	mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+		Return(otpEvents(time.Now(), LOGIN_REQUEST, LOGIN_SUCCESSFUL), nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.Anything, int64(4)).Return(&updated, nil)
+	mockPublisher.On("Publish", mock.Anything, mock.MatchedBy(func(request *otp.GenerateOTPRequest) bool {
+		return request.Email == "jane@example.com"
+	})).Return(nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, mock.Anything, "1234567890").Return()
+	_, err := authService.UpdateProfile(context.Background(), request)
+	assert.NoError(t, err)
+	mockPublisher.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestUpdateProfile_IdentityChangesNeedProofOfOwnership(t *testing.T) {
+	for _, path := range []string{"user_name", "email"} {
+		mockUserRepo, mockValidator, mockPublisher, _, mockEventRepo, authService := setupAuthServiceMocks(t)
+		request := &auth.UpdateProfileRequest{
+			UserId:     1,
+			User:       &auth.User{UserName: "attacker", Email: "attacker@example.com"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{path}},
+			Version:    3,
+		}
+		mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+		mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+		// the login is older than the fresh login window
+		mockEventRepo.On("ListRecentEvents", mock.Anything, "1234567890", 10*time.Minute).
+			Return(otpEvents(time.Now(), LOGIN_SUCCESSFUL, LOGOUT), nil)
+		_, err := authService.UpdateProfile(context.Background(), request)
+		assert.ErrorIs(t, err, ErrProfileProofRequired, path)
+		mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+		mockPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	}
+}
+
+func TestUpdateProfile_OtpOfThePhoneNumberProvesOwnership(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{UserName: "janedoe"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"user_name"}},
+		Version:    3,
+		Otp:        123456,
+	}
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(123456), nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.UserName == "janedoe"
+	}), int64(3)).Return(&models.User{Id: 1, UserName: "janedoe", PhoneNumber: "1234567890", Version: 4}, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(PROFILE_UPDATED), "1234567890").Return()
+	user, err := authService.UpdateProfile(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "janedoe", user.UserName)
+	mockEventRepo.AssertNotCalled(t, "ListRecentEvents", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateProfile_WrongOtpIsRejected(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{Email: "attacker@example.com"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"email"}},
+		Version:    3,
+		Otp:        111111,
+	}
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	mockGenerator.On("Generate", "1234567890").Return(int32(123456), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, err := authService.UpdateProfile(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateProfile_StaleVersion(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{
+		UserId:     1,
+		User:       &auth.User{Name: "Jane Doe"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"name"}},
+		Version:    2,
+	}
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	_, err := authService.UpdateProfile(context.Background(), request)
+	assert.ErrorIs(t, err, models.ErrStaleVersion)
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateProfile_ValidationFailure(t *testing.T) {
+	mockUserRepo, mockValidator, _, _, _, authService := setupAuthServiceMocks(t)
+	request := &auth.UpdateProfileRequest{UserId: 1}
+	mockValidator.On("ValidateUpdateProfileRequest", request).Return(errors.New("update mask is empty, list the fields to update"))
+	_, err := authService.UpdateProfile(context.Background(), request)
+	assert.EqualError(t, err, "update mask is empty, list the fields to update")
+	mockUserRepo.AssertNotCalled(t, "GetUser", mock.Anything, mock.Anything)
+}
+
+func TestConfirmEmailChange_Success(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmEmailChangeRequest{UserId: 1, Otp: 654321}
+	pending := storedProfile()
+	pending.PendingEmail, pending.PendingCanonicalEmail = "jane@example.com", "jane@example.com"
+	mockValidator.On("ValidateConfirmEmailChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(pending, nil)
+	mockGenerator.On("Generate", "jane@example.com").Return(int32(654321), nil)
+	mockUserRepo.On("UpdateUser", mock.Anything, mock.MatchedBy(func(user *models.User) bool {
+		return user.Email == "jane@example.com" && user.CanonicalEmail == "jane@example.com" && user.PendingEmail == "" &&
+			!user.EmailConfirmedAt.IsZero()
+	}), int64(3)).Return(&models.User{Id: 1, Email: "jane@example.com", PhoneNumber: "1234567890", Version: 4}, nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(EMAIL_CHANGED), "1234567890").Return()
+	user, err := authService.ConfirmEmailChange(context.Background(), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", user.Email)
+	mockEventRepo.AssertExpectations(t)
+}
+
+func TestConfirmEmailChange_InvalidOtp(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, mockEventRepo, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmEmailChangeRequest{UserId: 1, Otp: 111111}
+	pending := storedProfile()
+	pending.PendingEmail = "jane@example.com"
+	mockValidator.On("ValidateConfirmEmailChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(pending, nil)
+	mockGenerator.On("Generate", "jane@example.com").Return(int32(654321), nil)
+	mockEventRepo.On("InsertEvent", mock.Anything, string(INCORRECT_OTP), "1234567890").Return()
+	_, err := authService.ConfirmEmailChange(context.Background(), request)
+	assert.EqualError(t, err, "invalid OTP")
+	mockUserRepo.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestConfirmEmailChange_NothingPending(t *testing.T) {
+	mockUserRepo, mockValidator, _, mockGenerator, _, authService := setupAuthServiceMocks(t)
+	request := &auth.ConfirmEmailChangeRequest{UserId: 1, Otp: 111111}
+	mockValidator.On("ValidateConfirmEmailChangeRequest", request).Return(nil)
+	mockUserRepo.On("GetUser", mock.Anything, int32(1)).Return(storedProfile(), nil)
+	_, err := authService.ConfirmEmailChange(context.Background(), request)
+	assert.ErrorIs(t, err, ErrNoPendingEmailChange)
+	mockGenerator.AssertNotCalled(t, "Generate", mock.Anything)
+}
@@ -0,0 +1,25 @@
+package service
+
+import "sync/atomic"
+
+// Tunable holds a config that can be replaced while requests are served. A config is swapped as a whole, so a request
+// sees either the old or the new config and never a mix of both
+type Tunable[T any] struct {
+	active atomic.Pointer[T]
+}
+
+func NewTunable[T any](config T) *Tunable[T] {
+	tunable := &Tunable[T]{}
+	tunable.Swap(config)
+	return tunable
+}
+
+// Load returns the active config
+func (t *Tunable[T]) Load() T {
+	return *t.active.Load()
+}
+
+// Swap makes config the active config
+func (t *Tunable[T]) Swap(config T) {
+	t.active.Store(&config)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"auth-service/internal/validators"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const (
+	maxUserNameSuggestions   = 3
+	maxUserNameSuggestionTry = 10
+)
+
+// userNameCandidates derives alternatives by appending numbers to the requested name.
+// The requested name is already valid, so appending digits keeps the candidates valid as well.
+func userNameCandidates(userName string, count int) []string {
+	base := strings.ToLower(userName)
+	candidates := make([]string, 0, count)
+	seen := map[string]struct{}{base: {}}
+	separators := []string{"", "_", "."}
+	for len(candidates) < count {
+		suffix := fmt.Sprintf("%s%d", separators[len(candidates)%len(separators)], 10+rand.Intn(990))
+		trimmed := base
+		if len(trimmed)+len(suffix) > validators.MaxUserNameLength {
+			trimmed = strings.TrimRight(trimmed[:validators.MaxUserNameLength-len(suffix)], "._")
+		}
+		candidate := trimmed + suffix
+		if _, ok := seen[candidate]; ok {
+			continue
+		}
+		seen[candidate] = struct{}{}
+		candidates = append(candidates, candidate)
+	}
+	return candidates
+}
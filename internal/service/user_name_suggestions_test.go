@@ -0,0 +1,26 @@
+package service
+
+import (
+	"auth-service/internal/validators"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestUserNameCandidates(t *testing.T) {
+	candidates := userNameCandidates("JohnDoe", 5)
+	assert.Len(t, candidates, 5)
+	seen := map[string]bool{}
+	for _, candidate := range candidates {
+		assert.True(t, strings.HasPrefix(candidate, "johndoe"))
+		assert.False(t, seen[candidate], "duplicate candidate %s", candidate)
+		seen[candidate] = true
+	}
+}
+
+func TestUserNameCandidates_RespectsMaxLength(t *testing.T) {
+	longName := strings.Repeat("a", validators.MaxUserNameLength)
+	for _, candidate := range userNameCandidates(longName, 5) {
+		assert.LessOrEqual(t, len(candidate), validators.MaxUserNameLength)
+	}
+}
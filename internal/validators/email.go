@@ -0,0 +1,141 @@
+package validators
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/net/idna"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+const (
+	maxEmailLength    = 254
+	maxEmailLocalPart = 64
+	gmailDomain       = "gmail.com"
+	googleMailDomain  = "googlemail.com"
+	domainLabelLimit  = 63
+	minDomainLabels   = 2
+)
+
+// EmailPolicy decides which email addresses are accepted and how they are canonicalized for uniqueness
+type EmailPolicy struct {
+	disposableDomains map[string]struct{}
+	canonicalizeGmail bool
+}
+
+// NewEmailPolicy creates a policy rejecting the given disposable domains and their sub domains.
+// With canonicalizeGmail set, dots and "+tag" suffixes of gmail addresses are ignored when checking uniqueness.
+func NewEmailPolicy(disposableDomains []string, canonicalizeGmail bool) *EmailPolicy {
+	domains := make(map[string]struct{}, len(disposableDomains))
+	for _, domain := range disposableDomains {
+		domains[strings.ToLower(strings.TrimSpace(domain))] = struct{}{}
+	}
+	return &EmailPolicy{disposableDomains: domains, canonicalizeGmail: canonicalizeGmail}
+}
+
+// LoadDisposableDomains reads a blocklist with one domain per line, blank lines and lines starting with '#' are skipped
+func LoadDisposableDomains(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open disposable domain list: %w", err)
+	}
+	defer file.Close()
+	var domains []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains = append(domains, strings.ToLower(line))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read disposable domain list: %w", err)
+	}
+	return domains, nil
+}
+
+// Validate checks the address syntax and rejects disposable domains
+func (p *EmailPolicy) Validate(email string) error {
+	if err := validateEmail(email); err != nil {
+		return err
+	}
+	_, domain := splitEmail(strings.ToLower(email))
+	if p.isDisposable(domain) {
+		return fmt.Errorf("email addresses from %s are not accepted", domain)
+	}
+	return nil
+}
+
+// Normalize returns the address to store and the canonical form used for uniqueness checks.
+// The address is lowercased, the canonical form additionally drops gmail dots and "+tag" suffixes when enabled.
+func (p *EmailPolicy) Normalize(email string) (string, string) {
+	address := strings.ToLower(strings.TrimSpace(email))
+	local, domain := splitEmail(address)
+	if !p.canonicalizeGmail || (domain != gmailDomain && domain != googleMailDomain) {
+		return address, address
+	}
+	if index := strings.IndexByte(local, '+'); index >= 0 {
+		local = local[:index]
+	}
+	local = strings.ReplaceAll(local, ".", "")
+	return address, local + "@" + gmailDomain
+}
+
+func (p *EmailPolicy) isDisposable(domain string) bool {
+	for domain != "" {
+		if _, ok := p.disposableDomains[domain]; ok {
+			return true
+		}
+		index := strings.IndexByte(domain, '.')
+		if index < 0 {
+			return false
+		}
+		domain = domain[index+1:]
+	}
+	return false
+}
+
+func splitEmail(email string) (string, string) {
+	index := strings.LastIndexByte(email, '@')
+	if index < 0 {
+		return email, ""
+	}
+	return email[:index], email[index+1:]
+}
+
+// validateEmail parses the address as RFC 5322 addr-spec and checks the domain is a resolvable host name
+func validateEmail(email string) error {
+	invalid := fmt.Errorf("email %s is not a valid email", email)
+	if len(email) > maxEmailLength {
+		return invalid
+	}
+	address, err := mail.ParseAddress(email)
+	// display names and angle brackets are valid in headers but not in a sign up form
+	if err != nil || address.Name != "" || address.Address != email {
+		return invalid
+	}
+	local, domain := splitEmail(address.Address)
+	if local == "" || len(local) > maxEmailLocalPart {
+		return invalid
+	}
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return invalid
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < minDomainLabels {
+		return invalid
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > domainLabelLimit {
+			return invalid
+		}
+	}
+	tld := labels[len(labels)-1]
+	if len(tld) < 2 || strings.Trim(tld, "0123456789") == "" {
+		return invalid
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package validators
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateEmailRFC(t *testing.T) {
+	valid := []string{
+		"John@Example.com",
+		"jane.doe+signup@example.co.in",
+		"curator@history.museum",
+		"dev@startup.technology",
+		"o'brien@example.ie",
+	}
+	for _, email := range valid {
+		if err := validateEmail(email); err != nil {
+			t.Errorf("validateEmail(%s) returned error: %v", email, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"plainaddress",
+		"@example.com",
+		"john@",
+		"john@localhost",
+		"john@example..com",
+		"John Doe <john@example.com>",
+		"john@example.123",
+	}
+	for _, email := range invalid {
+		if err := validateEmail(email); err == nil {
+			t.Errorf("validateEmail(%s) expected error, but got nil", email)
+		}
+	}
+}
+
+func TestEmailPolicyRejectsDisposableDomains(t *testing.T) {
+	policy := NewEmailPolicy([]string{"Mailinator.com"}, false)
+	for _, email := range []string{"john@mailinator.com", "john@MAILINATOR.com", "john@eu.mailinator.com"} {
+		if err := policy.Validate(email); err == nil {
+			t.Errorf("Validate(%s) expected error for disposable domain, but got nil", email)
+		}
+	}
+	if err := policy.Validate("john@notmailinator.com"); err != nil {
+		t.Errorf("Validate returned error for a non disposable domain: %v", err)
+	}
+}
+
+func TestEmailPolicyNormalize(t *testing.T) {
+	policy := NewEmailPolicy(nil, true)
+	address, canonical := policy.Normalize("John.Doe+News@GoogleMail.com")
+	if address != "john.doe+news@googlemail.com" {
+		t.Errorf("Normalize returned address %s", address)
+	}
+	if canonical != "johndoe@gmail.com" {
+		t.Errorf("Normalize returned canonical %s", canonical)
+	}
+
+	address, canonical = policy.Normalize("John.Doe+News@Example.com")
+	if address != "john.doe+news@example.com" || canonical != address {
+		t.Errorf("Normalize changed a non gmail address: %s, %s", address, canonical)
+	}
+
+	_, canonical = NewEmailPolicy(nil, false).Normalize("John.Doe@gmail.com")
+	if canonical != "john.doe@gmail.com" {
+		t.Errorf("Normalize canonicalized gmail although disabled: %s", canonical)
+	}
+}
+
+func TestLoadDisposableDomains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt")
+	content := "# throwaway providers\nmailinator.com\n\n  Trashmail.com  \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	domains, err := LoadDisposableDomains(path)
+	if err != nil {
+		t.Fatalf("LoadDisposableDomains returned error: %v", err)
+	}
+	if len(domains) != 2 || domains[0] != "mailinator.com" || domains[1] != "trashmail.com" {
+		t.Errorf("LoadDisposableDomains returned %v", domains)
+	}
+	if _, err := LoadDisposableDomains(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Errorf("LoadDisposableDomains expected error for a missing file")
+	}
+}
@@ -0,0 +1,46 @@
+package validators
+
+import (
+	v1 "auth-service/internal/gen/auth/v1"
+	"errors"
+	"fmt"
+)
+
+// Field mask paths accepted by UpdateProfile, named like the fields of v1.User
+const (
+	PROFILE_PATH_NAME      = "name"
+	PROFILE_PATH_USER_NAME = "user_name"
+	PROFILE_PATH_EMAIL     = "email"
+)
+
+func validateUserId(userId int32) error {
+	if userId <= 0 {
+		return fmt.Errorf("user id %d is not valid", userId)
+	}
+	return nil
+}
+
+// validateProfileUpdate validates the fields listed in the update mask, other fields of the user are ignored
+func (v *validator) validateProfileUpdate(request *v1.UpdateProfileRequest) error {
+	paths := request.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		return errors.New("update mask is empty, list the fields to update")
+	}
+	if request.User == nil {
+		return errors.New("user is missing")
+	}
+	var errs []error
+	for _, path := range paths {
+		switch path {
+		case PROFILE_PATH_NAME:
+			errs = append(errs, validateName(request.User.Name))
+		case PROFILE_PATH_USER_NAME:
+			errs = append(errs, validateUserName(request.User.UserName))
+		case PROFILE_PATH_EMAIL:
+			errs = append(errs, v.emailPolicy.Validate(request.User.Email))
+		default:
+			errs = append(errs, fmt.Errorf("field %s can not be updated", path))
+		}
+	}
+	return errors.Join(errs...)
+}
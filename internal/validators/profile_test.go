@@ -0,0 +1,61 @@
+package validators
+
+import (
+	v1 "auth-service/internal/gen/auth/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"testing"
+)
+
+func updateProfileRequest(user *v1.User, paths ...string) *v1.UpdateProfileRequest {
+	return &v1.UpdateProfileRequest{UserId: 1, User: user, UpdateMask: &fieldmaskpb.FieldMask{Paths: paths}, Version: 1}
+}
+
+func TestValidateUpdateProfileRequest_OnlyMaskedFieldsAreValidated(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy([]string{"mailinator.com"}, false))
+	// the invalid email is not part of the mask
+	request := updateProfileRequest(&v1.User{Name: "Jane Doe", Email: "not an email"}, PROFILE_PATH_NAME)
+	assert.NoError(t, validator.ValidateUpdateProfileRequest(request))
+
+	request = updateProfileRequest(&v1.User{Name: "Jane Doe", UserName: "jane_doe", Email: "jane@example.com"},
+		PROFILE_PATH_NAME, PROFILE_PATH_USER_NAME, PROFILE_PATH_EMAIL)
+	assert.NoError(t, validator.ValidateUpdateProfileRequest(request))
+}
+
+func TestValidateUpdateProfileRequest_Failures(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy([]string{"mailinator.com"}, false))
+	cases := map[string]*v1.UpdateProfileRequest{
+		"empty mask":         updateProfileRequest(&v1.User{Name: "Jane Doe"}),
+		"missing user":       updateProfileRequest(nil, PROFILE_PATH_NAME),
+		"blank name":         updateProfileRequest(&v1.User{Name: " "}, PROFILE_PATH_NAME),
+		"reserved user name": updateProfileRequest(&v1.User{UserName: "admin"}, PROFILE_PATH_USER_NAME),
+		"disposable email":   updateProfileRequest(&v1.User{Email: "jane@mailinator.com"}, PROFILE_PATH_EMAIL),
+		"immutable field":    updateProfileRequest(&v1.User{PhoneNumber: "1234567890"}, "PhoneNumber"),
+	}
+	for name, request := range cases {
+		assert.Error(t, validator.ValidateUpdateProfileRequest(request), name)
+	}
+	request := updateProfileRequest(&v1.User{Name: "Jane Doe"}, PROFILE_PATH_NAME)
+	request.UserId = 0
+	assert.EqualError(t, validator.ValidateUpdateProfileRequest(request), "user id 0 is not valid")
+}
+
+func TestValidateConfirmEmailChangeRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+	assert.NoError(t, validator.ValidateConfirmEmailChangeRequest(&v1.ConfirmEmailChangeRequest{UserId: 1, Otp: 123456}))
+	assert.Error(t, validator.ValidateConfirmEmailChangeRequest(&v1.ConfirmEmailChangeRequest{UserId: 1, Otp: 12}))
+}
+
+func TestValidateStartPhoneChangeRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+	assert.NoError(t, validator.ValidateStartPhoneChangeRequest(&v1.StartPhoneChangeRequest{UserId: 1, CountryCode: 91, PhoneNumber: "1234567890", Otp: 123456}))
+	assert.Error(t, validator.ValidateStartPhoneChangeRequest(&v1.StartPhoneChangeRequest{UserId: 1, CountryCode: 91, PhoneNumber: "12ab", Otp: 123456}))
+	assert.Error(t, validator.ValidateStartPhoneChangeRequest(&v1.StartPhoneChangeRequest{CountryCode: 91, PhoneNumber: "1234567890", Otp: 123456}))
+	assert.Error(t, validator.ValidateStartPhoneChangeRequest(&v1.StartPhoneChangeRequest{UserId: 1, CountryCode: 91, PhoneNumber: "1234567890"}))
+}
+
+func TestValidateConfirmPhoneChangeRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+	assert.NoError(t, validator.ValidateConfirmPhoneChangeRequest(&v1.ConfirmPhoneChangeRequest{UserId: 1, Otp: 123456}))
+	assert.Error(t, validator.ValidateConfirmPhoneChangeRequest(&v1.ConfirmPhoneChangeRequest{UserId: 1, Otp: 12}))
+}
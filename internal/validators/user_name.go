@@ -0,0 +1,106 @@
+package validators
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MinUserNameLength = 3
+	MaxUserNameLength = 30
+	MaxNameLength     = 255
+)
+
+// user names start with a letter, contain only letters, digits, '.' and '_'
+// and never end with a separator, so they stay readable in urls and mentions
+var userNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._]*[A-Za-z0-9]$`)
+
+var consecutiveSeparators = regexp.MustCompile(`[._]{2,}`)
+
+// reservedUserNames can not be registered as they could be used to impersonate the service
+var reservedUserNames = map[string]struct{}{
+	"admin":         {},
+	"administrator": {},
+	"root":          {},
+	"system":        {},
+	"support":       {},
+	"help":          {},
+	"security":      {},
+	"auth":          {},
+	"api":           {},
+	"login":         {},
+	"logout":        {},
+	"signup":        {},
+	"official":      {},
+	"moderator":     {},
+	"staff":         {},
+	"null":          {},
+	"undefined":     {},
+}
+
+// confusables maps characters that render like latin letters or digits to the character they imitate.
+// Cyrillic and Greek homoglyphs are the usual way of registering look-alike user names.
+var confusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't',
+	'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T',
+	'Х': 'X', 'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'τ': 't', 'ι': 'i', 'κ': 'k',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O',
+	'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	'ℓ': 'l', 'ⅰ': 'i', 'ı': 'i',
+	'０': '0', '１': '1', '２': '2', '３': '3', '４': '4', '５': '5', '６': '6', '７': '7', '８': '8', '９': '9',
+}
+
+// digitLookAlikes folds digits commonly used in place of letters, so "adm1n" is treated like "admin"
+var digitLookAlikes = strings.NewReplacer("0", "o", "1", "l", "3", "e", "4", "a", "5", "s", "7", "t", "i", "l")
+
+func validateUserName(userName string) error {
+	if userName == "" {
+		return fmt.Errorf("user name is empty")
+	}
+	if r, ok := findConfusable(userName); ok {
+		return fmt.Errorf("user name %s contains character %q that looks like %q", userName, r, confusables[r])
+	}
+	length := utf8.RuneCountInString(userName)
+	if length < MinUserNameLength || length > MaxUserNameLength {
+		return fmt.Errorf("user name must be between %d and %d characters long", MinUserNameLength, MaxUserNameLength)
+	}
+	if !userNamePattern.MatchString(userName) {
+		return fmt.Errorf("user name %s must start with a letter, end with a letter or digit and contain only letters, digits, '.' and '_'", userName)
+	}
+	if consecutiveSeparators.MatchString(userName) {
+		return fmt.Errorf("user name %s can not contain consecutive '.' or '_'", userName)
+	}
+	if IsReservedUserName(userName) {
+		return fmt.Errorf("user name %s is reserved", userName)
+	}
+	return nil
+}
+
+// IsReservedUserName reports whether the user name matches a reserved word, ignoring case,
+// separators and digits used as letter look-alikes
+func IsReservedUserName(userName string) bool {
+	folded := strings.ToLower(userName)
+	if _, ok := reservedUserNames[folded]; ok {
+		return true
+	}
+	skeleton := digitLookAlikes.Replace(strings.NewReplacer(".", "", "_", "").Replace(folded))
+	for reserved := range reservedUserNames {
+		if skeleton == digitLookAlikes.Replace(reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+func findConfusable(value string) (rune, bool) {
+	for _, r := range value {
+		if _, ok := confusables[r]; ok {
+			return r, true
+		}
+	}
+	return 0, false
+}
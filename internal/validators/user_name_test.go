@@ -0,0 +1,50 @@
+package validators
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUserNamePolicy(t *testing.T) {
+	valid := []string{"john_doe", "JohnDoe", "j.doe42", "abc", strings.Repeat("a", MaxUserNameLength)}
+	for _, userName := range valid {
+		if err := validateUserName(userName); err != nil {
+			t.Errorf("validateUserName(%s) returned error: %v", userName, err)
+		}
+	}
+
+	invalid := []string{
+		"ab",                                     // too short
+		strings.Repeat("a", MaxUserNameLength+1), // too long
+		"1john",                                  // starts with a digit
+		"john_",                                  // ends with a separator
+		"john__doe",                              // consecutive separators
+		"john doe",                               // space
+		"john-doe",                               // dash
+		"jоhn",                                   // cyrillic o
+		"Admin",                                  // reserved, case-insensitive
+		"adm1n",                                  // reserved, digit look-alike
+		"sup.port",                               // reserved, separators ignored
+	}
+	for _, userName := range invalid {
+		if err := validateUserName(userName); err == nil {
+			t.Errorf("validateUserName(%s) expected error, but got nil", userName)
+		}
+	}
+}
+
+func TestValidateUserNameReportsConfusable(t *testing.T) {
+	err := validateUserName("jоhn")
+	if err == nil || !strings.Contains(err.Error(), "looks like") {
+		t.Errorf("validateUserName expected confusable error, got %v", err)
+	}
+}
+
+func TestValidateNameLength(t *testing.T) {
+	if err := validateName(strings.Repeat("a", MaxNameLength+1)); err == nil {
+		t.Errorf("validateName expected error for a name longer than %d characters", MaxNameLength)
+	}
+	if err := validateName("   "); err == nil {
+		t.Errorf("validateName expected error for a blank name")
+	}
+}
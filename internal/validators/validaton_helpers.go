@@ -4,6 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
 )
 
 type CountryCode int32
@@ -12,31 +16,30 @@ const (
 	India CountryCode = 91
 )
 
-var AllowedCountryCodes []CountryCode
+var allowedCountryCodes atomic.Pointer[[]CountryCode]
 
 func init() {
-	AllowedCountryCodes = append(AllowedCountryCodes, India)
+	SetAllowedCountryCodes([]CountryCode{India})
 }
 
-func validateEmail(email string) error {
-	pattern := `^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`
-	matched, _ := regexp.MatchString(pattern, email)
-	if !matched {
-		return fmt.Errorf("email %s is not a valid email", email)
-	}
-	return nil
+// AllowedCountryCodes returns the country codes phone numbers are validated against
+func AllowedCountryCodes() []CountryCode {
+	return *allowedCountryCodes.Load()
 }
 
-func validateName(userName string) error {
-	if userName == "" {
-		return errors.New("name is empty")
-	}
-	return nil
+// SetAllowedCountryCodes replaces the allowed country codes, requests being validated see either the old or the new
+// codes. It is called on startup and when the config is reloaded
+func SetAllowedCountryCodes(codes []CountryCode) {
+	allowed := slices.Clone(codes)
+	allowedCountryCodes.Store(&allowed)
 }
 
-func validateUserName(userName string) error {
-	if userName == "" {
-		return errors.New("user name is empty")
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is empty")
+	}
+	if utf8.RuneCountInString(name) > MaxNameLength {
+		return fmt.Errorf("name can not be longer than %d characters", MaxNameLength)
 	}
 	return nil
 }
@@ -51,7 +54,7 @@ func validatePhoneNumber(phoneNumber string) error {
 }
 
 func validateCountryCodes(countryCode int32) error {
-	for _, code := range AllowedCountryCodes {
+	for _, code := range AllowedCountryCodes() {
 		if countryCode == int32(code) {
 			return nil
 		}
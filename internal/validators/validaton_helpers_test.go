@@ -87,3 +87,15 @@ func TestValidateOtp(t *testing.T) {
 		t.Errorf("validateOtp(%d) expected error, but got nil", invalidOTP)
 	}
 }
+
+func TestSetAllowedCountryCodes(t *testing.T) {
+	defer SetAllowedCountryCodes(AllowedCountryCodes())
+	SetAllowedCountryCodes([]CountryCode{1, 44})
+
+	if err := validateCountryCodes(44); err != nil {
+		t.Errorf("validateCountryCodes(44) returned error: %v", err)
+	}
+	if err := validateCountryCodes(int32(India)); err == nil {
+		t.Errorf("validateCountryCodes(%d) expected error after the codes were replaced, but got nil", India)
+	}
+}
@@ -3,6 +3,7 @@ package validators
 import (
 	v1 "auth-service/internal/gen/auth/v1"
 	"errors"
+	"fmt"
 )
 
 type IRequestValidator interface {
@@ -11,21 +12,35 @@ type IRequestValidator interface {
 	ValidateVerifyPhoneNumberRequest(request *v1.VerifyPhoneNumberRequest) error
 	ValidatePhoneNumberLogin(request *v1.ValidatePhoneNumberLoginRequest) error
 	ValidateGetProfileByMobileNumberRequest(request *v1.GetProfileByPhoneNumberRequest) error
+	ValidateCheckUsernameAvailabilityRequest(request *v1.CheckUsernameAvailabilityRequest) error
+	ValidateResendOtpRequest(request *v1.ResendOtpRequest) error
+	ValidateUpdateProfileRequest(request *v1.UpdateProfileRequest) error
+	ValidateConfirmEmailChangeRequest(request *v1.ConfirmEmailChangeRequest) error
+	ValidateStartPhoneChangeRequest(request *v1.StartPhoneChangeRequest) error
+	ValidateConfirmPhoneChangeRequest(request *v1.ConfirmPhoneChangeRequest) error
+	ValidateDeleteAccountRequest(request *v1.DeleteAccountRequest) error
+	ValidateRestoreAccountRequest(request *v1.RestoreAccountRequest) error
+	ValidateExportMyDataRequest(request *v1.ExportMyDataRequest) error
+	ValidateDownloadMyDataRequest(request *v1.DownloadMyDataRequest) error
+	ValidateGetOtpDeliveryStatusRequest(request *v1.GetOtpDeliveryStatusRequest) error
+	NormalizeEmail(email string) (string, string)
 }
 
-func NewValidator() IRequestValidator {
-	return &validator{}
+func NewValidator(emailPolicy *EmailPolicy) IRequestValidator {
+	return &validator{emailPolicy: emailPolicy}
 }
 
-type validator struct{}
+type validator struct {
+	emailPolicy *EmailPolicy
+}
 
 func (v *validator) ValidateSignupWithPhoneNumberRequest(request *v1.SignupWithPhoneNumberRequest) error {
 	phoneErr := validatePhoneNumber(request.User.PhoneNumber)
-	userErr := validateUserName(request.User.Name)
+	nameErr := validateName(request.User.Name)
 	userNameErr := validateUserName(request.User.UserName)
-	emailErr := validateEmail(request.User.Email)
+	emailErr := v.emailPolicy.Validate(request.User.Email)
 	countryErr := validateCountryCodes(request.User.CountryCode)
-	return errors.Join(phoneErr, userErr, userNameErr, emailErr, countryErr)
+	return errors.Join(phoneErr, nameErr, userNameErr, emailErr, countryErr)
 }
 
 func (v *validator) ValidateLoginWithPhoneNumberRequest(request *v1.LoginWithPhoneNumberRequest) error {
@@ -53,3 +68,84 @@ func (v *validator) ValidateGetProfileByMobileNumberRequest(request *v1.GetProfi
 	phoneErr := validatePhoneNumber(request.PhoneNumber)
 	return errors.Join(countryErr, phoneErr)
 }
+
+func (v *validator) ValidateCheckUsernameAvailabilityRequest(request *v1.CheckUsernameAvailabilityRequest) error {
+	return validateUserName(request.UserName)
+}
+
+func (v *validator) ValidateResendOtpRequest(request *v1.ResendOtpRequest) error {
+	phoneErr := validatePhoneNumber(request.PhoneNumber)
+	countryErr := validateCountryCodes(request.CountryCode)
+	return errors.Join(phoneErr, countryErr)
+}
+
+func (v *validator) ValidateGetOtpDeliveryStatusRequest(request *v1.GetOtpDeliveryStatusRequest) error {
+	phoneErr := validatePhoneNumber(request.PhoneNumber)
+	countryErr := validateCountryCodes(request.CountryCode)
+	var deliveryErr error
+	if request.DeliveryId == "" {
+		deliveryErr = errors.New("delivery id is missing")
+	}
+	return errors.Join(phoneErr, countryErr, deliveryErr)
+}
+
+func (v *validator) ValidateUpdateProfileRequest(request *v1.UpdateProfileRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	updateErr := v.validateProfileUpdate(request)
+	return errors.Join(userIdErr, updateErr)
+}
+
+func (v *validator) ValidateConfirmEmailChangeRequest(request *v1.ConfirmEmailChangeRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(userIdErr, otpErr)
+}
+
+func (v *validator) ValidateStartPhoneChangeRequest(request *v1.StartPhoneChangeRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	phoneErr := validatePhoneNumber(request.PhoneNumber)
+	countryErr := validateCountryCodes(request.CountryCode)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(userIdErr, phoneErr, countryErr, otpErr)
+}
+
+func (v *validator) ValidateConfirmPhoneChangeRequest(request *v1.ConfirmPhoneChangeRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(userIdErr, otpErr)
+}
+
+func (v *validator) ValidateDeleteAccountRequest(request *v1.DeleteAccountRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(userIdErr, otpErr)
+}
+
+func (v *validator) ValidateRestoreAccountRequest(request *v1.RestoreAccountRequest) error {
+	phoneErr := validatePhoneNumber(request.PhoneNumber)
+	countryErr := validateCountryCodes(request.CountryCode)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(phoneErr, countryErr, otpErr)
+}
+
+func (v *validator) ValidateExportMyDataRequest(request *v1.ExportMyDataRequest) error {
+	userIdErr := validateUserId(request.UserId)
+	otpErr := validateOtp(request.Otp)
+	return errors.Join(userIdErr, otpErr)
+}
+
+func (v *validator) ValidateDownloadMyDataRequest(request *v1.DownloadMyDataRequest) error {
+	var errs []error
+	if request.ExportId <= 0 {
+		errs = append(errs, fmt.Errorf("export id %d is not valid", request.ExportId))
+	}
+	if request.DownloadToken == "" {
+		errs = append(errs, errors.New("download token is missing"))
+	}
+	return errors.Join(errs...)
+}
+
+// NormalizeEmail returns the address to store and its canonical form used for uniqueness
+func (v *validator) NormalizeEmail(email string) (string, string) {
+	return v.emailPolicy.Normalize(email)
+}
@@ -26,7 +26,7 @@ func TestValidateSignupWithPhoneNumberRequest(t *testing.T) {
 		},
 	}
 
-	validator := NewValidator()
+	validator := NewValidator(NewEmailPolicy(nil, false))
 
 	// Test valid request
 	if err := validator.ValidateSignupWithPhoneNumberRequest(validRequest); err != nil {
@@ -50,7 +50,7 @@ func TestValidateLoginWithPhoneNumberRequest(t *testing.T) {
 		CountryCode: 91,
 	}
 
-	validator := NewValidator()
+	validator := NewValidator(NewEmailPolicy(nil, false))
 
 	// Test valid request
 	if err := validator.ValidateLoginWithPhoneNumberRequest(validRequest); err != nil {
@@ -76,7 +76,7 @@ func TestValidateVerifyPhoneNumberRequest(t *testing.T) {
 		CountryCode: 91,
 	}
 
-	validator := NewValidator()
+	validator := NewValidator(NewEmailPolicy(nil, false))
 
 	// Test valid request
 	if err := validator.ValidateVerifyPhoneNumberRequest(validRequest); err != nil {
@@ -102,7 +102,7 @@ func TestValidatePhoneNumberLogin(t *testing.T) {
 		CountryCode: 91,
 	}
 
-	validator := NewValidator()
+	validator := NewValidator(NewEmailPolicy(nil, false))
 
 	// Test valid request
 	if err := validator.ValidatePhoneNumberLogin(validRequest); err != nil {
@@ -126,7 +126,7 @@ func TestValidateGetProfileByMobileNumberRequest(t *testing.T) {
 		CountryCode: 91,
 	}
 
-	validator := NewValidator()
+	validator := NewValidator(NewEmailPolicy(nil, false))
 
 	// Test valid request
 	if err := validator.ValidateGetProfileByMobileNumberRequest(validRequest); err != nil {
@@ -138,3 +138,118 @@ func TestValidateGetProfileByMobileNumberRequest(t *testing.T) {
 		t.Errorf("ValidateGetProfileByMobileNumberRequest expected error for invalid request, but got nil")
 	}
 }
+
+func TestValidateResendOtpRequest(t *testing.T) {
+	validRequest := &v1.ResendOtpRequest{
+		PhoneNumber: "+911234567890",
+		CountryCode: 91,
+	}
+
+	invalidRequest := &v1.ResendOtpRequest{
+		PhoneNumber: "+911234567890",
+		CountryCode: 0, // Missing country code
+	}
+
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	// Test valid request
+	if err := validator.ValidateResendOtpRequest(validRequest); err != nil {
+		t.Errorf("ValidateResendOtpRequest returned error for valid request: %v", err)
+	}
+
+	// Test invalid request
+	if err := validator.ValidateResendOtpRequest(invalidRequest); err == nil {
+		t.Errorf("ValidateResendOtpRequest expected error for invalid request, but got nil")
+	}
+}
+
+func TestValidateGetOtpDeliveryStatusRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	// Test valid request
+	if err := validator.ValidateGetOtpDeliveryStatusRequest(&v1.GetOtpDeliveryStatusRequest{PhoneNumber: "+911234567890", CountryCode: 91, DeliveryId: "request-1"}); err != nil {
+		t.Errorf("ValidateGetOtpDeliveryStatusRequest returned error for valid request: %v", err)
+	}
+
+	// Test invalid request
+	if err := validator.ValidateGetOtpDeliveryStatusRequest(&v1.GetOtpDeliveryStatusRequest{PhoneNumber: "+911234567890", DeliveryId: "request-1"}); err == nil {
+		t.Errorf("ValidateGetOtpDeliveryStatusRequest expected error for invalid request, but got nil")
+	}
+
+	// Test request without the delivery id
+	if err := validator.ValidateGetOtpDeliveryStatusRequest(&v1.GetOtpDeliveryStatusRequest{PhoneNumber: "+911234567890", CountryCode: 91}); err == nil {
+		t.Errorf("ValidateGetOtpDeliveryStatusRequest expected error for request without delivery id, but got nil")
+	}
+}
+
+func TestValidateDeleteAccountRequest(t *testing.T) {
+	validRequest := &v1.DeleteAccountRequest{
+		UserId: 1,
+		Otp:    123456,
+	}
+
+	invalidRequest := &v1.DeleteAccountRequest{
+		UserId: 1,
+		Otp:    0, // Missing otp
+	}
+
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	// Test valid request
+	if err := validator.ValidateDeleteAccountRequest(validRequest); err != nil {
+		t.Errorf("ValidateDeleteAccountRequest returned error for valid request: %v", err)
+	}
+
+	// Test invalid request
+	if err := validator.ValidateDeleteAccountRequest(invalidRequest); err == nil {
+		t.Errorf("ValidateDeleteAccountRequest expected error for invalid request, but got nil")
+	}
+}
+
+func TestValidateRestoreAccountRequest(t *testing.T) {
+	validRequest := &v1.RestoreAccountRequest{
+		PhoneNumber: "+911234567890",
+		CountryCode: 91,
+		Otp:         123456,
+	}
+
+	invalidRequest := &v1.RestoreAccountRequest{
+		PhoneNumber: "", // Empty phone number
+		CountryCode: 91,
+		Otp:         123456,
+	}
+
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	// Test valid request
+	if err := validator.ValidateRestoreAccountRequest(validRequest); err != nil {
+		t.Errorf("ValidateRestoreAccountRequest returned error for valid request: %v", err)
+	}
+
+	// Test invalid request
+	if err := validator.ValidateRestoreAccountRequest(invalidRequest); err == nil {
+		t.Errorf("ValidateRestoreAccountRequest expected error for invalid request, but got nil")
+	}
+}
+
+func TestValidateExportMyDataRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	if err := validator.ValidateExportMyDataRequest(&v1.ExportMyDataRequest{UserId: 1, Otp: 123456}); err != nil {
+		t.Errorf("ValidateExportMyDataRequest returned error for valid request: %v", err)
+	}
+	if err := validator.ValidateExportMyDataRequest(&v1.ExportMyDataRequest{UserId: 0, Otp: 123456}); err == nil {
+		t.Errorf("ValidateExportMyDataRequest expected error for invalid request, but got nil")
+	}
+}
+
+func TestValidateDownloadMyDataRequest(t *testing.T) {
+	validator := NewValidator(NewEmailPolicy(nil, false))
+
+	if err := validator.ValidateDownloadMyDataRequest(&v1.DownloadMyDataRequest{ExportId: 1, DownloadToken: "token"}); err != nil {
+		t.Errorf("ValidateDownloadMyDataRequest returned error for valid request: %v", err)
+	}
+	if err := validator.ValidateDownloadMyDataRequest(&v1.DownloadMyDataRequest{ExportId: 1}); err == nil {
+		t.Errorf("ValidateDownloadMyDataRequest expected error for invalid request, but got nil")
+	}
+}
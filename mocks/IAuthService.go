@@ -3,9 +3,17 @@
 package mocks
 
 import (
-	mock "github.com/stretchr/testify/mock"
-
 	v1 "auth-service/internal/gen/auth/v1"
+
+	otpv1 "auth-service/internal/gen/otp/v1"
+
+	models "auth-service/internal/models"
+
+	context "context"
+
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
 )
 
 // IAuthService is an autogenerated mock type for the IAuthService type
@@ -13,25 +21,58 @@ type IAuthService struct {
 	mock.Mock
 }
 
-// GetUserProfile provides a mock function with given fields: _a0
-func (_m *IAuthService) GetUserProfile(_a0 *v1.GetProfileRequest) (*v1.User, error) {
-	ret := _m.Called(_a0)
+// CheckUsernameAvailability provides a mock function with given fields: ctx, request
+func (_m *IAuthService) CheckUsernameAvailability(ctx context.Context, request *v1.CheckUsernameAvailabilityRequest) (bool, []string, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 bool
+	var r1 []string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.CheckUsernameAvailabilityRequest) (bool, []string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.CheckUsernameAvailabilityRequest) bool); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.CheckUsernameAvailabilityRequest) []string); ok {
+		r1 = rf(ctx, request)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *v1.CheckUsernameAvailabilityRequest) error); ok {
+		r2 = rf(ctx, request)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// ConfirmEmailChange provides a mock function with given fields: ctx, request
+func (_m *IAuthService) ConfirmEmailChange(ctx context.Context, request *v1.ConfirmEmailChangeRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
 
 	var r0 *v1.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*v1.GetProfileRequest) (*v1.User, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ConfirmEmailChangeRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
 	}
-	if rf, ok := ret.Get(0).(func(*v1.GetProfileRequest) *v1.User); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ConfirmEmailChangeRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*v1.GetProfileRequest) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.ConfirmEmailChangeRequest) error); ok {
+		r1 = rf(ctx, request)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -39,25 +80,101 @@ func (_m *IAuthService) GetUserProfile(_a0 *v1.GetProfileRequest) (*v1.User, err
 	return r0, r1
 }
 
-// GetUserProfileByPhone provides a mock function with given fields: _a0
-func (_m *IAuthService) GetUserProfileByPhone(_a0 *v1.GetProfileByPhoneNumberRequest) (*v1.User, error) {
-	ret := _m.Called(_a0)
+// ConfirmPhoneChange provides a mock function with given fields: ctx, request
+func (_m *IAuthService) ConfirmPhoneChange(ctx context.Context, request *v1.ConfirmPhoneChangeRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
 
 	var r0 *v1.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*v1.GetProfileByPhoneNumberRequest) (*v1.User, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ConfirmPhoneChangeRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
 	}
-	if rf, ok := ret.Get(0).(func(*v1.GetProfileByPhoneNumberRequest) *v1.User); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ConfirmPhoneChangeRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*v1.GetProfileByPhoneNumberRequest) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.ConfirmPhoneChangeRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteAccount provides a mock function with given fields: ctx, request
+func (_m *IAuthService) DeleteAccount(ctx context.Context, request *v1.DeleteAccountRequest) (time.Time, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.DeleteAccountRequest) (time.Time, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.DeleteAccountRequest) time.Time); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.DeleteAccountRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DownloadMyData provides a mock function with given fields: ctx, request
+func (_m *IAuthService) DownloadMyData(ctx context.Context, request *v1.DownloadMyDataRequest) (*models.DataExport, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *models.DataExport
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.DownloadMyDataRequest) (*models.DataExport, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.DownloadMyDataRequest) *models.DataExport); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExport)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.DownloadMyDataRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ExportMyData provides a mock function with given fields: ctx, request
+func (_m *IAuthService) ExportMyData(ctx context.Context, request *v1.ExportMyDataRequest) (*models.DataExportTicket, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *models.DataExportTicket
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ExportMyDataRequest) (*models.DataExportTicket, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ExportMyDataRequest) *models.DataExportTicket); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.DataExportTicket)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.ExportMyDataRequest) error); ok {
+		r1 = rf(ctx, request)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -65,25 +182,51 @@ func (_m *IAuthService) GetUserProfileByPhone(_a0 *v1.GetProfileByPhoneNumberReq
 	return r0, r1
 }
 
-// HandleSignUp provides a mock function with given fields: _a0
-func (_m *IAuthService) HandleSignUp(_a0 *v1.SignupWithPhoneNumberRequest) (*v1.User, error) {
-	ret := _m.Called(_a0)
+// GetOtpDeliveryStatus provides a mock function with given fields: ctx, request
+func (_m *IAuthService) GetOtpDeliveryStatus(ctx context.Context, request *v1.GetOtpDeliveryStatusRequest) (*models.OtpDelivery, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *models.OtpDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetOtpDeliveryStatusRequest) (*models.OtpDelivery, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetOtpDeliveryStatusRequest) *models.OtpDelivery); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.OtpDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.GetOtpDeliveryStatusRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserProfile provides a mock function with given fields: ctx, request
+func (_m *IAuthService) GetUserProfile(ctx context.Context, request *v1.GetProfileRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
 
 	var r0 *v1.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*v1.SignupWithPhoneNumberRequest) (*v1.User, error)); ok {
-		return rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetProfileRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
 	}
-	if rf, ok := ret.Get(0).(func(*v1.SignupWithPhoneNumberRequest) *v1.User); ok {
-		r0 = rf(_a0)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetProfileRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*v1.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*v1.SignupWithPhoneNumberRequest) error); ok {
-		r1 = rf(_a0)
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.GetProfileRequest) error); ok {
+		r1 = rf(ctx, request)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -91,27 +234,212 @@ func (_m *IAuthService) HandleSignUp(_a0 *v1.SignupWithPhoneNumberRequest) (*v1.
 	return r0, r1
 }
 
-// LoginWithPhoneNumber provides a mock function with given fields: request
-func (_m *IAuthService) LoginWithPhoneNumber(request *v1.LoginWithPhoneNumberRequest) error {
-	ret := _m.Called(request)
+// GetUserProfileByPhone provides a mock function with given fields: ctx, request
+func (_m *IAuthService) GetUserProfileByPhone(ctx context.Context, request *v1.GetProfileByPhoneNumberRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(*v1.LoginWithPhoneNumberRequest) error); ok {
-		r0 = rf(request)
+	var r0 *v1.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetProfileByPhoneNumberRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GetProfileByPhoneNumberRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.User)
+		}
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.GetProfileByPhoneNumberRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// HandleSignUp provides a mock function with given fields: ctx, request
+func (_m *IAuthService) HandleSignUp(ctx context.Context, request *v1.SignupWithPhoneNumberRequest) (*v1.User, string, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *v1.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.SignupWithPhoneNumberRequest) (*v1.User, string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.SignupWithPhoneNumberRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.SignupWithPhoneNumberRequest) string); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *v1.SignupWithPhoneNumberRequest) error); ok {
+		r2 = rf(ctx, request)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// LoginWithPhoneNumber provides a mock function with given fields: ctx, request
+func (_m *IAuthService) LoginWithPhoneNumber(ctx context.Context, request *v1.LoginWithPhoneNumberRequest) (string, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.LoginWithPhoneNumberRequest) (string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.LoginWithPhoneNumberRequest) string); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.LoginWithPhoneNumberRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ResendOtp provides a mock function with given fields: ctx, request
+func (_m *IAuthService) ResendOtp(ctx context.Context, request *v1.ResendOtpRequest) (otpv1.DeliveryChannel, string, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 otpv1.DeliveryChannel
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ResendOtpRequest) (otpv1.DeliveryChannel, string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ResendOtpRequest) otpv1.DeliveryChannel); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Get(0).(otpv1.DeliveryChannel)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.ResendOtpRequest) string); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *v1.ResendOtpRequest) error); ok {
+		r2 = rf(ctx, request)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// RestoreAccount provides a mock function with given fields: ctx, request
+func (_m *IAuthService) RestoreAccount(ctx context.Context, request *v1.RestoreAccountRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *v1.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.RestoreAccountRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.RestoreAccountRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.RestoreAccountRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StartPhoneChange provides a mock function with given fields: ctx, request
+func (_m *IAuthService) StartPhoneChange(ctx context.Context, request *v1.StartPhoneChangeRequest) (*v1.User, string, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *v1.User
+	var r1 string
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.StartPhoneChangeRequest) (*v1.User, string, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.StartPhoneChangeRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.StartPhoneChangeRequest) string); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, *v1.StartPhoneChangeRequest) error); ok {
+		r2 = rf(ctx, request)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// UpdateProfile provides a mock function with given fields: ctx, request
+func (_m *IAuthService) UpdateProfile(ctx context.Context, request *v1.UpdateProfileRequest) (*v1.User, error) {
+	ret := _m.Called(ctx, request)
+
+	var r0 *v1.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.UpdateProfileRequest) (*v1.User, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.UpdateProfileRequest) *v1.User); ok {
+		r0 = rf(ctx, request)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*v1.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *v1.UpdateProfileRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
-// ValidatePhoneNumberLogin provides a mock function with given fields: request
-func (_m *IAuthService) ValidatePhoneNumberLogin(request *v1.ValidatePhoneNumberLoginRequest) error {
-	ret := _m.Called(request)
+// ValidatePhoneNumberLogin provides a mock function with given fields: ctx, request
+func (_m *IAuthService) ValidatePhoneNumberLogin(ctx context.Context, request *v1.ValidatePhoneNumberLoginRequest) error {
+	ret := _m.Called(ctx, request)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*v1.ValidatePhoneNumberLoginRequest) error); ok {
-		r0 = rf(request)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.ValidatePhoneNumberLoginRequest) error); ok {
+		r0 = rf(ctx, request)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -119,13 +447,13 @@ func (_m *IAuthService) ValidatePhoneNumberLogin(request *v1.ValidatePhoneNumber
 	return r0
 }
 
-// VerifyOtp provides a mock function with given fields: request
-func (_m *IAuthService) VerifyOtp(request *v1.VerifyPhoneNumberRequest) error {
-	ret := _m.Called(request)
+// VerifyOtp provides a mock function with given fields: ctx, request
+func (_m *IAuthService) VerifyOtp(ctx context.Context, request *v1.VerifyPhoneNumberRequest) error {
+	ret := _m.Called(ctx, request)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*v1.VerifyPhoneNumberRequest) error); ok {
-		r0 = rf(request)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.VerifyPhoneNumberRequest) error); ok {
+		r0 = rf(ctx, request)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -2,16 +2,76 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	models "auth-service/internal/models"
+
+	context "context"
+
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // IEventRepository is an autogenerated mock type for the IEventRepository type
 type IEventRepository struct {
 	mock.Mock
 }
 
-// InsertEvent provides a mock function with given fields: event, phoneNumber
-func (_m *IEventRepository) InsertEvent(event string, phoneNumber string) {
-	_m.Called(event, phoneNumber)
+// InsertEvent provides a mock function with given fields: ctx, event, phoneNumber
+func (_m *IEventRepository) InsertEvent(ctx context.Context, event string, phoneNumber string) {
+	_m.Called(ctx, event, phoneNumber)
+}
+
+// ListEvents provides a mock function with given fields: ctx, phoneNumber, since, until
+func (_m *IEventRepository) ListEvents(ctx context.Context, phoneNumber string, since time.Time, until time.Time) ([]models.UserEvent, error) {
+	ret := _m.Called(ctx, phoneNumber, since, until)
+
+	var r0 []models.UserEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) ([]models.UserEvent, error)); ok {
+		return rf(ctx, phoneNumber, since, until)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Time, time.Time) []models.UserEvent); ok {
+		r0 = rf(ctx, phoneNumber, since, until)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UserEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Time, time.Time) error); ok {
+		r1 = rf(ctx, phoneNumber, since, until)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecentEvents provides a mock function with given fields: ctx, phoneNumber, window
+func (_m *IEventRepository) ListRecentEvents(ctx context.Context, phoneNumber string, window time.Duration) ([]models.UserEvent, error) {
+	ret := _m.Called(ctx, phoneNumber, window)
+
+	var r0 []models.UserEvent
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) ([]models.UserEvent, error)); ok {
+		return rf(ctx, phoneNumber, window)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, time.Duration) []models.UserEvent); ok {
+		r0 = rf(ctx, phoneNumber, window)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.UserEvent)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, time.Duration) error); ok {
+		r1 = rf(ctx, phoneNumber, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewIEventRepository creates a new instance of IEventRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -5,6 +5,8 @@ package mocks
 import (
 	v1 "auth-service/internal/gen/otp/v1"
 
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,13 +15,13 @@ type IMessagePublisher struct {
 	mock.Mock
 }
 
-// Publish provides a mock function with given fields: request
-func (_m *IMessagePublisher) Publish(request *v1.GenerateOTPRequest) error {
-	ret := _m.Called(request)
+// Publish provides a mock function with given fields: ctx, request
+func (_m *IMessagePublisher) Publish(ctx context.Context, request *v1.GenerateOTPRequest) error {
+	ret := _m.Called(ctx, request)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*v1.GenerateOTPRequest) error); ok {
-		r0 = rf(request)
+	if rf, ok := ret.Get(0).(func(context.Context, *v1.GenerateOTPRequest) error); ok {
+		r0 = rf(ctx, request)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -13,6 +13,128 @@ type IRequestValidator struct {
 	mock.Mock
 }
 
+// NormalizeEmail provides a mock function with given fields: email
+func (_m *IRequestValidator) NormalizeEmail(email string) (string, string) {
+	ret := _m.Called(email)
+
+	var r0 string
+	var r1 string
+	if rf, ok := ret.Get(0).(func(string) (string, string)); ok {
+		return rf(email)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(email)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) string); ok {
+		r1 = rf(email)
+	} else {
+		r1 = ret.Get(1).(string)
+	}
+
+	return r0, r1
+}
+
+// ValidateCheckUsernameAvailabilityRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateCheckUsernameAvailabilityRequest(request *v1.CheckUsernameAvailabilityRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.CheckUsernameAvailabilityRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateConfirmEmailChangeRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateConfirmEmailChangeRequest(request *v1.ConfirmEmailChangeRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.ConfirmEmailChangeRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateConfirmPhoneChangeRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateConfirmPhoneChangeRequest(request *v1.ConfirmPhoneChangeRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.ConfirmPhoneChangeRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateDeleteAccountRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateDeleteAccountRequest(request *v1.DeleteAccountRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.DeleteAccountRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateDownloadMyDataRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateDownloadMyDataRequest(request *v1.DownloadMyDataRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.DownloadMyDataRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateExportMyDataRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateExportMyDataRequest(request *v1.ExportMyDataRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.ExportMyDataRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateGetOtpDeliveryStatusRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateGetOtpDeliveryStatusRequest(request *v1.GetOtpDeliveryStatusRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.GetOtpDeliveryStatusRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ValidateGetProfileByMobileNumberRequest provides a mock function with given fields: request
 func (_m *IRequestValidator) ValidateGetProfileByMobileNumberRequest(request *v1.GetProfileByPhoneNumberRequest) error {
 	ret := _m.Called(request)
@@ -55,6 +177,34 @@ func (_m *IRequestValidator) ValidatePhoneNumberLogin(request *v1.ValidatePhoneN
 	return r0
 }
 
+// ValidateResendOtpRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateResendOtpRequest(request *v1.ResendOtpRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.ResendOtpRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateRestoreAccountRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateRestoreAccountRequest(request *v1.RestoreAccountRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.RestoreAccountRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ValidateSignupWithPhoneNumberRequest provides a mock function with given fields: request
 func (_m *IRequestValidator) ValidateSignupWithPhoneNumberRequest(request *v1.SignupWithPhoneNumberRequest) error {
 	ret := _m.Called(request)
@@ -69,6 +219,34 @@ func (_m *IRequestValidator) ValidateSignupWithPhoneNumberRequest(request *v1.Si
 	return r0
 }
 
+// ValidateStartPhoneChangeRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateStartPhoneChangeRequest(request *v1.StartPhoneChangeRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.StartPhoneChangeRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ValidateUpdateProfileRequest provides a mock function with given fields: request
+func (_m *IRequestValidator) ValidateUpdateProfileRequest(request *v1.UpdateProfileRequest) error {
+	ret := _m.Called(request)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*v1.UpdateProfileRequest) error); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ValidateVerifyPhoneNumberRequest provides a mock function with given fields: request
 func (_m *IRequestValidator) ValidateVerifyPhoneNumberRequest(request *v1.VerifyPhoneNumberRequest) error {
 	ret := _m.Called(request)
@@ -5,6 +5,12 @@ package mocks
 import (
 	models "auth-service/internal/models"
 
+	repository "auth-service/internal/repository"
+
+	context "context"
+
+	time "time"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -13,25 +19,49 @@ type IUserRepository struct {
 	mock.Mock
 }
 
-// GetUser provides a mock function with given fields: userId
-func (_m *IUserRepository) GetUser(userId int32) (*models.User, error) {
-	ret := _m.Called(userId)
+// ChangePhoneNumber provides a mock function with given fields: ctx, change
+func (_m *IUserRepository) ChangePhoneNumber(ctx context.Context, change repository.PhoneNumberChange) (*models.User, error) {
+	ret := _m.Called(ctx, change)
 
 	var r0 *models.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int32) (*models.User, error)); ok {
-		return rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, repository.PhoneNumberChange) (*models.User, error)); ok {
+		return rf(ctx, change)
 	}
-	if rf, ok := ret.Get(0).(func(int32) *models.User); ok {
-		r0 = rf(userId)
+	if rf, ok := ret.Get(0).(func(context.Context, repository.PhoneNumberChange) *models.User); ok {
+		r0 = rf(ctx, change)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int32) error); ok {
-		r1 = rf(userId)
+	if rf, ok := ret.Get(1).(func(context.Context, repository.PhoneNumberChange) error); ok {
+		r1 = rf(ctx, change)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteExpiredUnverified provides a mock function with given fields: ctx, ttl
+func (_m *IUserRepository) DeleteExpiredUnverified(ctx context.Context, ttl time.Duration) (int64, error) {
+	ret := _m.Called(ctx, ttl)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) (int64, error)); ok {
+		return rf(ctx, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) int64); ok {
+		r0 = rf(ctx, ttl)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = rf(ctx, ttl)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -39,25 +69,25 @@ func (_m *IUserRepository) GetUser(userId int32) (*models.User, error) {
 	return r0, r1
 }
 
-// GetUserByPhoneNumberAndCountry provides a mock function with given fields: countryCode, phoneNumber
-func (_m *IUserRepository) GetUserByPhoneNumberAndCountry(countryCode int32, phoneNumber string) (*models.User, error) {
-	ret := _m.Called(countryCode, phoneNumber)
+// GetUser provides a mock function with given fields: ctx, userId
+func (_m *IUserRepository) GetUser(ctx context.Context, userId int32) (*models.User, error) {
+	ret := _m.Called(ctx, userId)
 
 	var r0 *models.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(int32, string) (*models.User, error)); ok {
-		return rf(countryCode, phoneNumber)
+	if rf, ok := ret.Get(0).(func(context.Context, int32) (*models.User, error)); ok {
+		return rf(ctx, userId)
 	}
-	if rf, ok := ret.Get(0).(func(int32, string) *models.User); ok {
-		r0 = rf(countryCode, phoneNumber)
+	if rf, ok := ret.Get(0).(func(context.Context, int32) *models.User); ok {
+		r0 = rf(ctx, userId)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(int32, string) error); ok {
-		r1 = rf(countryCode, phoneNumber)
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, userId)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -65,25 +95,25 @@ func (_m *IUserRepository) GetUserByPhoneNumberAndCountry(countryCode int32, pho
 	return r0, r1
 }
 
-// SaveUser provides a mock function with given fields: user
-func (_m *IUserRepository) SaveUser(user *models.User) (*models.User, error) {
-	ret := _m.Called(user)
+// GetUserByPhoneNumberAndCountry provides a mock function with given fields: ctx, countryCode, phoneNumber
+func (_m *IUserRepository) GetUserByPhoneNumberAndCountry(ctx context.Context, countryCode int32, phoneNumber string) (*models.User, error) {
+	ret := _m.Called(ctx, countryCode, phoneNumber)
 
 	var r0 *models.User
 	var r1 error
-	if rf, ok := ret.Get(0).(func(*models.User) (*models.User, error)); ok {
-		return rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string) (*models.User, error)); ok {
+		return rf(ctx, countryCode, phoneNumber)
 	}
-	if rf, ok := ret.Get(0).(func(*models.User) *models.User); ok {
-		r0 = rf(user)
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string) *models.User); ok {
+		r0 = rf(ctx, countryCode, phoneNumber)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.User)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(*models.User) error); ok {
-		r1 = rf(user)
+	if rf, ok := ret.Get(1).(func(context.Context, int32, string) error); ok {
+		r1 = rf(ctx, countryCode, phoneNumber)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -91,13 +121,87 @@ func (_m *IUserRepository) SaveUser(user *models.User) (*models.User, error) {
 	return r0, r1
 }
 
-// UpdateVerifiedTrueById provides a mock function with given fields: id
-func (_m *IUserRepository) MarkVerified(id int32) error {
-	ret := _m.Called(id)
+// IsPhoneNumberTaken provides a mock function with given fields: ctx, countryCode, phoneNumber
+func (_m *IUserRepository) IsPhoneNumberTaken(ctx context.Context, countryCode int32, phoneNumber string) (bool, error) {
+	ret := _m.Called(ctx, countryCode, phoneNumber)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string) (bool, error)); ok {
+		return rf(ctx, countryCode, phoneNumber)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string) bool); ok {
+		r0 = rf(ctx, countryCode, phoneNumber)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32, string) error); ok {
+		r1 = rf(ctx, countryCode, phoneNumber)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// IsUserNameTaken provides a mock function with given fields: ctx, userName
+func (_m *IUserRepository) IsUserNameTaken(ctx context.Context, userName string) (bool, error) {
+	ret := _m.Called(ctx, userName)
+
+	var r0 bool
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (bool, error)); ok {
+		return rf(ctx, userName)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) bool); ok {
+		r0 = rf(ctx, userName)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, userName)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListPhoneNumbers provides a mock function with given fields: ctx, userId
+func (_m *IUserRepository) ListPhoneNumbers(ctx context.Context, userId int32) ([]models.HeldPhoneNumber, error) {
+	ret := _m.Called(ctx, userId)
+
+	var r0 []models.HeldPhoneNumber
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32) ([]models.HeldPhoneNumber, error)); ok {
+		return rf(ctx, userId)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32) []models.HeldPhoneNumber); ok {
+		r0 = rf(ctx, userId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]models.HeldPhoneNumber)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32) error); ok {
+		r1 = rf(ctx, userId)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkVerified provides a mock function with given fields: ctx, id
+func (_m *IUserRepository) MarkVerified(ctx context.Context, id int32) error {
+	ret := _m.Called(ctx, id)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int32) error); ok {
-		r0 = rf(id)
+	if rf, ok := ret.Get(0).(func(context.Context, int32) error); ok {
+		r0 = rf(ctx, id)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -105,6 +209,134 @@ func (_m *IUserRepository) MarkVerified(id int32) error {
 	return r0
 }
 
+// PurgeDeletedUsers provides a mock function with given fields: ctx, gracePeriod
+func (_m *IUserRepository) PurgeDeletedUsers(ctx context.Context, gracePeriod time.Duration) (int64, error) {
+	ret := _m.Called(ctx, gracePeriod)
+
+	var r0 int64
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) (int64, error)); ok {
+		return rf(ctx, gracePeriod)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) int64); ok {
+		r0 = rf(ctx, gracePeriod)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = rf(ctx, gracePeriod)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RegisterUser provides a mock function with given fields: ctx, registration
+func (_m *IUserRepository) RegisterUser(ctx context.Context, registration repository.Registration) (*models.User, error) {
+	ret := _m.Called(ctx, registration)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, repository.Registration) (*models.User, error)); ok {
+		return rf(ctx, registration)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, repository.Registration) *models.User); ok {
+		r0 = rf(ctx, registration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, repository.Registration) error); ok {
+		r1 = rf(ctx, registration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SaveUser provides a mock function with given fields: ctx, user
+func (_m *IUserRepository) SaveUser(ctx context.Context, user *models.User) (*models.User, error) {
+	ret := _m.Called(ctx, user)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) (*models.User, error)); ok {
+		return rf(ctx, user)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) *models.User); ok {
+		r0 = rf(ctx, user)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
+		r1 = rf(ctx, user)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetUserStatus provides a mock function with given fields: ctx, userId, status, expectedVersion
+func (_m *IUserRepository) SetUserStatus(ctx context.Context, userId int32, status string, expectedVersion int64) (*models.User, error) {
+	ret := _m.Called(ctx, userId, status, expectedVersion)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string, int64) (*models.User, error)); ok {
+		return rf(ctx, userId, status, expectedVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int32, string, int64) *models.User); ok {
+		r0 = rf(ctx, userId, status, expectedVersion)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int32, string, int64) error); ok {
+		r1 = rf(ctx, userId, status, expectedVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateUser provides a mock function with given fields: ctx, user, expectedVersion
+func (_m *IUserRepository) UpdateUser(ctx context.Context, user *models.User, expectedVersion int64) (*models.User, error) {
+	ret := _m.Called(ctx, user, expectedVersion)
+
+	var r0 *models.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, int64) (*models.User, error)); ok {
+		return rf(ctx, user, expectedVersion)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, int64) *models.User); ok {
+		r0 = rf(ctx, user, expectedVersion)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User, int64) error); ok {
+		r1 = rf(ctx, user, expectedVersion)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewIUserRepository creates a new instance of IUserRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewIUserRepository(t interface {
@@ -13,10 +13,33 @@ mockery --quiet --dir internal/repository --name IUserRepository
 printf "Generated Mocks for internal/repository/IUserRepository\n"
 
 
+mockery --quiet --dir internal/repository --name IOutboxRepository
+printf "Generated Mocks for internal/repository/IOutboxRepository\n"
+
+
+mockery --quiet --dir internal/repository --name IIdempotencyRepository
+printf "Generated Mocks for internal/repository/IIdempotencyRepository\n"
+
+
+mockery --quiet --dir internal/repository --name IDataExportRepository
+printf "Generated Mocks for internal/repository/IDataExportRepository\n"
+
+
+mockery --quiet --dir internal/repository --name IOtpDeliveryRepository
+printf "Generated Mocks for internal/repository/IOtpDeliveryRepository\n"
+
+
+mockery --quiet --dir internal/repository --name IOtpCodeRepository
+printf "Generated Mocks for internal/repository/IOtpCodeRepository\n"
+
+
 mockery --quiet --dir internal/service --name IAuthService
 printf "Generated Mocks for internal/service/IAuthService\n"
 
 mockery --quiet --dir internal/service --name IGenerator
 printf "Generated Mocks for internal/service/IGenerator\n"
 
+mockery --quiet --dir internal/service --name IOtpCodes
+printf "Generated Mocks for internal/service/IOtpCodes\n"
+
 printf "Done!!\n"
\ No newline at end of file